@@ -2,17 +2,91 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	Port               string
 	DBConnectionString string
+
+	// Auth
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	AdminUsername   string // dipakai sekali untuk bootstrap admin kalau table users masih kosong
+	AdminPassword   string
+
+	// WebSocket: origin browser yang boleh membuka /ws ("*" = semua)
+	WSAllowedOrigins []string
+
+	// Reconnect supervisor: backoff eksponensial + jitter, menyerah (needs_attention) setelah max attempt
+	ReconnectMaxAttempts int
+	ReconnectBaseDelay   time.Duration
+	ReconnectMaxDelay    time.Duration
+
+	// Interval refresh profil instance (URL foto profil WhatsApp bisa expire)
+	ProfileRefreshInterval time.Duration
+
+	// Outbox (mode kirim async): jumlah attempt maksimal sebelum job dianggap failed
+	OutboxMaxAttempts int
+
+	// Anti-ban: batas kirim per instance (0 = tanpa batas), jeda antar pesan, dan warm-up nomor baru
+	SendLimitPerMinute    int
+	SendLimitPerHour      int
+	SendLimitPerDay       int
+	SendMinGap            time.Duration
+	SendJitter            time.Duration
+	SendMaxWait           time.Duration
+	SendWarmupDays        int
+	SendWarmupStartPerDay int
+
+	// Idempotency-Key di endpoint kirim: lama response disimpan untuk request ulang dengan key yang sama
+	IdempotencyWindow time.Duration
+
+	// Batas sendAt untuk pesan media terjadwal (media sudah di-upload saat dijadwalkan)
+	ScheduleMediaMaxAhead time.Duration
+
+	// Webhook boleh ke alamat loopback / private / link-local (default ditolak, cegah SSRF)
+	WebhookAllowPrivateNetworks bool
 }
 
 func Load() *Config {
 	return &Config{
 		Port:               getEnv("PORT", "2121"),
 		DBConnectionString: getEnv("DATABASE_URL", ""),
+
+		JWTSecret:       getEnv("JWT_SECRET", ""),
+		AccessTokenTTL:  getDurationEnv("ACCESS_TOKEN_TTL", 1*time.Hour),
+		RefreshTokenTTL: getDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		AdminUsername:   getEnv("ADMIN_USERNAME", ""),
+		AdminPassword:   getEnv("ADMIN_PASSWORD", ""),
+
+		WSAllowedOrigins: getListEnv("WS_ALLOWED_ORIGINS", []string{"http://localhost:8080"}),
+
+		ReconnectMaxAttempts: getIntEnv("RECONNECT_MAX_ATTEMPTS", 10),
+		ReconnectBaseDelay:   getDurationEnv("RECONNECT_BASE_DELAY", 2*time.Second),
+		ReconnectMaxDelay:    getDurationEnv("RECONNECT_MAX_DELAY", 5*time.Minute),
+
+		ProfileRefreshInterval: getDurationEnv("PROFILE_REFRESH_INTERVAL", 6*time.Hour),
+
+		OutboxMaxAttempts: getIntEnv("OUTBOX_MAX_ATTEMPTS", 8),
+
+		SendLimitPerMinute:    getLimitEnv("SEND_LIMIT_PER_MINUTE", 20),
+		SendLimitPerHour:      getLimitEnv("SEND_LIMIT_PER_HOUR", 300),
+		SendLimitPerDay:       getLimitEnv("SEND_LIMIT_PER_DAY", 1500),
+		SendMinGap:            getOptionalDurationEnv("SEND_MIN_GAP", 1*time.Second),
+		SendJitter:            getOptionalDurationEnv("SEND_JITTER", 2*time.Second),
+		SendMaxWait:           getDurationEnv("SEND_MAX_WAIT", 30*time.Second),
+		SendWarmupDays:        getLimitEnv("SEND_WARMUP_DAYS", 7),
+		SendWarmupStartPerDay: getLimitEnv("SEND_WARMUP_START_PER_DAY", 50),
+
+		IdempotencyWindow: getDurationEnv("IDEMPOTENCY_WINDOW", 24*time.Hour),
+
+		ScheduleMediaMaxAhead: getDurationEnv("SCHEDULE_MEDIA_MAX_AHEAD", 7*24*time.Hour),
+
+		WebhookAllowPrivateNetworks: getBoolEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
 	}
 }
 
@@ -22,3 +96,70 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getIntEnv membaca angka positif, fallback kalau kosong / tidak valid
+func getIntEnv(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// getLimitEnv membaca angka >= 0 (0 = fitur / batas dimatikan)
+func getLimitEnv(key string, fallback int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return fallback
+}
+
+// getOptionalDurationEnv seperti getDurationEnv tapi "0" / "0s" diterima (fitur dimatikan)
+func getOptionalDurationEnv(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+// getDurationEnv membaca durasi format Go (mis. "15m", "720h")
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return fallback
+}
+
+// getBoolEnv membaca true/false (1/0, yes/no juga diterima), fallback kalau kosong / tidak valid
+func getBoolEnv(key string, fallback bool) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(key))) {
+	case "1", "true", "yes":
+		return true
+	case "0", "false", "no":
+		return false
+	}
+	return fallback
+}
+
+// getListEnv membaca daftar dipisah koma (mis. "https://a.com,https://b.com")
+func getListEnv(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	list := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderAPIKey        = "X-API-Key"
+	principalContextKey = "principal"
+)
+
+// Principal adalah identitas pemanggil API, dari JWT (user) atau dari X-API-Key.
+type Principal struct {
+	UserID   int64
+	Username string
+	Role     string
+	APIKey   *model.APIKey // nil kalau login via JWT
+}
+
+// IsAdmin hanya berlaku untuk login JWT, API key tidak pernah dianggap admin
+func (p *Principal) IsAdmin() bool {
+	return p.APIKey == nil && p.Role == model.RoleAdmin
+}
+
+// OwnerScope owner_id yang boleh diakses pemanggil: 0 = semua instance (role admin,
+// termasuk API key milik admin), selain itu hanya instance milik user sendiri.
+func (p *Principal) OwnerScope() int64 {
+	if p.Role == model.RoleAdmin {
+		return 0
+	}
+	return p.UserID
+}
+
+// OwnsInstance cek kepemilikan instance (tenant) oleh user pemanggil
+func (p *Principal) OwnsInstance(inst *model.Instance) bool {
+	owner := p.OwnerScope()
+	return owner == 0 || (inst.OwnerID.Valid && inst.OwnerID.Int64 == owner)
+}
+
+// CanAccessInstance cek scope API key terhadap instance (by ID atau nomor instance)
+func (p *Principal) CanAccessInstance(inst *model.Instance) bool {
+	if p.APIKey == nil || !p.APIKey.IsScoped() {
+		return true
+	}
+	for _, id := range p.APIKey.InstanceIDs {
+		if id == inst.InstanceID {
+			return true
+		}
+	}
+	if !inst.PhoneNumber.Valid {
+		return false
+	}
+	return p.canAccessPhoneOnly(inst.PhoneNumber.String)
+}
+
+// CanAccessPhone cek scope API key untuk route by-number
+func (p *Principal) CanAccessPhone(phoneNumber string) bool {
+	if p.APIKey == nil || !p.APIKey.IsScoped() {
+		return true
+	}
+	return p.canAccessPhoneOnly(phoneNumber)
+}
+
+func (p *Principal) canAccessPhoneOnly(phoneNumber string) bool {
+	if jid, err := helper.FormatPhoneNumber(phoneNumber); err == nil {
+		phoneNumber = jid.User
+	}
+	for _, phone := range p.APIKey.PhoneNumbers {
+		if phone == phoneNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// ownerScope owner_id untuk lookup instance (mis. GetActiveInstanceByPhoneNumber)
+func ownerScope(c echo.Context) int64 {
+	p := currentPrincipal(c)
+	if p == nil {
+		return -1 // tidak match owner manapun
+	}
+	return p.OwnerScope()
+}
+
+// currentPrincipal ambil identitas pemanggil dari context (API key atau JWT claims)
+func currentPrincipal(c echo.Context) *Principal {
+	if p, ok := c.Get(principalContextKey).(*Principal); ok {
+		return p
+	}
+	claims := currentClaims(c)
+	if claims == nil {
+		return nil
+	}
+	return &Principal{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+	}
+}
+
+// APIKeyOrJWT middleware group /api: kalau ada header X-API-Key pakai API key,
+// kalau tidak lanjut ke middleware echojwt seperti biasa.
+func APIKeyOrJWT(jwtMiddleware echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withJWT := jwtMiddleware(requireActiveUser(next))
+
+		return func(c echo.Context) error {
+			raw := c.Request().Header.Get(HeaderAPIKey)
+			if raw == "" {
+				return withJWT(c)
+			}
+
+			key, user, err := service.AuthenticateAPIKey(raw)
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidAPIKey) || errors.Is(err, service.ErrUserDisabled) {
+					return ErrorResponse(c, 401, "Invalid API key", "INVALID_API_KEY", err.Error())
+				}
+				return ErrorResponse(c, 500, "Failed to validate API key", "DB_ERROR", err.Error())
+			}
+
+			c.Set(principalContextKey, &Principal{
+				UserID:   user.ID,
+				Username: user.Username,
+				Role:     user.Role,
+				APIKey:   key,
+			})
+			return next(c)
+		}
+	}
+}
+
+// requireActiveUser tolak access token JWT milik user yang sudah di-disable (sama seperti API key)
+func requireActiveUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		claims := currentClaims(c)
+		if claims == nil {
+			return ErrorResponse(c, 401, "Authentication required", "UNAUTHORIZED", "")
+		}
+
+		active, err := service.IsUserActive(claims.UserID)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to validate user", "DB_ERROR", err.Error())
+		}
+		if !active {
+			return ErrorResponse(c, 401, "User is disabled", "USER_DISABLED", service.ErrUserDisabled.Error())
+		}
+		return next(c)
+	}
+}
+
+// RequirePermission middleware per route: :instanceId di path harus milik user pemanggil,
+// API key juga wajib punya permission & scope ke :instanceId / :phoneNumber.
+// Kepemilikan route by-number dicek saat lookup instance (lihat ownerScope).
+func RequirePermission(perm string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p := currentPrincipal(c)
+			if p == nil {
+				return ErrorResponse(c, 401, "Authentication required", "UNAUTHORIZED", "")
+			}
+
+			if p.APIKey != nil && !p.APIKey.HasPermission(perm) {
+				return ErrorResponse(c, 403, "API key does not have permission for this action", "PERMISSION_DENIED",
+					"Required permission: "+perm)
+			}
+
+			if instanceID := c.Param("instanceId"); instanceID != "" {
+				inst, err := model.GetInstanceByInstanceID(instanceID)
+				if err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return ErrorResponse(c, 500, "Failed to get instance", "DB_ERROR", err.Error())
+				}
+				// Instance milik tenant lain diperlakukan sama seperti tidak ada
+				if inst == nil || !p.OwnsInstance(inst) {
+					return ErrorResponse(c, 404, "Instance not found", "INSTANCE_NOT_FOUND", "")
+				}
+				if !p.CanAccessInstance(inst) {
+					return ErrorResponse(c, 403, "API key is not allowed to access this instance", "INSTANCE_FORBIDDEN", "")
+				}
+			}
+			if phoneNumber := c.Param("phoneNumber"); phoneNumber != "" && !p.CanAccessPhone(phoneNumber) {
+				// Key yang di-scope per instance ID tetap boleh lewat route by-number untuk nomor instance tsb
+				inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber, p.OwnerScope())
+				if err != nil && !errors.Is(err, model.ErrNoActiveInstance) {
+					return ErrorResponse(c, 500, "Failed to get instance", "DB_ERROR", err.Error())
+				}
+				if inst == nil || !p.CanAccessInstance(inst) {
+					return ErrorResponse(c, 403, "API key is not allowed to use this phone number", "INSTANCE_FORBIDDEN", "")
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// RequireUser middleware: hanya login JWT (bukan API key), mis. untuk kelola API key
+func RequireUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := currentPrincipal(c)
+		if p == nil || p.APIKey != nil || p.UserID == 0 {
+			return ErrorResponse(c, 403, "This endpoint requires a user login (JWT)", "FORBIDDEN", "")
+		}
+		return next(c)
+	}
+}
+
+// Request body untuk create API key
+type CreateAPIKeyRequest struct {
+	Name         string     `json:"name" validate:"required"`
+	InstanceIDs  []string   `json:"instanceIds"`
+	PhoneNumbers []string   `json:"phoneNumbers"`
+	Permissions  []string   `json:"permissions" validate:"required"` // send, read, manage
+	ExpiresAt    *time.Time `json:"expiresAt"`
+}
+
+// POST /api-keys
+func CreateAPIKey(c echo.Context) error {
+	p := currentPrincipal(c)
+
+	var req CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.Name == "" || len(req.Permissions) == 0 {
+		return ErrorResponse(c, 400, "Fields 'name' and 'permissions' are required", "VALIDATION_ERROR", "")
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return ErrorResponse(c, 400, "Field 'expiresAt' must be in the future", "VALIDATION_ERROR", "")
+	}
+
+	plaintext, key, err := service.CreateAPIKey(p.UserID, req.Name, req.InstanceIDs, req.PhoneNumbers, req.Permissions, req.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPermission) {
+			return ErrorResponse(c, 400, "Invalid permissions", "VALIDATION_ERROR", err.Error())
+		}
+		return ErrorResponse(c, 400, "Failed to create API key", "CREATE_API_KEY_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 201, "API key created. Store the key now, it will not be shown again.", map[string]interface{}{
+		"key":    plaintext,
+		"apiKey": model.ToAPIKeyResponse(*key),
+	})
+}
+
+// GET /api-keys (admin melihat semua key, user hanya miliknya)
+func GetAPIKeys(c echo.Context) error {
+	p := currentPrincipal(c)
+
+	userID := p.UserID
+	if p.IsAdmin() {
+		userID = 0
+	}
+
+	keys, err := model.GetAPIKeys(userID)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get API keys", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.APIKeyResp, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, model.ToAPIKeyResponse(k))
+	}
+
+	return SuccessResponse(c, 200, "API keys retrieved", map[string]interface{}{
+		"total":   len(result),
+		"apiKeys": result,
+	})
+}
+
+// DELETE /api-keys/:keyId - revoke key
+func RevokeAPIKey(c echo.Context) error {
+	p := currentPrincipal(c)
+
+	keyID, err := strconv.ParseInt(c.Param("keyId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid API key ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	key, err := model.GetAPIKey(keyID)
+	if err != nil {
+		if errors.Is(err, model.ErrAPIKeyNotFound) {
+			return ErrorResponse(c, 404, "API key not found", "API_KEY_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get API key", "DB_ERROR", err.Error())
+	}
+
+	if key.UserID != p.UserID && !p.IsAdmin() {
+		return ErrorResponse(c, 404, "API key not found", "API_KEY_NOT_FOUND", "")
+	}
+
+	if err := model.RevokeAPIKey(keyID); err != nil {
+		if errors.Is(err, model.ErrAPIKeyNotFound) {
+			return ErrorResponse(c, 400, "API key already revoked", "API_KEY_REVOKED", "")
+		}
+		return ErrorResponse(c, 500, "Failed to revoke API key", "DB_UPDATE_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "API key revoked", map[string]interface{}{
+		"keyId": keyID,
+	})
+}
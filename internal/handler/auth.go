@@ -3,29 +3,39 @@ package handler
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"gowa-yourself/internal/helper"
 	"gowa-yourself/internal/model"
 	"gowa-yourself/internal/service"
 	"gowa-yourself/internal/ws"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow"
 )
 
 // Simpan cancel functions untuk setiap instance
 var qrCancelFuncs = make(map[string]context.CancelFunc)
 var qrCancelMutex sync.RWMutex
-var JwtKey = []byte("secret_key_rahasia") // lebih baik dari ENV
+
+// JwtKey & TTL di-set dari config (lihat main.go), jangan hard-code
+var JwtKey []byte
+var AccessTokenTTL = 1 * time.Hour
+var RefreshTokenTTL = 30 * 24 * time.Hour
 
 type Claims struct {
+	UserID   int64  `json:"uid"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -35,11 +45,15 @@ type Claims struct {
 //
 //**********************************
 
-func GenerateJWT(username string) (string, error) {
-	exp := time.Now().Add(1 * time.Hour)
+func GenerateJWT(user *model.User) (string, error) {
+	exp := time.Now().Add(AccessTokenTTL)
 	claims := Claims{
-		Username: username,
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
 	}
@@ -47,6 +61,39 @@ func GenerateJWT(username string) (string, error) {
 	return token.SignedString(JwtKey)
 }
 
+// NewClaims dipakai echojwt (NewClaimsFunc) supaya token di-parse ke *Claims
+func NewClaims(c echo.Context) jwt.Claims {
+	return new(Claims)
+}
+
+// currentClaims ambil claims JWT dari context (nil kalau tidak ada)
+func currentClaims(c echo.Context) *Claims {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil
+	}
+	claims, _ := token.Claims.(*Claims)
+	return claims
+}
+
+// issueTokens buat pasangan access token + refresh token untuk user
+func issueTokens(c echo.Context, user *model.User) error {
+	token, err := GenerateJWT(user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating token"})
+	}
+	refreshToken, err := service.IssueRefreshToken(user.ID, RefreshTokenTTL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating refresh token"})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"token":        token,
+		"tokenType":    "Bearer",
+		"expiresIn":    int64(AccessTokenTTL.Seconds()),
+		"refreshToken": refreshToken,
+	})
+}
+
 func LoginJWT(c echo.Context) error {
 	var creds struct {
 		Username string `json:"username"`
@@ -55,28 +102,55 @@ func LoginJWT(c echo.Context) error {
 	if err := c.Bind(&creds); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Bad request"})
 	}
-	if creds.Username != "sudevwa" || creds.Password != "5ud3vw4" {
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
+
+	user, err := service.Authenticate(creds.Username, creds.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
+		}
+		if errors.Is(err, service.ErrUserDisabled) {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "User is disabled"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error checking credentials"})
+	}
+
+	return issueTokens(c, user)
+}
+
+// POST /refresh-token - tukar refresh token (sekali pakai) dengan token baru
+func RefreshJWT(c echo.Context) error {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := c.Bind(&req); err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Field 'refreshToken' is required"})
 	}
-	token, err := GenerateJWT(creds.Username)
+
+	user, err := service.RotateRefreshToken(req.RefreshToken)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating token"})
+		if errors.Is(err, model.ErrRefreshTokenInvalid) || errors.Is(err, service.ErrUserDisabled) || errors.Is(err, model.ErrUserNotFound) {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired refresh token"})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error refreshing token"})
 	}
-	return c.JSON(http.StatusOK, map[string]string{"token": token})
+
+	return issueTokens(c, user)
 }
 
 func ValidateToken(c echo.Context) error {
 	// JWT middleware sudah validasi token sebelum sampai sini
 	// Jika sampai ke handler ini berarti token valid
-	// Optional: Ambil username dari token claims
-	user := c.Get("user").(*jwt.Token)
-	claims := user.Claims.(jwt.MapClaims)
-	username := claims["username"].(string)
+	claims := currentClaims(c)
+	if claims == nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success":  true,
 		"message":  "Token is valid",
-		"username": username,
+		"username": claims.Username,
+		"userId":   claims.UserID,
+		"role":     claims.Role,
 	})
 }
 
@@ -95,6 +169,15 @@ func generateInstanceID() string {
 
 // POST /login
 func Login(c echo.Context) error {
+	// API key yang dibatasi ke instance tertentu tidak boleh membuat instance baru
+	principal := currentPrincipal(c)
+	if principal == nil {
+		return ErrorResponse(c, 401, "Authentication required", "UNAUTHORIZED", "")
+	}
+	if principal.APIKey != nil && principal.APIKey.IsScoped() {
+		return ErrorResponse(c, 403, "Scoped API key cannot create new instances", "PERMISSION_DENIED", "")
+	}
+
 	instanceID := generateInstanceID()
 
 	session, err := service.CreateSession(instanceID)
@@ -123,6 +206,7 @@ func Login(c echo.Context) error {
 		Status:      "qr_required",
 		IsConnected: false,
 		CreatedAt:   time.Now(),
+		OwnerID:     sql.NullInt64{Int64: principal.UserID, Valid: principal.UserID != 0},
 	}
 	err = model.InsertInstance(instance)
 	if err != nil {
@@ -404,6 +488,193 @@ func CancelQR(c echo.Context) error {
 	})
 }
 
+// Perkiraan umur kode pairing: websocket login ditutup setelah QR habis (~160 detik)
+const pairCodeTTL = 160 * time.Second
+
+// Request body untuk pairing via nomor HP
+type PairPhoneRequest struct {
+	PhoneNumber string `json:"phoneNumber" validate:"required"`
+}
+
+// POST /pair-phone/:instanceId - login dengan kode pairing (tanpa scan QR)
+func PairPhone(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	var req PairPhoneRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+	if req.PhoneNumber == "" {
+		return ErrorResponse(c, 400, "Field 'phoneNumber' is required", "VALIDATION_ERROR", "")
+	}
+
+	jid, err := helper.FormatPhoneNumber(req.PhoneNumber)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE_NUMBER", err.Error())
+	}
+	phoneNumber := jid.User
+
+	// QR & pairing code memakai websocket login yang sama, jadi tidak boleh jalan bersamaan
+	qrCancelMutex.RLock()
+	_, exists := qrCancelFuncs[instanceID]
+	qrCancelMutex.RUnlock()
+
+	if exists {
+		return ErrorResponse(c, 409, "QR / pairing already in progress, please wait", "QR_IN_PROGRESS", "Please wait or cancel the current login first.")
+	}
+
+	inst, err := model.GetInstanceByInstanceID(instanceID)
+	if err != nil {
+		return ErrorResponse(c, 404, "Instance not found", "INSTANCE_NOT_FOUND", err.Error())
+	}
+
+	if inst.Status == "logged_out" {
+		return ErrorResponse(c, 400,
+			"This instance is logged out and cannot be reused. Please create a new instance for this number.",
+			"INSTANCE_LOGGED_OUT",
+			"",
+		)
+	}
+
+	session, err := service.GetSession(instanceID)
+	if err != nil {
+		return ErrorResponse(c, 404, "Session not found. Please create a new instance first.", "SESSION_NOT_FOUND", "")
+	}
+
+	if session.IsConnected || session.Client.Store.ID != nil {
+		return ErrorResponse(c, 400, "Instance is already paired", "ALREADY_PAIRED", "")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+
+	qrCancelMutex.Lock()
+	qrCancelFuncs[instanceID] = cancel
+	qrCancelMutex.Unlock()
+
+	cleanup := func() {
+		qrCancelMutex.Lock()
+		delete(qrCancelFuncs, instanceID)
+		qrCancelMutex.Unlock()
+		cancel()
+	}
+
+	// PairPhone butuh websocket login yang sudah siap: connect lalu tunggu item pertama QR channel
+	qrChan, err := session.Client.GetQRChannel(ctx)
+	if err != nil {
+		cleanup()
+		return ErrorResponse(c, 500, "Failed to start pairing", "PAIR_FAILED", err.Error())
+	}
+
+	if err := session.Client.Connect(); err != nil {
+		cleanup()
+		return ErrorResponse(c, 500, "Failed to connect", "CONNECT_FAILED", err.Error())
+	}
+
+	select {
+	case evt, ok := <-qrChan:
+		if !ok || evt.Event != "code" {
+			cleanup()
+			session.Client.Disconnect()
+			return ErrorResponse(c, 500, "Failed to start pairing", "PAIR_FAILED", "unexpected login event: "+evt.Event)
+		}
+	case <-time.After(15 * time.Second):
+		cleanup()
+		session.Client.Disconnect()
+		return ErrorResponse(c, 504, "Timeout waiting for WhatsApp login", "PAIR_TIMEOUT", "")
+	}
+
+	code, err := session.Client.PairPhone(ctx, phoneNumber, true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+	if err != nil {
+		cleanup()
+		session.Client.Disconnect()
+		return ErrorResponse(c, 400, "Failed to generate pairing code", "PAIR_FAILED", err.Error())
+	}
+
+	expiresAt := time.Now().Add(pairCodeTTL)
+	if err := model.UpdateInstancePairCode(instanceID, code, expiresAt); err != nil {
+		log.Printf("Failed to update pairing code in database for instance %s: %v", instanceID, err)
+	}
+
+	if service.Realtime != nil {
+		service.Realtime.Publish(ws.WsEvent{
+			Event:     ws.EventPairCodeGenerated,
+			Timestamp: time.Now().UTC(),
+			Data: ws.PairCodeGeneratedData{
+				InstanceID:  instanceID,
+				PhoneNumber: phoneNumber,
+				PairCode:    code,
+				ExpiresAt:   expiresAt,
+			},
+		})
+	}
+
+	// Tunggu hasil pairing di background; QR yang tetap di-generate whatsmeow diabaikan
+	go func() {
+		defer cleanup()
+
+		for {
+			select {
+			case <-ctx.Done():
+				println("\n✗ Pairing cancelled or timeout for instance:", instanceID)
+				publishLoginEvent(ws.EventQRTimeout, instanceID, map[string]interface{}{
+					"status": "cancelled",
+					"reason": ctx.Err().Error(),
+				})
+				return
+
+			case evt, ok := <-qrChan:
+				if !ok {
+					publishLoginEvent(ws.EventInstanceError, instanceID, map[string]interface{}{
+						"error": "Pairing channel closed unexpectedly",
+					})
+					return
+				}
+
+				switch {
+				case evt.Event == "code":
+					continue
+				case evt.Event == "success":
+					println("\n✓ Pairing code accepted for instance:", instanceID)
+					publishLoginEvent(ws.EventQRSuccess, instanceID, map[string]interface{}{
+						"status": "connected",
+					})
+				case evt.Event == "timeout":
+					println("\n✗ Pairing timeout for instance:", instanceID)
+					publishLoginEvent(ws.EventQRTimeout, instanceID, map[string]interface{}{
+						"status": "timeout",
+					})
+				case strings.HasPrefix(evt.Event, "err-"):
+					publishLoginEvent(ws.EventInstanceError, instanceID, map[string]interface{}{
+						"error": evt.Event,
+					})
+				}
+				return
+			}
+		}
+	}()
+
+	return SuccessResponse(c, 200, "Pairing code generated", map[string]interface{}{
+		"instanceId":  instanceID,
+		"phoneNumber": phoneNumber,
+		"pairCode":    code,
+		"expiresAt":   expiresAt,
+		"nextStep":    "Open WhatsApp > Linked devices > Link with phone number, then enter the code",
+	})
+}
+
+// publishLoginEvent kirim event login (QR / pairing) dengan payload map + instance_id
+func publishLoginEvent(event, instanceID string, data map[string]interface{}) {
+	if service.Realtime == nil {
+		return
+	}
+	data["instance_id"] = instanceID
+	service.Realtime.Publish(ws.WsEvent{
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+}
+
 // GET /status/:instanceId
 func GetStatus(c echo.Context) error {
 	instanceID := c.Param("instanceId")
@@ -424,8 +695,10 @@ func GetStatus(c echo.Context) error {
 func GetAllInstances(c echo.Context) error {
 	showAll := c.QueryParam("all") == "true"
 
-	// Ambil semua instance dari table custom
-	dbInstances, err := model.GetAllInstances()
+	principal := currentPrincipal(c)
+
+	// Ambil semua instance dari table custom (non-admin hanya instance miliknya)
+	dbInstances, err := model.GetAllInstances(principal.OwnerScope())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
 			"success": false,
@@ -441,6 +714,11 @@ func GetAllInstances(c echo.Context) error {
 	for _, inst := range dbInstances {
 		log.Printf("🔍 Processing instance: %s", inst.InstanceID)
 
+		// API key ber-scope hanya melihat instance miliknya
+		if !principal.CanAccessInstance(&inst) {
+			continue
+		}
+
 		// Convert dari model.Instance ke model.InstanceResp (string primitif)
 		resp := model.ToResponse(inst)
 
@@ -490,6 +768,37 @@ func GetAllInstances(c echo.Context) error {
 	})
 }
 
+// GET /instances/:instanceId?refresh=true - detail satu instance (profil, status, reconnect)
+func GetInstance(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	// refresh=true: ambil ulang profil dari WhatsApp sebelum dikembalikan
+	if c.QueryParam("refresh") == "true" {
+		if err := service.RefreshProfile(instanceID); err != nil {
+			log.Printf("Failed to refresh profile for instance %s: %v", instanceID, err)
+		}
+	}
+
+	inst, err := model.GetInstanceByInstanceID(instanceID)
+	if err != nil {
+		return ErrorResponse(c, 404, "Instance not found", "INSTANCE_NOT_FOUND", err.Error())
+	}
+
+	resp := model.ToResponse(*inst)
+
+	session, err := service.GetSession(instanceID)
+	if err == nil {
+		resp.IsConnected = session.IsConnected
+		resp.JID = session.JID
+		if resp.IsConnected {
+			resp.Status = "online"
+		}
+	}
+	resp.ExistsInWhatsmeow = err == nil
+
+	return SuccessResponse(c, 200, "Instance retrieved", resp)
+}
+
 // POST /logout/:instanceId
 func Logout(c echo.Context) error {
 	instanceID := c.Param("instanceId")
@@ -504,6 +813,29 @@ func Logout(c echo.Context) error {
 	})
 }
 
+// POST /reconnect/:instanceId - mulai ulang reconnect manual (mis. status needs_attention)
+func ReconnectInstance(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	if err := service.ReconnectInstance(instanceID); err != nil {
+		if errors.Is(err, service.ErrInstanceNotFound) {
+			return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "")
+		}
+		if errors.Is(err, service.ErrNotPaired) {
+			return ErrorResponse(c, 400, "Instance is not paired yet. Please scan QR first.", "NOT_PAIRED", "")
+		}
+		if errors.Is(err, service.ErrAlreadyConnected) {
+			return ErrorResponse(c, 400, "Instance is already connected", "ALREADY_CONNECTED", "")
+		}
+		return ErrorResponse(c, 500, "Failed to reconnect", "RECONNECT_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Reconnect scheduled", map[string]interface{}{
+		"instanceId": instanceID,
+		"status":     service.StatusReconnecting,
+	})
+}
+
 // DELETE /instances/:instanceId
 func DeleteInstance(c echo.Context) error {
 	instanceID := c.Param("instanceId")
@@ -540,3 +872,38 @@ func DeleteInstance(c echo.Context) error {
 		"instanceId": instanceID,
 	})
 }
+
+// PUT /instances/:instanceId/owner (admin) - pindahkan instance ke user lain,
+// juga dipakai untuk meng-assign instance lama yang belum punya owner
+func SetInstanceOwner(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	var req struct {
+		UserID int64 `json:"userId"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+	if req.UserID == 0 {
+		return ErrorResponse(c, 400, "Field 'userId' is required", "VALIDATION_ERROR", "")
+	}
+
+	if _, err := model.GetUserByID(req.UserID); err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return ErrorResponse(c, 404, "User not found", "USER_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get user", "DB_ERROR", err.Error())
+	}
+
+	if err := model.UpdateInstanceOwner(instanceID, req.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, 404, "Instance not found", "INSTANCE_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to update instance owner", "DB_UPDATE_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Instance owner updated", map[string]interface{}{
+		"instanceId": instanceID,
+		"ownerId":    req.UserID,
+	})
+}
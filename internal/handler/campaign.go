@@ -0,0 +1,435 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+const (
+	campaignMaxRecipients  = 50000
+	campaignMinDelayMs     = 500
+	campaignDefaultDelayMs = 3000
+	campaignDefaultJitter  = 2000
+)
+
+// Request body untuk create campaign (JSON). Versi form-data memakai field yang sama,
+// ditambah file `recipientsFile` (CSV) dan file `file` untuk media.
+type CreateCampaignRequest struct {
+	Name       string                   `json:"name"`
+	Recipients []CampaignRecipientInput `json:"recipients"`
+	Message    string                   `json:"message"`    // teks (kalau tanpa media)
+	MediaURL   string                   `json:"mediaUrl"`   // media dari URL
+	Caption    string                   `json:"caption"`    // caption media
+	MediaType  string                   `json:"mediaType"`  // image, video, document, audio
+	TemplateID int64                    `json:"templateId"` // opsional, teks / caption di-render per penerima
+	Variables  map[string]interface{}   `json:"variables"`  // variabel template untuk semua penerima
+	DelayMs    *int                     `json:"delayMs"`    // jeda minimal antar pesan (default 3000)
+	JitterMs   *int                     `json:"jitterMs"`   // tambahan jeda acak (default 2000)
+}
+
+// CampaignRecipientInput penerima campaign: string nomor ("628xxx") atau
+// object {"phone": "628xxx", "variables": {...}} untuk variabel template per penerima
+type CampaignRecipientInput struct {
+	Phone     string                 `json:"phone"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+func (r *CampaignRecipientInput) UnmarshalJSON(data []byte) error {
+	var phone string
+	if err := json.Unmarshal(data, &phone); err == nil {
+		r.Phone = phone
+		return nil
+	}
+
+	type plain CampaignRecipientInput
+	var v plain
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("recipient must be a phone number or {phone, variables} object")
+	}
+	*r = CampaignRecipientInput(v)
+	return nil
+}
+
+// POST /campaigns/:instanceId
+func CreateCampaign(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	var req CreateCampaignRequest
+	isMultipart := strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm)
+	if isMultipart {
+		if err := bindCampaignForm(c, &req); err != nil {
+			return ErrorResponse(c, 400, "Invalid form data", "INVALID_REQUEST", err.Error())
+		}
+	} else if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.Name == "" {
+		req.Name = "Campaign"
+	}
+	if len(req.Recipients) == 0 {
+		return ErrorResponse(c, 400, "Recipients are required", "VALIDATION_ERROR", "Provide 'recipients' array or 'recipientsFile' CSV")
+	}
+	if len(req.Recipients) > campaignMaxRecipients {
+		return ErrorResponse(c, 400, "Too many recipients", "VALIDATION_ERROR",
+			fmt.Sprintf("Max %d recipients per campaign", campaignMaxRecipients))
+	}
+
+	delayMs, jitterMs := campaignDefaultDelayMs, campaignDefaultJitter
+	if req.DelayMs != nil {
+		delayMs = *req.DelayMs
+	}
+	if req.JitterMs != nil {
+		jitterMs = *req.JitterMs
+	}
+	if delayMs < campaignMinDelayMs || jitterMs < 0 {
+		return ErrorResponse(c, 400, "Invalid pacing", "VALIDATION_ERROR",
+			fmt.Sprintf("delayMs must be >= %d and jitterMs must be >= 0", campaignMinDelayMs))
+	}
+
+	// Template: body jadi teks / caption (di-render per penerima oleh runner)
+	var templateBody string
+	if req.TemplateID != 0 {
+		tmpl, err := model.GetTemplate(req.TemplateID, ownerScope(c))
+		if err != nil {
+			return templateErrorResponse(c, err)
+		}
+		templateBody = tmpl.Body
+		req.Message, req.Caption = tmpl.Body, tmpl.Body
+		if req.MediaURL == "" {
+			req.MediaURL = tmpl.MediaURL.String
+		}
+		if req.MediaType == "" {
+			req.MediaType = tmpl.MediaType.String
+		}
+	}
+
+	session, err := service.GetSession(instanceID)
+	if err != nil {
+		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login first")
+	}
+	if !session.IsConnected || !session.Client.IsConnected() || session.Client.Store.ID == nil {
+		return ErrorResponse(c, 400, "WhatsApp session is not connected", "NOT_CONNECTED", "Please scan QR or reconnect")
+	}
+
+	// Media di-upload sekali, pesan yang sama dikirim ke semua penerima
+	var msg *waE2E.Message
+	var fileData []byte
+	var filename string
+	var contentType string // Content-Type upload / response download, fallback deteksi MIME
+
+	if isMultipart {
+		if file, err := c.FormFile("file"); err == nil {
+			src, err := file.Open()
+			if err != nil {
+				return ErrorResponse(c, 500, "Failed to open file", "FILE_OPEN_FAILED", err.Error())
+			}
+			defer src.Close()
+
+			fileData, err = io.ReadAll(src)
+			if err != nil {
+				return ErrorResponse(c, 500, "Failed to read file", "FILE_READ_FAILED", err.Error())
+			}
+			filename = file.Filename
+			contentType = file.Header.Get("Content-Type")
+		}
+	}
+	if fileData == nil && req.MediaURL != "" {
+		fileData, filename, contentType, err = helper.DownloadFile(req.MediaURL)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to download file", "DOWNLOAD_FAILED", err.Error())
+		}
+	}
+
+	if fileData != nil {
+		mimeType := helper.DetectMime(fileData, contentType, filename)
+		mediaType := req.MediaType
+		if mediaType == "" {
+			mediaType = helper.MediaTypeFromMime(mimeType)
+		}
+
+		maxSize := getMaxFileSize(mediaType)
+		if len(fileData) > maxSize {
+			return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
+				fmt.Sprintf("File size: %d bytes, Max: %d bytes (%s)", len(fileData), maxSize, mediaType))
+		}
+
+		whatsmeowMediaType := helper.WhatsmeowMediaType(mediaType)
+
+		uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED", err.Error())
+		}
+		msg = helper.CreateMediaMessage(uploaded, req.Caption, filename, mediaType, mimeType)
+	} else {
+		if req.Message == "" {
+			return ErrorResponse(c, 400, "Field 'message' or media is required", "VALIDATION_ERROR", "")
+		}
+		msg = &waE2E.Message{
+			Conversation: &req.Message,
+		}
+	}
+
+	targets := make([]service.CampaignTarget, len(req.Recipients))
+	for i, r := range req.Recipients {
+		targets[i] = service.CampaignTarget{Phone: r.Phone, Variables: r.Variables}
+	}
+
+	campaign, err := service.CreateCampaign(instanceID, req.Name, msg, targets, templateBody, req.Variables, delayMs, jitterMs)
+	if err != nil {
+		var missing *service.RecipientVariablesError
+		if errors.Is(err, service.ErrCampaignNoRecipients) {
+			return ErrorResponse(c, 400, "Recipients are required", "VALIDATION_ERROR", err.Error())
+		}
+		if errors.As(err, &missing) {
+			return ErrorResponse(c, 400, "Missing template variables", "TEMPLATE_VARIABLES_MISSING", err.Error())
+		}
+		if errors.Is(err, service.ErrCampaignTooLong) {
+			return ErrorResponse(c, 400, "Campaign too long for media", "CAMPAIGN_TOO_LONG", err.Error())
+		}
+		return ErrorResponse(c, 500, "Failed to create campaign", "DB_INSERT_FAILED", err.Error())
+	}
+
+	stats, _ := model.GetCampaignStats(campaign.ID)
+	return SuccessResponse(c, 201, "Campaign created", model.ToCampaignResponse(*campaign, stats))
+}
+
+// bindCampaignForm isi request dari form-data; penerima dari field `recipients`
+// (dipisah koma / baris baru) dan/atau file CSV `recipientsFile`
+func bindCampaignForm(c echo.Context, req *CreateCampaignRequest) error {
+	req.Name = c.FormValue("name")
+	req.Message = c.FormValue("message")
+	req.MediaURL = c.FormValue("mediaUrl")
+	req.Caption = c.FormValue("caption")
+	req.MediaType = c.FormValue("mediaType")
+
+	templateID, vars, err := formTemplateParams(c)
+	if err != nil {
+		return err
+	}
+	req.TemplateID, req.Variables = templateID, vars
+
+	if v := c.FormValue("delayMs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("delayMs must be a number")
+		}
+		req.DelayMs = &n
+	}
+	if v := c.FormValue("jitterMs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("jitterMs must be a number")
+		}
+		req.JitterMs = &n
+	}
+
+	for _, phone := range strings.FieldsFunc(c.FormValue("recipients"), func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ';'
+	}) {
+		if phone = strings.TrimSpace(phone); phone != "" {
+			req.Recipients = append(req.Recipients, CampaignRecipientInput{Phone: phone})
+		}
+	}
+
+	file, err := c.FormFile("recipientsFile")
+	if err != nil {
+		return nil
+	}
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	recipients, err := parseRecipientsCSV(src)
+	if err != nil {
+		return fmt.Errorf("invalid recipients CSV: %w", err)
+	}
+	req.Recipients = append(req.Recipients, recipients...)
+	return nil
+}
+
+// parseRecipientsCSV ambil nomor dari kolom pertama. Kalau ada baris header (tanpa angka),
+// kolom berikutnya dipakai sebagai variabel template per penerima, mis. "phone,name,order_id".
+func parseRecipientsCSV(r io.Reader) ([]CampaignRecipientInput, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var header []string
+	recipients := make([]CampaignRecipientInput, 0)
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 0 {
+			continue
+		}
+
+		phone := strings.TrimSpace(record[0])
+		if row == 0 && !strings.ContainsAny(phone, "0123456789") {
+			header = record
+			continue
+		}
+		if phone == "" {
+			continue
+		}
+
+		recipient := CampaignRecipientInput{Phone: phone}
+		for i := 1; i < len(record) && i < len(header); i++ {
+			name, value := strings.TrimSpace(header[i]), strings.TrimSpace(record[i])
+			if name == "" || value == "" {
+				continue
+			}
+			if recipient.Variables == nil {
+				recipient.Variables = make(map[string]interface{})
+			}
+			recipient.Variables[name] = value
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// GET /campaigns/:instanceId?status=&page=&limit=
+func GetCampaigns(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	status := c.QueryParam("status")
+	page, limit := parsePagination(c)
+
+	campaigns, total, err := model.GetCampaigns(instanceID, status, limit, (page-1)*limit)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get campaigns", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.CampaignResp, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		stats, err := model.GetCampaignStats(campaign.ID)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to get campaign progress", "DB_ERROR", err.Error())
+		}
+		result = append(result, model.ToCampaignResponse(campaign, stats))
+	}
+
+	return SuccessResponse(c, 200, "Campaigns retrieved", map[string]interface{}{
+		"instanceId": instanceID,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"campaigns":  result,
+	})
+}
+
+// GET /campaigns/:instanceId/:campaignId
+func GetCampaign(c echo.Context) error {
+	campaign, err := campaignFromParam(c)
+	if err != nil {
+		return campaignErrorResponse(c, err)
+	}
+
+	stats, err := model.GetCampaignStats(campaign.ID)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get campaign progress", "DB_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Campaign retrieved", model.ToCampaignResponse(*campaign, stats))
+}
+
+// GET /campaigns/:instanceId/:campaignId/recipients?status=&page=&limit=
+func GetCampaignRecipients(c echo.Context) error {
+	campaign, err := campaignFromParam(c)
+	if err != nil {
+		return campaignErrorResponse(c, err)
+	}
+
+	status := c.QueryParam("status")
+	page, limit := parsePagination(c)
+
+	recipients, total, err := model.GetCampaignRecipients(campaign.ID, status, limit, (page-1)*limit)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get campaign recipients", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.CampaignRecipientResp, 0, len(recipients))
+	for _, r := range recipients {
+		result = append(result, model.ToCampaignRecipientResponse(r))
+	}
+
+	return SuccessResponse(c, 200, "Campaign recipients retrieved", map[string]interface{}{
+		"campaignId": campaign.ID,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"recipients": result,
+	})
+}
+
+// POST /campaigns/:instanceId/:campaignId/pause
+func PauseCampaign(c echo.Context) error {
+	return campaignAction(c, service.PauseCampaign, "Campaign paused")
+}
+
+// POST /campaigns/:instanceId/:campaignId/resume
+func ResumeCampaign(c echo.Context) error {
+	return campaignAction(c, service.ResumeCampaign, "Campaign resumed")
+}
+
+// POST /campaigns/:instanceId/:campaignId/cancel
+func CancelCampaign(c echo.Context) error {
+	return campaignAction(c, service.CancelCampaign, "Campaign cancelled")
+}
+
+func campaignAction(c echo.Context, action func(string, int64) (*model.Campaign, error), message string) error {
+	campaignID, err := strconv.ParseInt(c.Param("campaignId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid campaign id", "VALIDATION_ERROR", err.Error())
+	}
+
+	campaign, err := action(c.Param("instanceId"), campaignID)
+	if err != nil {
+		return campaignErrorResponse(c, err)
+	}
+
+	stats, _ := model.GetCampaignStats(campaign.ID)
+	return SuccessResponse(c, 200, message, model.ToCampaignResponse(*campaign, stats))
+}
+
+func campaignFromParam(c echo.Context) (*model.Campaign, error) {
+	campaignID, err := strconv.ParseInt(c.Param("campaignId"), 10, 64)
+	if err != nil {
+		return nil, model.ErrCampaignNotFound
+	}
+	return model.GetCampaign(c.Param("instanceId"), campaignID)
+}
+
+func campaignErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, model.ErrCampaignNotFound):
+		return ErrorResponse(c, 404, "Campaign not found", "CAMPAIGN_NOT_FOUND", "")
+	case errors.Is(err, service.ErrCampaignNotRunning),
+		errors.Is(err, service.ErrCampaignNotPaused),
+		errors.Is(err, service.ErrCampaignFinished):
+		return ErrorResponse(c, 409, "Invalid campaign state", "INVALID_CAMPAIGN_STATE", err.Error())
+	case errors.Is(err, service.ErrCampaignMediaExpired):
+		return ErrorResponse(c, 409, "Campaign media expired", "CAMPAIGN_MEDIA_EXPIRED", err.Error())
+	}
+	return ErrorResponse(c, 500, "Failed to process campaign", "DB_ERROR", err.Error())
+}
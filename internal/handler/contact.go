@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"gowa-yourself/internal/helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+const maxContactsPerMessage = 50
+
+// Request body kirim kartu kontak (vCard); satu kontak → ContactMessage, lebih → ContactsArrayMessage
+type SendContactRequest struct {
+	To       string               `json:"to"`       // personal
+	GroupJID string               `json:"groupJid"` // group
+	Contacts []ContactCardRequest `json:"contacts"`
+	SendAt   string               `json:"sendAt"` // opsional, RFC3339 / unix detik → kirim terjadwal
+}
+
+type ContactCardRequest struct {
+	FullName     string                `json:"fullName"`
+	FirstName    string                `json:"firstName"`
+	LastName     string                `json:"lastName"`
+	Organization string                `json:"organization"`
+	Title        string                `json:"title"`
+	Phones       []ContactPhoneRequest `json:"phones"`
+	Emails       []string              `json:"emails"`
+	URL          string                `json:"url"`
+	Note         string                `json:"note"`
+	VCard        string                `json:"vcard"` // opsional, vCard mentah (field lain diabaikan)
+}
+
+type ContactPhoneRequest struct {
+	Number string `json:"number"`
+	Type   string `json:"type"` // CELL (default), WORK, HOME, ...
+}
+
+// POST /send/:instanceId/contact & /by-number/:phoneNumber/contact
+func SendContact(c echo.Context) error {
+	return sendContact(c, false)
+}
+
+// POST /send-group/:instanceId/contact & /send-group/by-number/:phoneNumber/contact
+func SendGroupContact(c echo.Context) error {
+	return sendContact(c, true)
+}
+
+func sendContact(c echo.Context, group bool) error {
+	var req SendContactRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if len(req.Contacts) == 0 {
+		return ErrorResponse(c, 400, "Field 'contacts' is required", "VALIDATION_ERROR", "")
+	}
+	if len(req.Contacts) > maxContactsPerMessage {
+		return ErrorResponse(c, 400, "Too many contacts", "VALIDATION_ERROR", "Maximum 50 contacts per message")
+	}
+
+	cards := make([]helper.ContactCard, 0, len(req.Contacts))
+	for _, rc := range req.Contacts {
+		card := helper.ContactCard{
+			FullName:     rc.FullName,
+			FirstName:    rc.FirstName,
+			LastName:     rc.LastName,
+			Organization: rc.Organization,
+			Title:        rc.Title,
+			Emails:       rc.Emails,
+			URL:          rc.URL,
+			Note:         rc.Note,
+			VCard:        rc.VCard,
+		}
+		for _, p := range rc.Phones {
+			card.Phones = append(card.Phones, helper.ContactPhone{Number: p.Number, Type: p.Type})
+		}
+		cards = append(cards, card)
+	}
+
+	msg, err := helper.CreateContactMessage(cards)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid contact", "VALIDATION_ERROR", err.Error())
+	}
+
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+
+	to := req.To
+	if group {
+		to = req.GroupJID
+	}
+	target, err := resolveSendTarget(c, to, group)
+	if err != nil {
+		return targetErrorResponse(c, err)
+	}
+
+	return sendToTarget(c, target, msg, sendAt, "Contact sent successfully", map[string]interface{}{
+		"contacts": len(cards),
+	})
+}
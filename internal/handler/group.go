@@ -3,23 +3,22 @@ package handler
 import (
 	"context"
 	"errors"
-	"fmt"
-	"io"
 
-	"gowa-yourself/internal/helper"
 	"gowa-yourself/internal/model"
 	"gowa-yourself/internal/service"
 
 	"github.com/labstack/echo/v4"
-	"go.mau.fi/whatsmeow"
-	"go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 )
 
 // Request untuk send group message
 type SendGroupMessageRequest struct {
-	GroupJID string `json:"groupJid" validate:"required"`
-	Message  string `json:"message" validate:"required"`
+	GroupJID   string                 `json:"groupJid" validate:"required"`
+	Message    string                 `json:"message" validate:"required"`
+	SendAt     string                 `json:"sendAt"`     // opsional, RFC3339 / unix detik → kirim terjadwal
+	TemplateID int64                  `json:"templateId"` // opsional, isi message / caption dari template
+	Variables  map[string]interface{} `json:"variables"`  // nilai placeholder template
+	TextMessageOptions
 }
 
 // GET /groups/:instanceId - List all groups
@@ -72,65 +71,22 @@ func SendGroupMessage(c echo.Context) error {
 		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
 	}
 
-	if req.GroupJID == "" || req.Message == "" {
-		return ErrorResponse(c, 400, "Fields 'groupJid' and 'message' are required", "VALIDATION_ERROR", "")
-	}
-
-	session, err := service.GetSession(instanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "")
-	}
-
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "")
-	}
-
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "")
-	}
-
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "")
-	}
-
-	// Parse group JID
-	groupJID, err := types.ParseJID(req.GroupJID)
-	if err != nil {
-		return ErrorResponse(c, 400, "Invalid group JID", "INVALID_GROUP_JID", err.Error())
-	}
-
-	// Validate it's a group JID (ends with @g.us)
-	if groupJID.Server != types.GroupServer {
-		return ErrorResponse(c, 400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us")
+	// Template: message diisi dari hasil render template
+	if req.TemplateID != 0 {
+		_, text, err := applyTemplate(c, req.TemplateID, req.Variables)
+		if err != nil {
+			return templateErrorResponse(c, err)
+		}
+		req.Message = text
 	}
 
-	// Create message
-	msg := &waE2E.Message{
-		Conversation: &req.Message,
+	if req.GroupJID == "" || req.Message == "" {
+		return ErrorResponse(c, 400, "Fields 'groupJid' and 'message' are required", "VALIDATION_ERROR", "")
 	}
 
-	// Send to group
-	resp, err := session.Client.SendMessage(context.Background(), groupJID, msg)
+	sendAt, err := parseSendAt(req.SendAt)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send message", "SEND_FAILED", err.Error())
-	}
-
-	return SuccessResponse(c, 200, "Message sent to group", map[string]interface{}{
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"groupJid":  req.GroupJID,
-	})
-}
-
-// POST /send-group/:instanceId/media - Send media to group
-func SendGroupMedia(c echo.Context) error {
-	instanceID := c.Param("instanceId")
-
-	groupJid := c.FormValue("groupJid")
-	caption := c.FormValue("caption")
-
-	if groupJid == "" {
-		return ErrorResponse(c, 400, "Field 'groupJid' is required", "VALIDATION_ERROR", "")
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
 	}
 
 	session, err := service.GetSession(instanceID)
@@ -151,166 +107,39 @@ func SendGroupMedia(c echo.Context) error {
 	}
 
 	// Parse group JID
-	groupJID, err := types.ParseJID(groupJid)
-	if err != nil {
-		return ErrorResponse(c, 400, "Invalid group JID", "INVALID_GROUP_JID", err.Error())
-	}
-
-	if groupJID.Server != types.GroupServer {
-		return ErrorResponse(c, 400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us")
-	}
-
-	// Get file
-	file, err := c.FormFile("file")
-	if err != nil {
-		return ErrorResponse(c, 400, "File is required", "FILE_REQUIRED", err.Error())
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to open file", "FILE_OPEN_FAILED", err.Error())
-	}
-	defer src.Close()
-
-	fileData, err := io.ReadAll(src)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to read file", "FILE_READ_FAILED", err.Error())
-	}
-
-	mediaType := helper.DetectMediaType(file.Filename)
-
-	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
-			fmt.Sprintf("File: %d bytes, Max: %d bytes", len(fileData), maxSize))
-	}
-
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
-	}
-
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED", err.Error())
-	}
-
-	msg := helper.CreateMediaMessage(uploaded, caption, file.Filename, mediaType)
-
-	resp, err := session.Client.SendMessage(context.Background(), groupJID, msg)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
-	}
-
-	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"groupJid":  groupJid,
-		"mediaType": mediaType,
-		"fileName":  file.Filename,
-		"fileSize":  len(fileData),
-	})
-}
-
-// POST /send-group/:instanceId/media-url - Send media from URL to group
-func SendGroupMediaURL(c echo.Context) error {
-	instanceID := c.Param("instanceId")
-
-	var req struct {
-		GroupJID  string `json:"groupJid" validate:"required"`
-		MediaURL  string `json:"mediaUrl" validate:"required"`
-		Caption   string `json:"caption"`
-		MediaType string `json:"mediaType"`
-	}
-
-	if err := c.Bind(&req); err != nil {
-		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
-	}
-
-	if req.GroupJID == "" || req.MediaURL == "" {
-		return ErrorResponse(c, 400, "Fields 'groupJid' and 'mediaUrl' are required", "VALIDATION_ERROR", "")
-	}
-
-	session, err := service.GetSession(instanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "")
-	}
-
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "")
-	}
-
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "")
-	}
-
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "")
-	}
-
 	groupJID, err := types.ParseJID(req.GroupJID)
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid group JID", "INVALID_GROUP_JID", err.Error())
 	}
 
+	// Validate it's a group JID (ends with @g.us)
 	if groupJID.Server != types.GroupServer {
 		return ErrorResponse(c, 400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us")
 	}
 
-	fileData, filename, err := helper.DownloadFile(req.MediaURL)
+	// Create message
+	msg, err := buildTextMessage(session.ID, groupJID, req.Message, req.TextMessageOptions)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to download file", "DOWNLOAD_FAILED", err.Error())
-	}
-
-	mediaType := req.MediaType
-	if mediaType == "" {
-		mediaType = helper.DetectMediaType(filename)
-	}
-
-	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
-			fmt.Sprintf("File: %d bytes, Max: %d bytes", len(fileData), maxSize))
+		return textMessageErrorResponse(c, err)
 	}
 
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
-	}
-
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED", err.Error())
+	// Mode async / terjadwal: masuk outbox atau scheduled_messages
+	if sendAt != nil || isAsyncSend(c) {
+		return queueSend(c, session.ID, groupJID, msg, sendAt, map[string]interface{}{
+			"groupJid": req.GroupJID,
+		})
 	}
 
-	msg := helper.CreateMediaMessage(uploaded, req.Caption, filename, mediaType)
-
-	resp, err := session.Client.SendMessage(context.Background(), groupJID, msg)
+	// Send to group
+	resp, err := service.SendMessage(context.Background(), session.ID, groupJID, msg, nil)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
+		return sendErrorResponse(c, "Failed to send message", err)
 	}
 
-	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
+	return SuccessResponse(c, 200, "Message sent to group", map[string]interface{}{
 		"messageId": resp.ID,
 		"timestamp": resp.Timestamp.Unix(),
 		"groupJid":  req.GroupJID,
-		"mediaType": mediaType,
-		"fileName":  filename,
-		"fileSize":  len(fileData),
 	})
 }
 
@@ -318,7 +147,7 @@ func SendGroupMediaURL(c echo.Context) error {
 func GetGroupsByNumber(c echo.Context) error {
 	phoneNumber := c.Param("phoneNumber")
 
-	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber)
+	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber, ownerScope(c))
 	if err != nil {
 		if errors.Is(err, model.ErrNoActiveInstance) {
 			return ErrorResponse(c, 404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number")
@@ -372,80 +201,26 @@ func SendGroupMessageByNumber(c echo.Context) error {
 		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
 	}
 
-	if req.GroupJID == "" || req.Message == "" {
-		return ErrorResponse(c, 400, "Fields 'groupJid' and 'message' are required", "VALIDATION_ERROR", "")
-	}
-
-	// 1. Cari instance aktif berdasarkan nomor pengirim
-	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber)
-	if err != nil {
-		if errors.Is(err, model.ErrNoActiveInstance) {
-			return ErrorResponse(c, 404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number")
+	// Template: message diisi dari hasil render template
+	if req.TemplateID != 0 {
+		_, text, err := applyTemplate(c, req.TemplateID, req.Variables)
+		if err != nil {
+			return templateErrorResponse(c, err)
 		}
-		return ErrorResponse(c, 500, "Failed to get instance for this phone number", "DB_ERROR", err.Error())
+		req.Message = text
 	}
 
-	// 2. Ambil session dari memory berdasarkan instance_id
-	session, err := service.GetSession(inst.InstanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "")
-	}
-
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "")
-	}
-
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "")
-	}
-
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "")
-	}
-
-	// Parse group JID
-	groupJID, err := types.ParseJID(req.GroupJID)
-	if err != nil {
-		return ErrorResponse(c, 400, "Invalid group JID", "INVALID_GROUP_JID", err.Error())
-	}
-
-	// Validate it's a group JID (ends with @g.us)
-	if groupJID.Server != types.GroupServer {
-		return ErrorResponse(c, 400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us")
-	}
-
-	// Create message
-	msg := &waE2E.Message{
-		Conversation: &req.Message,
+	if req.GroupJID == "" || req.Message == "" {
+		return ErrorResponse(c, 400, "Fields 'groupJid' and 'message' are required", "VALIDATION_ERROR", "")
 	}
 
-	// Send to group
-	resp, err := session.Client.SendMessage(context.Background(), groupJID, msg)
+	sendAt, err := parseSendAt(req.SendAt)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send message", "SEND_FAILED", err.Error())
-	}
-
-	return SuccessResponse(c, 200, "Message sent to group", map[string]interface{}{
-		"from":      phoneNumber,
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"groupJid":  req.GroupJID,
-	})
-}
-
-// POST /send-group/by-number/:phoneNumber/media - Send media to group by sender number
-func SendGroupMediaByNumber(c echo.Context) error {
-	phoneNumber := c.Param("phoneNumber")
-
-	groupJid := c.FormValue("groupJid")
-	caption := c.FormValue("caption")
-
-	if groupJid == "" {
-		return ErrorResponse(c, 400, "Field 'groupJid' is required", "VALIDATION_ERROR", "")
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
 	}
 
 	// 1. Cari instance aktif berdasarkan nomor pengirim
-	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber)
+	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber, ownerScope(c))
 	if err != nil {
 		if errors.Is(err, model.ErrNoActiveInstance) {
 			return ErrorResponse(c, 404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number")
@@ -472,175 +247,40 @@ func SendGroupMediaByNumber(c echo.Context) error {
 	}
 
 	// Parse group JID
-	groupJID, err := types.ParseJID(groupJid)
-	if err != nil {
-		return ErrorResponse(c, 400, "Invalid group JID", "INVALID_GROUP_JID", err.Error())
-	}
-
-	if groupJID.Server != types.GroupServer {
-		return ErrorResponse(c, 400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us")
-	}
-
-	// Get file
-	file, err := c.FormFile("file")
-	if err != nil {
-		return ErrorResponse(c, 400, "File is required", "FILE_REQUIRED", err.Error())
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to open file", "FILE_OPEN_FAILED", err.Error())
-	}
-	defer src.Close()
-
-	fileData, err := io.ReadAll(src)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to read file", "FILE_READ_FAILED", err.Error())
-	}
-
-	mediaType := helper.DetectMediaType(file.Filename)
-
-	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE", fmt.Sprintf("File: %d bytes, Max: %d bytes", len(fileData), maxSize))
-	}
-
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
-	}
-
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED", err.Error())
-	}
-
-	msg := helper.CreateMediaMessage(uploaded, caption, file.Filename, mediaType)
-
-	resp, err := session.Client.SendMessage(context.Background(), groupJID, msg)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
-	}
-
-	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
-		"from":      phoneNumber,
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"groupJid":  groupJid,
-		"mediaType": mediaType,
-		"fileName":  file.Filename,
-		"fileSize":  len(fileData),
-	})
-}
-
-// POST /send-group/by-number/:phoneNumber/media-url - Send media from URL to group by sender number
-func SendGroupMediaURLByNumber(c echo.Context) error {
-	phoneNumber := c.Param("phoneNumber")
-
-	var req struct {
-		GroupJID  string `json:"groupJid" validate:"required"`
-		MediaURL  string `json:"mediaUrl" validate:"required"`
-		Caption   string `json:"caption"`
-		MediaType string `json:"mediaType"`
-	}
-
-	if err := c.Bind(&req); err != nil {
-		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
-	}
-
-	if req.GroupJID == "" || req.MediaURL == "" {
-		return ErrorResponse(c, 400, "Fields 'groupJid' and 'mediaUrl' are required", "VALIDATION_ERROR", "")
-	}
-
-	// 1. Cari instance aktif berdasarkan nomor pengirim
-	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber)
-	if err != nil {
-		if errors.Is(err, model.ErrNoActiveInstance) {
-			return ErrorResponse(c, 404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number")
-		}
-		return ErrorResponse(c, 500, "Failed to get instance for this phone number", "DB_ERROR", err.Error())
-	}
-
-	// 2. Ambil session dari memory berdasarkan instance_id
-	session, err := service.GetSession(inst.InstanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "")
-	}
-
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "")
-	}
-
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "")
-	}
-
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "")
-	}
-
 	groupJID, err := types.ParseJID(req.GroupJID)
 	if err != nil {
 		return ErrorResponse(c, 400, "Invalid group JID", "INVALID_GROUP_JID", err.Error())
 	}
 
+	// Validate it's a group JID (ends with @g.us)
 	if groupJID.Server != types.GroupServer {
 		return ErrorResponse(c, 400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us")
 	}
 
-	fileData, filename, err := helper.DownloadFile(req.MediaURL)
+	// Create message
+	msg, err := buildTextMessage(session.ID, groupJID, req.Message, req.TextMessageOptions)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to download file", "DOWNLOAD_FAILED", err.Error())
-	}
-
-	mediaType := req.MediaType
-	if mediaType == "" {
-		mediaType = helper.DetectMediaType(filename)
-	}
-
-	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE", fmt.Sprintf("File: %d bytes, Max: %d bytes", len(fileData), maxSize))
-	}
-
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
+		return textMessageErrorResponse(c, err)
 	}
 
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED", err.Error())
+	// Mode async / terjadwal: masuk outbox atau scheduled_messages
+	if sendAt != nil || isAsyncSend(c) {
+		return queueSend(c, session.ID, groupJID, msg, sendAt, map[string]interface{}{
+			"from":     phoneNumber,
+			"groupJid": req.GroupJID,
+		})
 	}
 
-	msg := helper.CreateMediaMessage(uploaded, req.Caption, filename, mediaType)
-
-	resp, err := session.Client.SendMessage(context.Background(), groupJID, msg)
+	// Send to group
+	resp, err := service.SendMessage(context.Background(), session.ID, groupJID, msg, nil)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
+		return sendErrorResponse(c, "Failed to send message", err)
 	}
 
-	return SuccessResponse(c, 200, "Media sent to group", map[string]interface{}{
+	return SuccessResponse(c, 200, "Message sent to group", map[string]interface{}{
 		"from":      phoneNumber,
 		"messageId": resp.ID,
 		"timestamp": resp.Timestamp.Unix(),
 		"groupJid":  req.GroupJID,
-		"mediaType": mediaType,
-		"fileName":  filename,
-		"fileSize":  len(fileData),
 	})
 }
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey   = "Idempotency-Key"
+	headerIdempotentReplay = "Idempotent-Replayed"
+	maxIdempotencyKeyLen   = 255
+	maxMultipartMemory     = 32 << 20 // sama dengan default echo c.FormFile
+)
+
+// Idempotent middleware route kirim: request dengan header Idempotency-Key yang sama (per user)
+// dalam window IDEMPOTENCY_WINDOW mendapat response request pertama tanpa mengirim ulang pesan.
+// Response sukses (2xx) dan gagal saat / setelah pesan dikirim ke WhatsApp (hasil tidak pasti, lihat
+// sendErrorResponse) disimpan; gagal validasi / sebelum kirim melepas key supaya bisa di-retry.
+func Idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := strings.TrimSpace(c.Request().Header.Get(HeaderIdempotencyKey))
+		if key == "" {
+			return next(c)
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			return ErrorResponse(c, 400, "Invalid Idempotency-Key header", "VALIDATION_ERROR",
+				"Idempotency-Key must be at most 255 characters")
+		}
+
+		p := currentPrincipal(c)
+		if p == nil || p.UserID == 0 {
+			return next(c) // token lama tanpa user id, tidak bisa di-scope per user
+		}
+
+		requestHash, err := requestFingerprint(c)
+		if err != nil {
+			return ErrorResponse(c, 400, "Failed to read request body", "VALIDATION_ERROR", err.Error())
+		}
+
+		existing, err := service.BeginIdempotentRequest(p.UserID, key, requestHash)
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyInUse):
+			return ErrorResponse(c, 409, "A request with this Idempotency-Key is still in progress", "IDEMPOTENCY_KEY_IN_USE", err.Error())
+		case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+			return ErrorResponse(c, 422, "Idempotency-Key was already used with a different request", "IDEMPOTENCY_KEY_MISMATCH", err.Error())
+		case err != nil:
+			return ErrorResponse(c, 500, "Failed to check Idempotency-Key", "DB_ERROR", err.Error())
+		}
+
+		if existing != nil {
+			c.Response().Header().Set(headerIdempotentReplay, "true")
+			return c.JSONBlob(int(existing.StatusCode.Int64), existing.ResponseBody)
+		}
+
+		res := c.Response()
+		recorder := &responseRecorder{ResponseWriter: res.Writer}
+		res.Writer = recorder
+
+		err = next(c)
+		if err != nil {
+			c.Error(err) // tulis response error sekarang supaya status code final
+		}
+
+		if (res.Status >= 200 && res.Status < 300) || sendAttempted(c) {
+			service.CompleteIdempotentRequest(p.UserID, key, res.Status, recorder.body.Bytes(), responseMessageID(recorder.body.Bytes()))
+		} else {
+			service.ReleaseIdempotentRequest(p.UserID, key)
+		}
+		return nil
+	}
+}
+
+const ctxKeySendAttempted = "idempotency.sendAttempted"
+
+// markSendAttempted tandai request sudah sampai ke Client.SendMessage (response gagal ikut disimpan)
+func markSendAttempted(c echo.Context) {
+	c.Set(ctxKeySendAttempted, true)
+}
+
+func sendAttempted(c echo.Context) bool {
+	attempted, _ := c.Get(ctxKeySendAttempted).(bool)
+	return attempted
+}
+
+// responseRecorder salin body response yang ditulis handler (untuk disimpan & di-replay)
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// requestFingerprint sha256 dari method, path, query dan isi request. Multipart di-hash per field
+// & isi file (boundary berbeda tiap retry), body lain di-hash apa adanya lalu dikembalikan ke request.
+func requestFingerprint(c echo.Context) (string, error) {
+	req := c.Request()
+	h := sha256.New()
+	io.WriteString(h, req.Method+" "+req.URL.Path+"?"+req.URL.RawQuery+"\n")
+
+	if strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		if err := req.ParseMultipartForm(maxMultipartMemory); err != nil {
+			return "", err
+		}
+		if err := hashMultipartForm(h, req); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashMultipartForm(h hash.Hash, req *http.Request) error {
+	form := req.MultipartForm
+
+	names := make([]string, 0, len(form.Value))
+	for name := range form.Value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range form.Value[name] {
+			io.WriteString(h, name+"="+v+"\n")
+		}
+	}
+
+	names = names[:0]
+	for name := range form.File {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, fh := range form.File[name] {
+			io.WriteString(h, name+":"+fh.Filename+"\n")
+			f, err := fh.Open()
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(h, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// responseMessageID ambil data.messageId dari response kirim (kalau ada)
+func responseMessageID(body []byte) string {
+	var resp struct {
+		Data struct {
+			MessageID string `json:"messageId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	return resp.Data.MessageID
+}
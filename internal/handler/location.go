@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"gowa-yourself/internal/helper"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Request body kirim lokasi (pin) / live location
+type SendLocationRequest struct {
+	To             string   `json:"to"`       // personal
+	GroupJID       string   `json:"groupJid"` // group
+	Latitude       *float64 `json:"latitude"`
+	Longitude      *float64 `json:"longitude"`
+	Name           string   `json:"name"`    // nama tempat
+	Address        string   `json:"address"` // alamat
+	URL            string   `json:"url"`
+	Live           bool     `json:"live"`    // true = live location
+	Caption        string   `json:"caption"` // caption live location
+	AccuracyMeters uint32   `json:"accuracyMeters"`
+	SendAt         string   `json:"sendAt"` // opsional, RFC3339 / unix detik → kirim terjadwal
+}
+
+// POST /send/:instanceId/location & /by-number/:phoneNumber/location
+func SendLocation(c echo.Context) error {
+	return sendLocation(c, false)
+}
+
+// POST /send-group/:instanceId/location & /send-group/by-number/:phoneNumber/location
+func SendGroupLocation(c echo.Context) error {
+	return sendLocation(c, true)
+}
+
+func sendLocation(c echo.Context, group bool) error {
+	var req SendLocationRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.Latitude == nil || req.Longitude == nil {
+		return ErrorResponse(c, 400, "Field 'latitude' and 'longitude' are required", "VALIDATION_ERROR", "")
+	}
+	if err := helper.ValidateCoordinates(*req.Latitude, *req.Longitude); err != nil {
+		return ErrorResponse(c, 400, "Invalid coordinates", "VALIDATION_ERROR", err.Error())
+	}
+
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+
+	to := req.To
+	if group {
+		to = req.GroupJID
+	}
+	target, err := resolveSendTarget(c, to, group)
+	if err != nil {
+		return targetErrorResponse(c, err)
+	}
+
+	msg := helper.CreateLocationMessage(helper.Location{
+		Latitude:       *req.Latitude,
+		Longitude:      *req.Longitude,
+		Name:           req.Name,
+		Address:        req.Address,
+		URL:            req.URL,
+		Caption:        req.Caption,
+		AccuracyMeters: req.AccuracyMeters,
+		Live:           req.Live,
+	})
+
+	return sendToTarget(c, target, msg, sendAt, "Location sent successfully", map[string]interface{}{
+		"latitude":  *req.Latitude,
+		"longitude": *req.Longitude,
+		"live":      req.Live,
+	})
+}
@@ -2,77 +2,112 @@ package handler
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"time"
 
 	"gowa-yourself/internal/helper"
-	"gowa-yourself/internal/model"
-	"gowa-yourself/internal/service"
 
 	"github.com/labstack/echo/v4"
-	"go.mau.fi/whatsmeow"
 )
 
 // Request body untuk send media from URL
 type SendMediaRequest struct {
-	To        string `json:"to" validate:"required"`
-	MediaURL  string `json:"mediaUrl" validate:"required"`
-	Caption   string `json:"caption"`
-	MediaType string `json:"mediaType"` // image, video, document, audio
+	To         string                 `json:"to"`       // personal
+	GroupJID   string                 `json:"groupJid"` // group
+	MediaURL   string                 `json:"mediaUrl" validate:"required"`
+	Caption    string                 `json:"caption"`
+	MediaType  string                 `json:"mediaType"`  // image, video, document, audio
+	PTT        bool                   `json:"ptt"`        // true = kirim audio Ogg/Opus sebagai voice note
+	SendAt     string                 `json:"sendAt"`     // opsional, RFC3339 / unix detik → kirim terjadwal
+	TemplateID int64                  `json:"templateId"` // opsional, isi message / caption dari template
+	Variables  map[string]interface{} `json:"variables"`  // nilai placeholder template
+}
+
+// mediaFile isi file media (upload / download) + parameter pesan
+type mediaFile struct {
+	Data        []byte
+	FileName    string
+	ContentType string
+	MediaType   string // dari request; kosong = dideteksi dari MIME
+	Caption     string
+	PTT         bool
 }
 
-// BY INSTANCE ID
 // POST /send/:instanceId/media (upload file)
 func SendMediaFile(c echo.Context) error {
-	instanceID := c.Param("instanceId")
+	return sendMediaFile(c, false)
+}
 
-	to := c.FormValue("to")
-	caption := c.FormValue("caption")
+// POST /by-number/:phoneNumber/media-file
+func SendMediaFileByNumber(c echo.Context) error {
+	return sendMediaFile(c, false)
+}
 
-	if to == "" {
-		return ErrorResponse(c, 400, "Field 'to' is required", "VALIDATION_ERROR", "")
-	}
+// POST /send-group/:instanceId/media - Send media to group
+func SendGroupMedia(c echo.Context) error {
+	return sendMediaFile(c, true)
+}
 
-	// 1. CEK SESSION EXISTS
-	session, err := service.GetSession(instanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login first")
-	}
+// POST /send-group/by-number/:phoneNumber/media - Send media to group by sender number
+func SendGroupMediaByNumber(c echo.Context) error {
+	return sendMediaFile(c, true)
+}
 
-	// 2. CEK CONNECTION FLAG (dari memory/database)
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "Please check /status endpoint")
-	}
+// POST /send/:instanceId/media-url (from URL)
+func SendMediaURL(c echo.Context) error {
+	return sendMediaURL(c, false)
+}
 
-	// 3. CEK REAL WHATSAPP CONNECTION (websocket)
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "Please reconnect")
-	}
+// POST /by-number/:phoneNumber/media-url
+func SendMediaURLByNumber(c echo.Context) error {
+	return sendMediaURL(c, false)
+}
 
-	// 4. CEK SUDAH LOGIN (punya JID)
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "Please scan QR code first")
+// POST /send-group/:instanceId/media-url - Send media from URL to group
+func SendGroupMediaURL(c echo.Context) error {
+	return sendMediaURL(c, true)
+}
+
+// POST /send-group/by-number/:phoneNumber/media-url - Send media from URL to group by sender number
+func SendGroupMediaURLByNumber(c echo.Context) error {
+	return sendMediaURL(c, true)
+}
+
+// sendMediaFile media dari multipart form-data (field 'file')
+func sendMediaFile(c echo.Context, group bool) error {
+	to := c.FormValue("to")
+	if group {
+		to = c.FormValue("groupJid")
 	}
 
-	// 5. FORMAT & VALIDATE PHONE NUMBER
-	recipient, err := helper.FormatPhoneNumber(to)
+	// Template: caption dari hasil render (templateId + variables JSON di form-data)
+	caption := c.FormValue("caption")
+	templateID, vars, err := formTemplateParams(c)
 	if err != nil {
-		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
+		return ErrorResponse(c, 400, "Invalid template parameters", "VALIDATION_ERROR", err.Error())
+	}
+	if templateID != 0 {
+		_, text, err := applyTemplate(c, templateID, vars)
+		if err != nil {
+			return templateErrorResponse(c, err)
+		}
+		caption = text
 	}
 
-	// 6. CEK NOMOR TERDAFTAR DI WHATSAPP
-	isRegistered, err := session.Client.IsOnWhatsApp(context.Background(), []string{recipient.User})
+	sendAt, err := parseSendAt(c.FormValue("sendAt"))
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to verify phone number", "VERIFICATION_FAILED", err.Error())
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
 	}
 
-	if len(isRegistered) == 0 || !isRegistered[0].IsIn {
-		return ErrorResponse(c, 400, "Phone number is not registered on WhatsApp", "PHONE_NOT_REGISTERED",
-			"Please check the number or ask recipient to install WhatsApp")
+	// 1. SESSION & TUJUAN
+	target, err := resolveSendTarget(c, to, group)
+	if err != nil {
+		return targetErrorResponse(c, err)
 	}
 
-	// 7. GET & VALIDATE FILE
+	// 2. GET & READ FILE
 	file, err := c.FormFile("file")
 	if err != nil {
 		return ErrorResponse(c, 400, "File is required", "FILE_REQUIRED", err.Error())
@@ -89,403 +124,121 @@ func SendMediaFile(c echo.Context) error {
 		return ErrorResponse(c, 500, "Failed to read file", "FILE_READ_FAILED", err.Error())
 	}
 
-	// 8. DETECT MEDIA TYPE
-	mediaType := helper.DetectMediaType(file.Filename)
-
-	// 9. VALIDATE FILE SIZE
-	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
-			fmt.Sprintf("File size: %d bytes, Max: %d bytes (%s)", len(fileData), maxSize, mediaType))
-	}
-
-	// 10. CONVERT MEDIA TYPE
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
-	}
-
-	// 11. UPLOAD TO WHATSAPP
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED",
-			fmt.Sprintf("Type: %s, Size: %d bytes, Error: %v", mediaType, len(fileData), err))
-	}
-
-	// 12. CREATE MESSAGE
-	msg := helper.CreateMediaMessage(uploaded, caption, file.Filename, mediaType)
-
-	// 13. SEND MESSAGE
-	resp, err := session.Client.SendMessage(context.Background(), recipient, msg)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
-	}
-
-	// 14. SUCCESS RESPONSE
-	return SuccessResponse(c, 200, "Media sent successfully", map[string]interface{}{
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"to":        to,
-		"mediaType": mediaType,
-		"fileName":  file.Filename,
-		"fileSize":  len(fileData),
-		"verified":  true,
-	})
-}
-
-// BY INSTANCE ID
-// POST /send/:instanceId/media-url (from URL)
-func SendMediaURL(c echo.Context) error {
-	instanceID := c.Param("instanceId")
-
-	var req SendMediaRequest
-	if err := c.Bind(&req); err != nil {
-		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
-	}
-
-	if req.To == "" || req.MediaURL == "" {
-		return ErrorResponse(c, 400, "Fields 'to' and 'mediaUrl' are required", "VALIDATION_ERROR", "")
-	}
-
-	// 1. CEK SESSION EXISTS
-	session, err := service.GetSession(instanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login first")
-	}
-
-	// 2. CEK CONNECTION FLAG (dari memory/database)
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "Please check /status endpoint")
-	}
-
-	// 3. CEK REAL WHATSAPP CONNECTION (websocket)
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "Please reconnect")
-	}
-
-	// 4. CEK SUDAH LOGIN (punya JID)
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "Please scan QR code first")
-	}
-
-	// 5. FORMAT & VALIDATE PHONE NUMBER
-	recipient, err := helper.FormatPhoneNumber(req.To)
-	if err != nil {
-		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
-	}
-
-	// 6. CEK NOMOR TERDAFTAR DI WHATSAPP
-	isRegistered, err := session.Client.IsOnWhatsApp(context.Background(), []string{recipient.User})
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to verify phone number", "VERIFICATION_FAILED", err.Error())
-	}
-
-	if len(isRegistered) == 0 || !isRegistered[0].IsIn {
-		return ErrorResponse(c, 400, "Phone number is not registered on WhatsApp", "PHONE_NOT_REGISTERED",
-			"Please check the number or ask recipient to install WhatsApp")
-	}
-
-	// 7. DOWNLOAD FILE FROM URL
-	fmt.Printf("Downloading from: %s\n", req.MediaURL)
-	fileData, filename, err := helper.DownloadFile(req.MediaURL)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to download file", "DOWNLOAD_FAILED", err.Error())
-	}
-	fmt.Printf("Downloaded: %s (%d bytes)\n", filename, len(fileData))
-
-	// 8. DETECT MEDIA TYPE
-	mediaType := req.MediaType
-	if mediaType == "" {
-		mediaType = helper.DetectMediaType(filename)
-	}
-	fmt.Printf("Detected media type: %s\n", mediaType)
-
-	// 9. VALIDATE FILE SIZE
-	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
-			fmt.Sprintf("File size: %d bytes, Max allowed: %d bytes (%s)", len(fileData), maxSize, mediaType))
-	}
-
-	// 10. CONVERT MEDIA TYPE
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
-	}
-
-	// 11. UPLOAD TO WHATSAPP
-	fmt.Printf("Uploading to WhatsApp as: %s\n", whatsmeowMediaType)
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media to WhatsApp", "UPLOAD_FAILED",
-			fmt.Sprintf("File: %s, Size: %d bytes, Type: %s, Error: %v", filename, len(fileData), mediaType, err))
-	}
-
-	// 12. CREATE MESSAGE
-	msg := helper.CreateMediaMessage(uploaded, req.Caption, filename, mediaType)
-
-	// 13. SEND MESSAGE
-	resp, err := session.Client.SendMessage(context.Background(), recipient, msg)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
-	}
-
-	// 14. SUCCESS RESPONSE
-	return SuccessResponse(c, 200, "Media sent successfully", map[string]interface{}{
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"to":        req.To,
-		"mediaType": mediaType,
-		"fileName":  filename,
-		"fileSize":  len(fileData),
-		"verified":  true,
-	})
+	return sendMediaToTarget(c, target, mediaFile{
+		Data:        fileData,
+		FileName:    file.Filename,
+		ContentType: file.Header.Get("Content-Type"),
+		Caption:     caption,
+		PTT:         formBool(c, "ptt"),
+	}, sendAt)
 }
 
-// BY NOMOR HP
-// POST /send/by-number/:phoneNumber/media-url
-func SendMediaURLByNumber(c echo.Context) error {
-	phoneNumber := c.Param("phoneNumber")
-
+// sendMediaURL media dari JSON body (mediaUrl di-download dulu)
+func sendMediaURL(c echo.Context, group bool) error {
 	var req SendMediaRequest
 	if err := c.Bind(&req); err != nil {
 		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
 	}
 
-	if req.To == "" || req.MediaURL == "" {
-		return ErrorResponse(c, 400, "Fields 'to' and 'mediaUrl' are required", "VALIDATION_ERROR", "")
-	}
-
-	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber)
-	if err != nil {
-		if errors.Is(err, model.ErrNoActiveInstance) {
-			return ErrorResponse(c, 404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number")
+	// Template: caption dari hasil render, media default dari template
+	if req.TemplateID != 0 {
+		tmpl, text, err := applyTemplate(c, req.TemplateID, req.Variables)
+		if err != nil {
+			return templateErrorResponse(c, err)
+		}
+		req.Caption = text
+		if req.MediaURL == "" {
+			req.MediaURL = tmpl.MediaURL.String
+		}
+		if req.MediaType == "" {
+			req.MediaType = tmpl.MediaType.String
 		}
-		return ErrorResponse(c, 500, "Failed to get instance for this phone number", "DB_ERROR", err.Error())
-	}
-
-	session, err := service.GetSession(inst.InstanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login / reconnect first")
-	}
-
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "Please check /status endpoint")
 	}
 
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "Please reconnect")
+	to, field := req.To, "to"
+	if group {
+		to, field = req.GroupJID, "groupJid"
 	}
-
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "Please scan QR code first")
+	if to == "" || req.MediaURL == "" {
+		return ErrorResponse(c, 400, fmt.Sprintf("Fields '%s' and 'mediaUrl' are required", field), "VALIDATION_ERROR", "")
 	}
 
-	recipient, err := helper.FormatPhoneNumber(req.To)
+	sendAt, err := parseSendAt(req.SendAt)
 	if err != nil {
-		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
 	}
 
-	isRegistered, err := session.Client.IsOnWhatsApp(context.Background(), []string{recipient.User})
+	// 1. SESSION & TUJUAN
+	target, err := resolveSendTarget(c, to, group)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to verify phone number", "VERIFICATION_FAILED", err.Error())
+		return targetErrorResponse(c, err)
 	}
 
-	if len(isRegistered) == 0 || !isRegistered[0].IsIn {
-		return ErrorResponse(c, 400, "Phone number is not registered on WhatsApp", "PHONE_NOT_REGISTERED", "Please check the number or ask recipient to install WhatsApp")
-	}
-
-	fmt.Printf("Downloading from: %s\n", req.MediaURL)
-	fileData, filename, err := helper.DownloadFile(req.MediaURL)
+	// 2. DOWNLOAD FILE FROM URL
+	fileData, filename, contentType, err := helper.DownloadFile(req.MediaURL)
 	if err != nil {
 		return ErrorResponse(c, 500, "Failed to download file", "DOWNLOAD_FAILED", err.Error())
 	}
-	fmt.Printf("Downloaded: %s (%d bytes)\n", filename, len(fileData))
-
-	mediaType := req.MediaType
-	if mediaType == "" {
-		mediaType = helper.DetectMediaType(filename)
-	}
-	fmt.Printf("Detected media type: %s\n", mediaType)
 
-	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE", fmt.Sprintf("File size: %d bytes, Max allowed: %d bytes (%s)", len(fileData), maxSize, mediaType))
-	}
-
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
-	}
-
-	fmt.Printf("Uploading to WhatsApp as: %s\n", whatsmeowMediaType)
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media to WhatsApp", "UPLOAD_FAILED", fmt.Sprintf("File: %s, Size: %d bytes, Type: %s, Error: %v", filename, len(fileData), mediaType, err))
-	}
-
-	msg := helper.CreateMediaMessage(uploaded, req.Caption, filename, mediaType)
-
-	resp, err := session.Client.SendMessage(context.Background(), recipient, msg)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
-	}
-
-	return SuccessResponse(c, 200, "Media sent successfully", map[string]interface{}{
-		"from":      phoneNumber,
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"to":        req.To,
-		"mediaType": mediaType,
-		"fileName":  filename,
-		"fileSize":  len(fileData),
-		"verified":  true,
-	})
+	return sendMediaToTarget(c, target, mediaFile{
+		Data:        fileData,
+		FileName:    filename,
+		ContentType: contentType,
+		MediaType:   req.MediaType,
+		Caption:     req.Caption,
+		PTT:         req.PTT,
+	}, sendAt)
 }
 
-// POST /send/by-number/:phoneNumber/media-file
-func SendMediaFileByNumber(c echo.Context) error {
-	// 0. Nomor WA pengirim dari path (format: 6285xxxx...)
-	phoneNumber := c.Param("phoneNumber")
-
-	to := c.FormValue("to")
-	caption := c.FormValue("caption")
-
-	if to == "" {
-		return ErrorResponse(c, 400, "Field 'to' is required", "VALIDATION_ERROR", "")
-	}
-
-	// 1. Cari instance aktif berdasarkan nomor pengirim
-	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber)
-	if err != nil {
-		if errors.Is(err, model.ErrNoActiveInstance) {
-			return ErrorResponse(c, 404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number")
-		}
-		return ErrorResponse(c, 500, "Failed to get instance for this phone number", "DB_ERROR", err.Error())
-	}
-
-	// 2. Ambil session dari memory berdasarkan instance_id
-	session, err := service.GetSession(inst.InstanceID)
-	if err != nil {
-		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login / reconnect first")
-	}
-
-	// 3. CEK CONNECTION FLAG (dari memory/database)
-	if !session.IsConnected {
-		return ErrorResponse(c, 400, "Session is not connected", "NOT_CONNECTED", "Please check /status endpoint")
-	}
-
-	// 4. CEK REAL WHATSAPP CONNECTION (websocket)
-	if !session.Client.IsConnected() {
-		return ErrorResponse(c, 400, "WhatsApp connection lost", "CONNECTION_LOST", "Please reconnect")
-	}
-
-	// 5. CEK SUDAH LOGIN (punya JID)
-	if session.Client.Store.ID == nil {
-		return ErrorResponse(c, 400, "Not logged in", "NOT_LOGGED_IN", "Please scan QR code first")
-	}
-
-	// 6. FORMAT & VALIDATE PHONE NUMBER TUJUAN
-	recipient, err := helper.FormatPhoneNumber(to)
-	if err != nil {
-		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
+// sendMediaToTarget deteksi tipe media, validasi voice note & ukuran, upload ke WhatsApp,
+// lalu kirim / antrikan lewat sendToTarget
+func sendMediaToTarget(c echo.Context, target *sendTarget, file mediaFile, sendAt *time.Time) error {
+	// 1. DETECT MEDIA TYPE
+	mimeType := helper.DetectMime(file.Data, file.ContentType, file.FileName)
+	mediaType := file.MediaType
+	if mediaType == "" {
+		mediaType = helper.MediaTypeFromMime(mimeType)
 	}
 
-	// 7. CEK NOMOR TUJUAN TERDAFTAR DI WHATSAPP
-	isRegistered, err := session.Client.IsOnWhatsApp(context.Background(), []string{recipient.User})
+	// Voice note (PTT): audio wajib Ogg/Opus, durasi & waveform dibaca dari container
+	voice, err := parseVoiceNote(file.PTT, file.Data)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to verify phone number", "VERIFICATION_FAILED", err.Error())
-	}
-
-	if len(isRegistered) == 0 || !isRegistered[0].IsIn {
-		return ErrorResponse(c, 400, "Phone number is not registered on WhatsApp", "PHONE_NOT_REGISTERED", "Please check the number or ask recipient to install WhatsApp")
+		return ErrorResponse(c, 400, "Invalid voice note audio", "INVALID_AUDIO_FORMAT", err.Error())
 	}
-
-	// 8. GET & VALIDATE FILE
-	file, err := c.FormFile("file")
-	if err != nil {
-		return ErrorResponse(c, 400, "File is required", "FILE_REQUIRED", err.Error())
+	if voice != nil {
+		mediaType = "audio"
 	}
 
-	src, err := file.Open()
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to open file", "FILE_OPEN_FAILED", err.Error())
-	}
-	defer src.Close()
-
-	fileData, err := io.ReadAll(src)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to read file", "FILE_READ_FAILED", err.Error())
-	}
-
-	// 9. DETECT MEDIA TYPE
-	mediaType := helper.DetectMediaType(file.Filename)
-
-	// 10. VALIDATE FILE SIZE
+	// 2. VALIDATE FILE SIZE
 	maxSize := getMaxFileSize(mediaType)
-	if len(fileData) > maxSize {
-		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE", fmt.Sprintf("File size: %d bytes, Max: %d bytes (%s)", len(fileData), maxSize, mediaType))
-	}
-
-	// 11. CONVERT MEDIA TYPE
-	var whatsmeowMediaType whatsmeow.MediaType
-	switch mediaType {
-	case "image":
-		whatsmeowMediaType = whatsmeow.MediaImage
-	case "video":
-		whatsmeowMediaType = whatsmeow.MediaVideo
-	case "audio":
-		whatsmeowMediaType = whatsmeow.MediaAudio
-	default:
-		whatsmeowMediaType = whatsmeow.MediaDocument
+	if len(file.Data) > maxSize {
+		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
+			fmt.Sprintf("File size: %d bytes, Max: %d bytes (%s)", len(file.Data), maxSize, mediaType))
 	}
 
-	// 12. UPLOAD TO WHATSAPP
-	uploaded, err := session.Client.Upload(context.Background(), fileData, whatsmeowMediaType)
+	// 3. UPLOAD TO WHATSAPP
+	uploaded, err := target.Session.Client.Upload(context.Background(), file.Data, helper.WhatsmeowMediaType(mediaType))
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED", fmt.Sprintf("Type: %s, Size: %d bytes, Error: %v", mediaType, len(fileData), err))
+		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED",
+			fmt.Sprintf("Type: %s, Size: %d bytes, Error: %v", mediaType, len(file.Data), err))
 	}
 
-	// 13. CREATE MESSAGE
-	msg := helper.CreateMediaMessage(uploaded, caption, file.Filename, mediaType)
-
-	// 14. SEND MESSAGE
-	resp, err := session.Client.SendMessage(context.Background(), recipient, msg)
-	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send media", "SEND_FAILED", err.Error())
+	// 4. CREATE MESSAGE
+	msg := helper.CreateMediaMessage(uploaded, file.Caption, file.FileName, mediaType, mimeType)
+	if voice != nil {
+		msg = helper.CreateVoiceNoteMessage(uploaded, voice)
 	}
 
-	// 15. SUCCESS RESPONSE
-	return SuccessResponse(c, 200, "Media sent successfully", map[string]interface{}{
-		"from":      phoneNumber,
-		"messageId": resp.ID,
-		"timestamp": resp.Timestamp.Unix(),
-		"to":        to,
+	message := "Media sent successfully"
+	if target.Group {
+		message = "Media sent to group"
+	}
+	return sendToTarget(c, target, msg, sendAt, message, map[string]interface{}{
 		"mediaType": mediaType,
-		"fileName":  file.Filename,
-		"fileSize":  len(fileData),
-		"verified":  true,
+		"ptt":       voice != nil,
+		"mimeType":  mimeType,
+		"fileName":  file.FileName,
+		"fileSize":  len(file.Data),
 	})
 }
 
@@ -502,3 +255,17 @@ func getMaxFileSize(mediaType string) int {
 		return 100 * 1024 * 1024 // 100MB for documents
 	}
 }
+
+// parseVoiceNote ptt=true → audio wajib Ogg/Opus; nil kalau bukan voice note
+func parseVoiceNote(ptt bool, fileData []byte) (*helper.OpusInfo, error) {
+	if !ptt {
+		return nil, nil
+	}
+	return helper.ParseOggOpus(fileData)
+}
+
+// formBool baca field boolean dari form-data (true/1/yes-style sesuai strconv.ParseBool)
+func formBool(c echo.Context, name string) bool {
+	v, _ := strconv.ParseBool(c.FormValue(name))
+	return v
+}
@@ -2,7 +2,12 @@ package handler
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"gowa-yourself/internal/helper"
 	"gowa-yourself/internal/service"
@@ -10,13 +15,25 @@ import (
 	"gowa-yourself/internal/model"
 
 	"github.com/labstack/echo/v4"
-	"go.mau.fi/whatsmeow/proto/waE2E"
 )
 
 // Request body untuk send message
 type SendMessageRequest struct {
-	To      string `json:"to" validate:"required"`
-	Message string `json:"message" validate:"required"`
+	To         string                 `json:"to" validate:"required"`
+	Message    string                 `json:"message" validate:"required"`
+	SendAt     string                 `json:"sendAt"`     // opsional, RFC3339 / unix detik → kirim terjadwal
+	TemplateID int64                  `json:"templateId"` // opsional, isi message / caption dari template
+	Variables  map[string]interface{} `json:"variables"`  // nilai placeholder template
+	TextMessageOptions
+}
+
+// Request body react / edit pesan yang sudah ada
+type ReactMessageRequest struct {
+	Emoji string `json:"emoji"` // kosong = hapus reaksi
+}
+
+type EditMessageRequest struct {
+	Message string `json:"message"`
 }
 
 type CheckNumberRequest struct {
@@ -32,10 +49,24 @@ func SendMessage(c echo.Context) error {
 		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
 	}
 
+	// Template: message diisi dari hasil render template
+	if req.TemplateID != 0 {
+		_, text, err := applyTemplate(c, req.TemplateID, req.Variables)
+		if err != nil {
+			return templateErrorResponse(c, err)
+		}
+		req.Message = text
+	}
+
 	if req.To == "" || req.Message == "" {
 		return ErrorResponse(c, 400, "Field 'to' and 'message' are required", "VALIDATION_ERROR", "")
 	}
 
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+
 	session, err := service.GetSession(instanceID)
 	if err != nil {
 		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login first")
@@ -68,13 +99,22 @@ func SendMessage(c echo.Context) error {
 			"Please check the number or ask recipient to install WhatsApp")
 	}
 
-	msg := &waE2E.Message{
-		Conversation: &req.Message,
+	msg, err := buildTextMessage(session.ID, recipient, req.Message, req.TextMessageOptions)
+	if err != nil {
+		return textMessageErrorResponse(c, err)
 	}
 
-	resp, err := session.Client.SendMessage(context.Background(), recipient, msg)
+	// Mode async / terjadwal: masuk outbox atau scheduled_messages
+	if sendAt != nil || isAsyncSend(c) {
+		return queueSend(c, session.ID, recipient, msg, sendAt, map[string]interface{}{
+			"to":       req.To,
+			"verified": true,
+		})
+	}
+
+	resp, err := service.SendMessage(context.Background(), session.ID, recipient, msg, nil)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send message", "SEND_FAILED", err.Error())
+		return sendErrorResponse(c, "Failed to send message", err)
 	}
 
 	return SuccessResponse(c, 200, "Message sent successfully", map[string]interface{}{
@@ -94,12 +134,26 @@ func SendMessageByNumber(c echo.Context) error {
 		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
 	}
 
+	// Template: message diisi dari hasil render template
+	if req.TemplateID != 0 {
+		_, text, err := applyTemplate(c, req.TemplateID, req.Variables)
+		if err != nil {
+			return templateErrorResponse(c, err)
+		}
+		req.Message = text
+	}
+
 	if req.To == "" || req.Message == "" {
 		return ErrorResponse(c, 400, "Field 'to' and 'message' are required", "VALIDATION_ERROR", "")
 	}
 
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+
 	//Cari instance aktif berdasarkan nomor pengirim (phoneNumber)
-	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber)
+	inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber, ownerScope(c))
 	if err != nil {
 		if errors.Is(err, model.ErrNoActiveInstance) {
 			return ErrorResponse(c, 404,
@@ -142,12 +196,22 @@ func SendMessageByNumber(c echo.Context) error {
 	}
 
 	// 5) Kirim pesan
-	msg := &waE2E.Message{
-		Conversation: &req.Message,
+	msg, err := buildTextMessage(session.ID, recipient, req.Message, req.TextMessageOptions)
+	if err != nil {
+		return textMessageErrorResponse(c, err)
+	}
+	// Mode async / terjadwal: masuk outbox atau scheduled_messages
+	if sendAt != nil || isAsyncSend(c) {
+		return queueSend(c, session.ID, recipient, msg, sendAt, map[string]interface{}{
+			"from":     phoneNumber,
+			"to":       req.To,
+			"verified": true,
+		})
 	}
-	resp, err := session.Client.SendMessage(context.Background(), recipient, msg)
+
+	resp, err := service.SendMessage(context.Background(), session.ID, recipient, msg, nil)
 	if err != nil {
-		return ErrorResponse(c, 500, "Failed to send message", "SEND_FAILED", err.Error())
+		return sendErrorResponse(c, "Failed to send message", err)
 	}
 
 	return SuccessResponse(c, 200, "Message sent successfully", map[string]interface{}{
@@ -197,3 +261,211 @@ func CheckNumber(c echo.Context) error {
 		"jid":          isRegistered[0].JID.String(),
 	})
 }
+
+// GET /messages/:instanceId?chatJid=&sender=&direction=&from=&to=&page=&limit=
+func GetMessages(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	if _, err := model.GetInstanceByInstanceID(instanceID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, 404, "Instance not found", "INSTANCE_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get instance", "DB_ERROR", err.Error())
+	}
+
+	direction := c.QueryParam("direction")
+	if direction != "" && direction != "incoming" && direction != "outgoing" {
+		return ErrorResponse(c, 400, "Invalid direction", "VALIDATION_ERROR", "direction must be 'incoming' or 'outgoing'")
+	}
+
+	from, err := parseTimeParam(c.QueryParam("from"))
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'from' parameter", "VALIDATION_ERROR", err.Error())
+	}
+	to, err := parseTimeParam(c.QueryParam("to"))
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'to' parameter", "VALIDATION_ERROR", err.Error())
+	}
+
+	page, limit := parsePagination(c)
+
+	messages, total, err := model.GetMessages(model.MessageFilter{
+		InstanceID: instanceID,
+		ChatJID:    c.QueryParam("chatJid"),
+		Sender:     c.QueryParam("sender"),
+		Direction:  direction,
+		From:       from,
+		To:         to,
+		Limit:      limit,
+		Offset:     (page - 1) * limit,
+	})
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get messages", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.MessageResp, 0, len(messages))
+	for _, m := range messages {
+		result = append(result, model.ToMessageResponse(m))
+	}
+
+	return SuccessResponse(c, 200, "Messages retrieved", map[string]interface{}{
+		"instanceId": instanceID,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"messages":   result,
+	})
+}
+
+// GET /messages/:instanceId/:messageId/status
+// Status pengiriman pesan outgoing (server_ack / delivered / read / played) + receipt per penerima
+func GetMessageStatus(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	status, err := model.GetMessageStatus(instanceID, messageID)
+	if err != nil {
+		if errors.Is(err, model.ErrMessageNotFound) {
+			return ErrorResponse(c, 404, "Message not found", "MESSAGE_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get message status", "DB_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Message status retrieved", status)
+}
+
+// POST /messages/:instanceId/:messageId/react - reaksi emoji ke pesan (personal & group)
+func ReactToMessage(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	var req ReactMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	req.Emoji = strings.TrimSpace(req.Emoji)
+
+	target, resp, err := service.ReactToMessage(context.Background(), instanceID, messageID, req.Emoji)
+	if err != nil {
+		return messageActionErrorResponse(c, "Failed to send reaction", err)
+	}
+
+	message := "Reaction sent"
+	if req.Emoji == "" {
+		message = "Reaction removed"
+	}
+	return SuccessResponse(c, 200, message, map[string]interface{}{
+		"messageId":       resp.ID,
+		"timestamp":       resp.Timestamp.Unix(),
+		"targetMessageId": target.MessageID,
+		"chatJid":         target.ChatJID,
+		"emoji":           req.Emoji,
+	})
+}
+
+// PUT /messages/:instanceId/:messageId - edit teks pesan yang dikirim instance ini (maks. 20 menit)
+func EditMessage(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	var req EditMessageRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+	if req.Message == "" {
+		return ErrorResponse(c, 400, "Field 'message' is required", "VALIDATION_ERROR", "")
+	}
+
+	target, resp, err := service.EditMessage(context.Background(), instanceID, messageID, req.Message)
+	if err != nil {
+		return messageActionErrorResponse(c, "Failed to edit message", err)
+	}
+
+	return SuccessResponse(c, 200, "Message edited", map[string]interface{}{
+		"messageId":       resp.ID,
+		"timestamp":       resp.Timestamp.Unix(),
+		"targetMessageId": target.MessageID,
+		"chatJid":         target.ChatJID,
+		"message":         req.Message,
+	})
+}
+
+// DELETE /messages/:instanceId/:messageId - tarik pesan (delete for everyone)
+func RevokeMessage(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	target, resp, err := service.RevokeMessage(context.Background(), instanceID, messageID)
+	if err != nil {
+		return messageActionErrorResponse(c, "Failed to revoke message", err)
+	}
+
+	return SuccessResponse(c, 200, "Message revoked", map[string]interface{}{
+		"messageId":       resp.ID,
+		"timestamp":       resp.Timestamp.Unix(),
+		"targetMessageId": target.MessageID,
+		"chatJid":         target.ChatJID,
+	})
+}
+
+// messageActionErrorResponse error react / edit / revoke; selain error validasi → sendErrorResponse
+func messageActionErrorResponse(c echo.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrInstanceNotFound):
+		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login first")
+	case errors.Is(err, service.ErrSessionNotConnected):
+		return ErrorResponse(c, 400, "WhatsApp session is not connected", "NOT_CONNECTED", "Please scan QR or reconnect")
+	case errors.Is(err, model.ErrMessageNotFound):
+		return ErrorResponse(c, 404, "Message not found", "MESSAGE_NOT_FOUND", "")
+	case errors.Is(err, service.ErrMessageRevoked):
+		return ErrorResponse(c, 409, "Message has already been revoked", "MESSAGE_REVOKED", "")
+	case errors.Is(err, service.ErrEditWindowExpired):
+		return ErrorResponse(c, 409, "Edit window has passed", "EDIT_WINDOW_EXPIRED", err.Error())
+	case errors.Is(err, service.ErrNotOwnMessage):
+		return ErrorResponse(c, 403, "Cannot edit this message", "NOT_OWN_MESSAGE", err.Error())
+	case errors.Is(err, service.ErrRevokeNotPermitted):
+		return ErrorResponse(c, 403, "Cannot revoke this message", "REVOKE_NOT_PERMITTED", err.Error())
+	case errors.Is(err, service.ErrNotTextMessage):
+		return ErrorResponse(c, 400, "Cannot edit this message", "NOT_TEXT_MESSAGE", err.Error())
+	case errors.Is(err, service.ErrUnsupportedChatType):
+		return ErrorResponse(c, 400, "Unsupported chat type", "UNSUPPORTED_CHAT", err.Error())
+	}
+	return sendErrorResponse(c, message, err)
+}
+
+// Helper: parse waktu dari query (RFC3339 atau unix detik)
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t := time.Unix(unix, 0).UTC()
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("use RFC3339 or unix seconds: %w", err)
+	}
+	return &t, nil
+}
+
+// Helper: ambil page & limit dari query (default page 1, limit 50, max 200)
+func parsePagination(c echo.Context) (page, limit int) {
+	page, _ = strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	return page, limit
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// isAsyncSend: ?async=true (atau form field async untuk upload) → pesan masuk outbox, bukan dikirim langsung
+func isAsyncSend(c echo.Context) bool {
+	async := c.QueryParam("async")
+	if async == "" {
+		async = c.FormValue("async")
+	}
+	v, _ := strconv.ParseBool(async)
+	return v
+}
+
+// queueSend simpan pesan ke outbox (async) atau scheduled_messages (sendAt diisi) lalu balas 202.
+// extra berisi field response yang sama dengan mode sync (to, groupJid, mediaType, dll).
+func queueSend(c echo.Context, instanceID string, to types.JID, msg *waE2E.Message, sendAt *time.Time, extra map[string]interface{}) error {
+	var data map[string]interface{}
+	var message string
+
+	if sendAt != nil {
+		scheduled, err := service.ScheduleMessage(instanceID, to, msg, *sendAt)
+		if err != nil {
+			if errors.Is(err, service.ErrScheduleTooFar) {
+				return ErrorResponse(c, 400, "Invalid 'sendAt'", "SCHEDULE_TOO_FAR", err.Error())
+			}
+			return ErrorResponse(c, 500, "Failed to schedule message", "SCHEDULE_FAILED", err.Error())
+		}
+		message = "Message scheduled"
+		data = map[string]interface{}{
+			"scheduleId": scheduled.ID,
+			"messageId":  scheduled.MessageID.String,
+			"sendAt":     scheduled.SendAt,
+			"status":     scheduled.Status,
+		}
+	} else {
+		if service.Outbox == nil {
+			return ErrorResponse(c, 503, "Outbox is not running", "OUTBOX_UNAVAILABLE", "")
+		}
+		job, err := service.Outbox.Enqueue(instanceID, to, msg)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to queue message", "QUEUE_FAILED", err.Error())
+		}
+		message = "Message queued"
+		data = map[string]interface{}{
+			"jobId":     job.ID,
+			"messageId": job.MessageID.String,
+			"status":    job.Status,
+		}
+	}
+
+	for k, v := range extra {
+		data[k] = v
+	}
+	return SuccessResponse(c, 202, message, data)
+}
+
+// parseSendAt: kosong = kirim sekarang, selain itu wajib waktu di masa depan (RFC3339 / unix detik).
+// Batas maksimal untuk pesan media (SCHEDULE_MEDIA_MAX_AHEAD) dicek di service.ScheduleMessage.
+func parseSendAt(value string) (*time.Time, error) {
+	sendAt, err := parseTimeParam(value)
+	if err != nil || sendAt == nil {
+		return nil, err
+	}
+	if !sendAt.After(time.Now()) {
+		return nil, fmt.Errorf("sendAt must be in the future")
+	}
+	return sendAt, nil
+}
+
+// GET /outbox/:instanceId?status=&page=&limit=
+func GetOutboxJobs(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	status := c.QueryParam("status")
+	switch status {
+	case "", model.OutboxStatusPending, model.OutboxStatusProcessing, model.OutboxStatusSent, model.OutboxStatusFailed:
+	default:
+		return ErrorResponse(c, 400, "Invalid status", "VALIDATION_ERROR", "status must be one of: pending, processing, sent, failed")
+	}
+
+	page, limit := parsePagination(c)
+
+	jobs, total, err := model.GetOutboxJobs(instanceID, status, limit, (page-1)*limit)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get outbox jobs", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.OutboxJobResp, 0, len(jobs))
+	for _, j := range jobs {
+		result = append(result, model.ToOutboxJobResponse(j))
+	}
+
+	return SuccessResponse(c, 200, "Outbox jobs retrieved", map[string]interface{}{
+		"instanceId": instanceID,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"jobs":       result,
+	})
+}
+
+// GET /outbox/:instanceId/:jobId
+func GetOutboxJob(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	jobID, err := strconv.ParseInt(c.Param("jobId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid job id", "VALIDATION_ERROR", err.Error())
+	}
+
+	job, err := model.GetOutboxJob(instanceID, jobID)
+	if err != nil {
+		if errors.Is(err, model.ErrOutboxJobNotFound) {
+			return ErrorResponse(c, 404, "Outbox job not found", "JOB_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get outbox job", "DB_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Outbox job retrieved", model.ToOutboxJobResponse(*job))
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Batas opsi polling dari WhatsApp
+const (
+	minPollOptions = 2
+	maxPollOptions = 12
+)
+
+// Request body kirim polling
+type SendPollRequest struct {
+	To              string   `json:"to"`       // personal
+	GroupJID        string   `json:"groupJid"` // group
+	Question        string   `json:"question"`
+	Options         []string `json:"options"`
+	MultiSelect     bool     `json:"multiSelect"`     // true = boleh pilih lebih dari satu opsi
+	SelectableCount int      `json:"selectableCount"` // opsional, batas jumlah pilihan untuk multiSelect
+	SendAt          string   `json:"sendAt"`          // opsional, RFC3339 / unix detik → kirim terjadwal
+}
+
+// POST /send/:instanceId/poll & /by-number/:phoneNumber/poll
+func SendPoll(c echo.Context) error {
+	return sendPoll(c, false)
+}
+
+// POST /send-group/:instanceId/poll & /send-group/by-number/:phoneNumber/poll
+func SendGroupPoll(c echo.Context) error {
+	return sendPoll(c, true)
+}
+
+func sendPoll(c echo.Context, group bool) error {
+	var req SendPollRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		return ErrorResponse(c, 400, "Field 'question' is required", "VALIDATION_ERROR", "")
+	}
+
+	options := make([]string, 0, len(req.Options))
+	seen := make(map[string]bool)
+	for _, opt := range req.Options {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			return ErrorResponse(c, 400, "Invalid poll options", "VALIDATION_ERROR", "Options must not be empty")
+		}
+		if seen[opt] {
+			return ErrorResponse(c, 400, "Invalid poll options", "VALIDATION_ERROR", "Duplicate option: "+opt)
+		}
+		seen[opt] = true
+		options = append(options, opt)
+	}
+	if len(options) < minPollOptions || len(options) > maxPollOptions {
+		return ErrorResponse(c, 400, "Invalid poll options", "VALIDATION_ERROR", "A poll needs 2 to 12 options")
+	}
+
+	// WhatsApp: selectableOptionsCount 1 = single select, 0 = pilih berapa saja
+	selectable := 1
+	if req.MultiSelect {
+		selectable = req.SelectableCount
+		if selectable < 0 || selectable > len(options) {
+			return ErrorResponse(c, 400, "Invalid 'selectableCount'", "VALIDATION_ERROR",
+				"selectableCount must be between 0 (no limit) and the number of options")
+		}
+	}
+
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+
+	to := req.To
+	if group {
+		to = req.GroupJID
+	}
+	target, err := resolveSendTarget(c, to, group)
+	if err != nil {
+		return targetErrorResponse(c, err)
+	}
+
+	// Message secret polling ikut di payload, jadi vote tetap bisa didekripsi untuk mode async / terjadwal
+	msg := target.Session.Client.BuildPollCreation(req.Question, options, selectable)
+
+	return sendToTarget(c, target, msg, sendAt, "Poll sent successfully", map[string]interface{}{
+		"question":        req.Question,
+		"options":         options,
+		"selectableCount": selectable,
+	})
+}
+
+// GET /polls/:instanceId/:messageId - hasil polling per opsi & per voter
+func GetPollResults(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	messageID := c.Param("messageId")
+
+	results, err := service.GetPollResults(instanceID, messageID)
+	if err != nil {
+		if errors.Is(err, model.ErrPollNotFound) {
+			return ErrorResponse(c, 404, "Poll not found", "POLL_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get poll results", "DB_ERROR", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Poll results retrieved", results)
+}
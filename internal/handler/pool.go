@@ -0,0 +1,361 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Request body untuk create / update sender pool
+type SenderPoolRequest struct {
+	Name      string   `json:"name"`
+	Strategy  string   `json:"strategy"`  // round_robin (default), lru, sticky
+	Instances []string `json:"instances"` // instance ID anggota pool, urutan = urutan round-robin
+}
+
+// Request body kirim pesan lewat sender pool
+type PoolSendRequest struct {
+	To         string                 `json:"to"`
+	Message    string                 `json:"message"`
+	SendAt     string                 `json:"sendAt"`     // opsional, RFC3339 / unix detik → kirim terjadwal
+	TemplateID int64                  `json:"templateId"` // opsional, isi message dari template
+	Variables  map[string]interface{} `json:"variables"`  // nilai placeholder template
+	TextMessageOptions
+}
+
+// POST /pools
+func CreateSenderPool(c echo.Context) error {
+	var req SenderPoolRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Instances) == 0 {
+		return ErrorResponse(c, 400, "Field 'name' and 'instances' are required", "VALIDATION_ERROR", "")
+	}
+	if req.Strategy == "" {
+		req.Strategy = model.PoolStrategyRoundRobin
+	}
+	if err := validatePoolStrategy(req.Strategy); err != nil {
+		return ErrorResponse(c, 400, "Invalid strategy", "VALIDATION_ERROR", err.Error())
+	}
+
+	instanceIDs, err := validatePoolInstances(c, req.Instances)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid pool members", "VALIDATION_ERROR", err.Error())
+	}
+
+	pool := &model.SenderPool{
+		OwnerID:  poolOwnerID(c),
+		Name:     req.Name,
+		Strategy: req.Strategy,
+	}
+	if err := model.InsertSenderPool(pool, instanceIDs); err != nil {
+		if errors.Is(err, model.ErrSenderPoolNameTaken) {
+			return ErrorResponse(c, 409, "Sender pool name already exists", "POOL_NAME_TAKEN", "")
+		}
+		return ErrorResponse(c, 500, "Failed to create sender pool", "DB_INSERT_FAILED", err.Error())
+	}
+
+	return senderPoolResponse(c, 201, "Sender pool created", *pool)
+}
+
+// GET /pools
+func GetSenderPools(c echo.Context) error {
+	pools, err := model.GetSenderPools(ownerScope(c))
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get sender pools", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.SenderPoolResp, 0, len(pools))
+	for _, pool := range pools {
+		members, err := poolMemberResponses(pool.ID)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to get sender pool members", "DB_ERROR", err.Error())
+		}
+		result = append(result, model.ToSenderPoolResponse(pool, members))
+	}
+
+	return SuccessResponse(c, 200, "Sender pools retrieved", map[string]interface{}{
+		"total": len(result),
+		"pools": result,
+	})
+}
+
+// GET /pools/:poolName
+func GetSenderPool(c echo.Context) error {
+	pool, err := model.GetSenderPoolByName(c.Param("poolName"), poolOwnerID(c))
+	if err != nil {
+		return poolErrorResponse(c, err)
+	}
+	return senderPoolResponse(c, 200, "Sender pool retrieved", *pool)
+}
+
+// PUT /pools/:poolName
+func UpdateSenderPool(c echo.Context) error {
+	pool, err := model.GetSenderPoolByName(c.Param("poolName"), poolOwnerID(c))
+	if err != nil {
+		return poolErrorResponse(c, err)
+	}
+
+	var req SenderPoolRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.Strategy != "" {
+		if err := validatePoolStrategy(req.Strategy); err != nil {
+			return ErrorResponse(c, 400, "Invalid strategy", "VALIDATION_ERROR", err.Error())
+		}
+		pool.Strategy = req.Strategy
+	}
+
+	var instanceIDs []string
+	if req.Instances != nil {
+		if len(req.Instances) == 0 {
+			return ErrorResponse(c, 400, "Pool must have at least one instance", "VALIDATION_ERROR", "")
+		}
+		if instanceIDs, err = validatePoolInstances(c, req.Instances); err != nil {
+			return ErrorResponse(c, 400, "Invalid pool members", "VALIDATION_ERROR", err.Error())
+		}
+	}
+
+	if err := model.UpdateSenderPool(pool, instanceIDs); err != nil {
+		return poolErrorResponse(c, err)
+	}
+
+	return senderPoolResponse(c, 200, "Sender pool updated", *pool)
+}
+
+// DELETE /pools/:poolName
+func DeleteSenderPool(c echo.Context) error {
+	pool, err := model.GetSenderPoolByName(c.Param("poolName"), poolOwnerID(c))
+	if err != nil {
+		return poolErrorResponse(c, err)
+	}
+
+	if err := model.DeleteSenderPool(pool.ID); err != nil {
+		return poolErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Sender pool deleted", map[string]interface{}{
+		"name": pool.Name,
+	})
+}
+
+// POST /pool/:poolName/send - kirim teks lewat instance pool yang sehat (round-robin / lru / sticky + failover)
+func SendPoolMessage(c echo.Context) error {
+	var req PoolSendRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	// Template: message diisi dari hasil render template
+	if req.TemplateID != 0 {
+		_, text, err := applyTemplate(c, req.TemplateID, req.Variables)
+		if err != nil {
+			return templateErrorResponse(c, err)
+		}
+		req.Message = text
+	}
+
+	if req.To == "" || req.Message == "" {
+		return ErrorResponse(c, 400, "Field 'to' and 'message' are required", "VALIDATION_ERROR", "")
+	}
+
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+
+	pool, err := model.GetSenderPoolByName(c.Param("poolName"), poolOwnerID(c))
+	if err != nil {
+		return poolErrorResponse(c, err)
+	}
+
+	members, err := allowedPoolMembers(c, pool.ID)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get sender pool members", "DB_ERROR", err.Error())
+	}
+
+	recipient, err := helper.FormatPhoneNumber(req.To)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid phone number", "INVALID_PHONE", err.Error())
+	}
+
+	candidates := service.PoolCandidates(*pool, members, recipient)
+	session, err := service.PickPoolSession(candidates)
+	if err != nil {
+		return poolErrorResponse(c, err)
+	}
+
+	isRegistered, err := session.Client.IsOnWhatsApp(context.Background(), []string{recipient.User})
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to verify phone number", "VERIFICATION_FAILED", err.Error())
+	}
+	if len(isRegistered) == 0 || !isRegistered[0].IsIn {
+		return ErrorResponse(c, 400, "Phone number is not registered on WhatsApp", "PHONE_NOT_REGISTERED",
+			"Please check the number or ask recipient to install WhatsApp")
+	}
+
+	msg, err := buildTextMessage(session.ID, recipient, req.Message, req.TextMessageOptions)
+	if err != nil {
+		return textMessageErrorResponse(c, err)
+	}
+
+	// Reply memakai pesan dari riwayat instance terpilih; instance lain tidak punya pesan tsb,
+	// jadi failover dimatikan supaya tidak mengirim reply ke pesan yang tidak pernah dilihatnya
+	if strings.TrimSpace(req.QuotedMessageID) != "" {
+		candidates = []string{session.ID}
+	}
+
+	// Mode async / terjadwal: instance pool dipilih sekarang, pesan masuk outbox instance tsb
+	if sendAt != nil || isAsyncSend(c) {
+		service.RecordPoolUse(*pool, session.ID, recipient)
+		return queueSend(c, session.ID, recipient, msg, sendAt, map[string]interface{}{
+			"pool":       pool.Name,
+			"instanceId": session.ID,
+			"to":         req.To,
+			"verified":   true,
+		})
+	}
+
+	instanceID, resp, err := service.SendViaPool(context.Background(), *pool, candidates, recipient, msg)
+	if err != nil {
+		if errors.Is(err, service.ErrPoolNoHealthyMember) {
+			return poolErrorResponse(c, err)
+		}
+		return sendErrorResponse(c, "Failed to send message", err)
+	}
+
+	return SuccessResponse(c, 200, "Message sent successfully", map[string]interface{}{
+		"messageId":  resp.ID,
+		"timestamp":  resp.Timestamp.Unix(),
+		"pool":       pool.Name,
+		"instanceId": instanceID,
+		"to":         req.To,
+		"verified":   true,
+	})
+}
+
+// poolOwnerID pemilik pool = user pemanggil. Nama pool hanya unik per owner, jadi lookup by name
+// selalu ke pool milik pemanggil sendiri (admin juga) supaya tidak nyasar ke pool user lain.
+func poolOwnerID(c echo.Context) sql.NullInt64 {
+	if p := currentPrincipal(c); p != nil && p.UserID > 0 {
+		return sql.NullInt64{Int64: p.UserID, Valid: true}
+	}
+	return sql.NullInt64{}
+}
+
+// allowedPoolMembers anggota pool yang masih boleh dipakai pemanggil
+// (instance milik user & sesuai scope API key)
+func allowedPoolMembers(c echo.Context, poolID int64) ([]model.SenderPoolMember, error) {
+	members, err := model.GetSenderPoolMembers(poolID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := currentPrincipal(c)
+	allowed := make([]model.SenderPoolMember, 0, len(members))
+	for _, m := range members {
+		inst, err := model.GetInstanceByInstanceID(m.InstanceID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, err
+		}
+		if p != nil && p.OwnsInstance(inst) && p.CanAccessInstance(inst) {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed, nil
+}
+
+// validatePoolInstances cek semua anggota ada & boleh diakses pemanggil, buang duplikat
+func validatePoolInstances(c echo.Context, instances []string) ([]string, error) {
+	p := currentPrincipal(c)
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(instances))
+
+	for _, id := range instances {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		inst, err := model.GetInstanceByInstanceID(id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if inst == nil || p == nil || !p.OwnsInstance(inst) || !p.CanAccessInstance(inst) {
+			return nil, fmt.Errorf("instance %s not found", id)
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("pool must have at least one instance")
+	}
+	return ids, nil
+}
+
+func validatePoolStrategy(strategy string) error {
+	switch strategy {
+	case model.PoolStrategyRoundRobin, model.PoolStrategyLRU, model.PoolStrategySticky:
+		return nil
+	}
+	return fmt.Errorf("strategy must be one of round_robin, lru, sticky")
+}
+
+func senderPoolResponse(c echo.Context, code int, message string, pool model.SenderPool) error {
+	members, err := poolMemberResponses(pool.ID)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get sender pool members", "DB_ERROR", err.Error())
+	}
+	return SuccessResponse(c, code, message, model.ToSenderPoolResponse(pool, members))
+}
+
+func poolMemberResponses(poolID int64) ([]model.SenderPoolMemberResp, error) {
+	members, err := model.GetSenderPoolMembers(poolID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.SenderPoolMemberResp, 0, len(members))
+	for _, m := range members {
+		var phoneNumber string
+		var connected bool
+		if inst, err := model.GetInstanceByInstanceID(m.InstanceID); err == nil {
+			phoneNumber = inst.PhoneNumber.String
+		}
+		if session, err := service.GetSession(m.InstanceID); err == nil {
+			connected = session.IsConnected && session.Client.IsConnected()
+		}
+		result = append(result, model.ToSenderPoolMemberResponse(m, phoneNumber, connected))
+	}
+	return result, nil
+}
+
+func poolErrorResponse(c echo.Context, err error) error {
+	var limited *service.RateLimitError
+	switch {
+	case errors.Is(err, model.ErrSenderPoolNotFound):
+		return ErrorResponse(c, 404, "Sender pool not found", "POOL_NOT_FOUND", "")
+	case errors.Is(err, service.ErrPoolNoHealthyMember):
+		return ErrorResponse(c, 503, "No connected instance in sender pool", "NO_HEALTHY_INSTANCE", err.Error())
+	case errors.As(err, &limited):
+		return sendErrorResponse(c, "Failed to send message", err)
+	}
+	return ErrorResponse(c, 500, "Failed to process sender pool", "DB_ERROR", err.Error())
+}
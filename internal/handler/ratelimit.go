@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GET /instances/:instanceId/rate-limit - pemakaian kuota kirim instance
+func GetRateLimitStatus(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+	return SuccessResponse(c, 200, "Rate limit status retrieved", service.GetRateLimitStatus(instanceID))
+}
+
+// sendErrorResponse response gagal kirim; rate limit instance → 429 RATE_LIMITED + header Retry-After.
+// Session tidak ada / tidak connected → 4xx (pesan belum dikirim). Error lain terjadi saat / setelah
+// Client.SendMessage: pesan mungkin sudah sampai ke WhatsApp, jadi Idempotency-Key tidak dilepas.
+func sendErrorResponse(c echo.Context, message string, err error) error {
+	var limited *service.RateLimitError
+	switch {
+	case errors.As(err, &limited):
+		retryAfter := int(math.Ceil(limited.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		return c.JSON(429, APIResponse{
+			Success: false,
+			Message: "Send rate limit reached for this instance",
+			Data: map[string]interface{}{
+				"limit":      limited.Limit,
+				"max":        limited.Max,
+				"retryAfter": retryAfter,
+			},
+			Error: &ErrorInfo{
+				Code:    "RATE_LIMITED",
+				Details: limited.Error(),
+			},
+		})
+	case errors.Is(err, service.ErrInstanceNotFound):
+		return ErrorResponse(c, 404, "Session not found", "SESSION_NOT_FOUND", "Please login first")
+	case errors.Is(err, service.ErrSessionNotConnected):
+		return ErrorResponse(c, 400, "WhatsApp session is not connected", "NOT_CONNECTED", "Please scan QR or reconnect")
+	}
+
+	markSendAttempted(c)
+	return ErrorResponse(c, 500, message, "SEND_FAILED", err.Error())
+}
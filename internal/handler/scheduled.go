@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Request body untuk reschedule pesan
+type RescheduleRequest struct {
+	SendAt string `json:"sendAt" validate:"required"`
+}
+
+// GET /scheduled/:instanceId?status=&page=&limit=
+func GetScheduledMessages(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	status := c.QueryParam("status")
+	switch status {
+	case "", model.ScheduledStatusScheduled, model.ScheduledStatusDispatched, model.ScheduledStatusCancelled:
+	default:
+		return ErrorResponse(c, 400, "Invalid status", "VALIDATION_ERROR", "status must be one of: scheduled, dispatched, cancelled")
+	}
+
+	page, limit := parsePagination(c)
+
+	list, total, err := model.GetScheduledMessages(instanceID, status, limit, (page-1)*limit)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get scheduled messages", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.ScheduledMessageResp, 0, len(list))
+	for _, s := range list {
+		result = append(result, model.ToScheduledMessageResponse(s))
+	}
+
+	return SuccessResponse(c, 200, "Scheduled messages retrieved", map[string]interface{}{
+		"instanceId": instanceID,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"scheduled":  result,
+	})
+}
+
+// GET /scheduled/:instanceId/:scheduleId
+func GetScheduledMessage(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	scheduleID, err := strconv.ParseInt(c.Param("scheduleId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid schedule id", "VALIDATION_ERROR", err.Error())
+	}
+
+	scheduled, err := model.GetScheduledMessage(instanceID, scheduleID)
+	if err != nil {
+		return scheduledErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Scheduled message retrieved", model.ToScheduledMessageResponse(*scheduled))
+}
+
+// PUT /scheduled/:instanceId/:scheduleId body {"sendAt": "..."}
+func RescheduleMessage(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	scheduleID, err := strconv.ParseInt(c.Param("scheduleId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid schedule id", "VALIDATION_ERROR", err.Error())
+	}
+
+	var req RescheduleRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+	if sendAt == nil {
+		return ErrorResponse(c, 400, "Field 'sendAt' is required", "VALIDATION_ERROR", "")
+	}
+
+	scheduled, err := service.RescheduleMessage(instanceID, scheduleID, *sendAt)
+	if err != nil {
+		return scheduledErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Message rescheduled", model.ToScheduledMessageResponse(*scheduled))
+}
+
+// DELETE /scheduled/:instanceId/:scheduleId
+func CancelScheduledMessage(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	scheduleID, err := strconv.ParseInt(c.Param("scheduleId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid schedule id", "VALIDATION_ERROR", err.Error())
+	}
+
+	scheduled, err := model.CancelScheduledMessage(instanceID, scheduleID)
+	if err != nil {
+		return scheduledErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Scheduled message cancelled", model.ToScheduledMessageResponse(*scheduled))
+}
+
+func scheduledErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, model.ErrScheduledMessageNotFound):
+		return ErrorResponse(c, 404, "Scheduled message not found", "SCHEDULE_NOT_FOUND", "")
+	case errors.Is(err, model.ErrScheduledMessageLocked):
+		return ErrorResponse(c, 409, "Scheduled message already dispatched or cancelled", "SCHEDULE_NOT_PENDING", "")
+	case errors.Is(err, service.ErrScheduleTooFar):
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "SCHEDULE_TOO_FAR", err.Error())
+	}
+	return ErrorResponse(c, 500, "Failed to process scheduled message", "DB_ERROR", err.Error())
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gowa-yourself/internal/helper"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow"
+)
+
+// Request kirim sticker: multipart (field 'file') atau JSON / form dengan 'mediaUrl'
+type SendStickerRequest struct {
+	To       string `json:"to" form:"to"`             // personal
+	GroupJID string `json:"groupJid" form:"groupJid"` // group
+	MediaURL string `json:"mediaUrl" form:"mediaUrl"` // opsional kalau upload file
+	SendAt   string `json:"sendAt" form:"sendAt"`     // opsional, RFC3339 / unix detik → kirim terjadwal
+}
+
+// POST /send/:instanceId/sticker & /by-number/:phoneNumber/sticker
+func SendSticker(c echo.Context) error {
+	return sendSticker(c, false)
+}
+
+// POST /send-group/:instanceId/sticker & /send-group/by-number/:phoneNumber/sticker
+func SendGroupSticker(c echo.Context) error {
+	return sendSticker(c, true)
+}
+
+func sendSticker(c echo.Context, group bool) error {
+	var req SendStickerRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	sendAt, err := parseSendAt(req.SendAt)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid 'sendAt'", "VALIDATION_ERROR", err.Error())
+	}
+
+	// 1. AMBIL GAMBAR (upload file / download dari URL)
+	var fileData []byte
+	var contentType string
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		if file, err := c.FormFile("file"); err == nil {
+			src, err := file.Open()
+			if err != nil {
+				return ErrorResponse(c, 500, "Failed to open file", "FILE_OPEN_FAILED", err.Error())
+			}
+			defer src.Close()
+
+			fileData, err = io.ReadAll(src)
+			if err != nil {
+				return ErrorResponse(c, 500, "Failed to read file", "FILE_READ_FAILED", err.Error())
+			}
+			contentType = file.Header.Get("Content-Type")
+		} else if !errors.Is(err, http.ErrMissingFile) {
+			return ErrorResponse(c, 400, "Invalid file", "FILE_REQUIRED", err.Error())
+		}
+	}
+	if fileData == nil {
+		if req.MediaURL == "" {
+			return ErrorResponse(c, 400, "File or 'mediaUrl' is required", "FILE_REQUIRED", "")
+		}
+		fileData, _, contentType, err = helper.DownloadFile(req.MediaURL)
+		if err != nil {
+			return ErrorResponse(c, 500, "Failed to download file", "DOWNLOAD_FAILED", err.Error())
+		}
+	}
+
+	// 2. VALIDATE FILE SIZE (input mengikuti batas image)
+	maxSize := getMaxFileSize("image")
+	if len(fileData) > maxSize {
+		return ErrorResponse(c, 400, "File too large", "FILE_TOO_LARGE",
+			fmt.Sprintf("File size: %d bytes, Max: %d bytes (image)", len(fileData), maxSize))
+	}
+
+	// 3. CEK FORMAT DARI ISI FILE, LALU CONVERT KE WEBP 512x512
+	if mimeType := helper.DetectMime(fileData, contentType, ""); mimeType != "image/png" && mimeType != "image/jpeg" && mimeType != "image/webp" {
+		return ErrorResponse(c, 400, "Unsupported sticker image", "UNSUPPORTED_MEDIA_TYPE",
+			fmt.Sprintf("Detected %s, use PNG, JPEG or static WebP", mimeType))
+	}
+	sticker, err := helper.ConvertToSticker(fileData)
+	if err != nil {
+		if errors.Is(err, helper.ErrUnsupportedStickerFormat) {
+			return ErrorResponse(c, 400, "Unsupported sticker image", "UNSUPPORTED_MEDIA_TYPE", err.Error())
+		}
+		if errors.Is(err, helper.ErrStickerTooLarge) {
+			return ErrorResponse(c, 400, "Sticker image too large", "STICKER_TOO_LARGE", err.Error())
+		}
+		return ErrorResponse(c, 500, "Failed to convert sticker", "STICKER_CONVERSION_FAILED", err.Error())
+	}
+
+	// 4. SESSION & TUJUAN
+	to := req.To
+	if group {
+		to = req.GroupJID
+	}
+	target, err := resolveSendTarget(c, to, group)
+	if err != nil {
+		return targetErrorResponse(c, err)
+	}
+
+	// 5. UPLOAD TO WHATSAPP (sticker memakai media key image)
+	uploaded, err := target.Session.Client.Upload(context.Background(), sticker, whatsmeow.MediaImage)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to upload media", "UPLOAD_FAILED",
+			fmt.Sprintf("Type: sticker, Size: %d bytes, Error: %v", len(sticker), err))
+	}
+
+	msg := helper.CreateStickerMessage(uploaded)
+
+	return sendToTarget(c, target, msg, sendAt, "Sticker sent successfully", map[string]interface{}{
+		"mediaType": "sticker",
+		"fileSize":  len(sticker),
+	})
+}
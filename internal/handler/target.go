@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// sendTarget session pengirim (dari :instanceId atau :phoneNumber) + JID tujuan
+type sendTarget struct {
+	Session *model.Session
+	From    string // nomor pengirim untuk route by-number
+	To      types.JID
+	Group   bool
+}
+
+// targetError error validasi yang langsung jadi response (lihat targetErrorResponse)
+type targetError struct {
+	Status  int
+	Message string
+	Code    string
+	Details string
+}
+
+func (e *targetError) Error() string { return e.Message }
+
+// resolveSendTarget cari session pengirim dari :instanceId (atau instance aktif :phoneNumber),
+// cek koneksi, lalu validasi tujuan: group = groupJid @g.us, personal = nomor terdaftar di WhatsApp.
+func resolveSendTarget(c echo.Context, to string, group bool) (*sendTarget, error) {
+	target := &sendTarget{Group: group}
+
+	instanceID := c.Param("instanceId")
+	if phoneNumber := c.Param("phoneNumber"); phoneNumber != "" {
+		inst, err := model.GetActiveInstanceByPhoneNumber(phoneNumber, ownerScope(c))
+		if err != nil {
+			if errors.Is(err, model.ErrNoActiveInstance) {
+				return nil, &targetError{404, "No active instance for this phone number", "NO_ACTIVE_INSTANCE", "Please login / scan QR for this number"}
+			}
+			return nil, &targetError{500, "Failed to get instance for this phone number", "DB_ERROR", err.Error()}
+		}
+		instanceID = inst.InstanceID
+		target.From = phoneNumber
+	}
+
+	session, err := service.GetSession(instanceID)
+	if err != nil {
+		return nil, &targetError{404, "Session not found", "SESSION_NOT_FOUND", "Please login first"}
+	}
+	if !session.IsConnected || !session.Client.IsConnected() || session.Client.Store.ID == nil {
+		return nil, &targetError{400, "WhatsApp session is not connected", "NOT_CONNECTED", "Please scan QR or reconnect"}
+	}
+	target.Session = session
+
+	if group {
+		if to == "" {
+			return nil, &targetError{400, "Field 'groupJid' is required", "VALIDATION_ERROR", ""}
+		}
+		groupJID, err := types.ParseJID(to)
+		if err != nil {
+			return nil, &targetError{400, "Invalid group JID", "INVALID_GROUP_JID", err.Error()}
+		}
+		if groupJID.Server != types.GroupServer {
+			return nil, &targetError{400, "Not a group JID", "NOT_GROUP_JID", "Group JID must end with @g.us"}
+		}
+		target.To = groupJID
+		return target, nil
+	}
+
+	if to == "" {
+		return nil, &targetError{400, "Field 'to' is required", "VALIDATION_ERROR", ""}
+	}
+	recipient, err := helper.FormatPhoneNumber(to)
+	if err != nil {
+		return nil, &targetError{400, "Invalid phone number", "INVALID_PHONE", err.Error()}
+	}
+	isRegistered, err := session.Client.IsOnWhatsApp(context.Background(), []string{recipient.User})
+	if err != nil {
+		return nil, &targetError{500, "Failed to verify phone number", "VERIFICATION_FAILED", err.Error()}
+	}
+	if len(isRegistered) == 0 || !isRegistered[0].IsIn {
+		return nil, &targetError{400, "Phone number is not registered on WhatsApp", "PHONE_NOT_REGISTERED",
+			"Please check the number or ask recipient to install WhatsApp"}
+	}
+	target.To = recipient
+	return target, nil
+}
+
+func targetErrorResponse(c echo.Context, err error) error {
+	var te *targetError
+	if errors.As(err, &te) {
+		return ErrorResponse(c, te.Status, te.Message, te.Code, te.Details)
+	}
+	return ErrorResponse(c, 500, "Failed to resolve recipient", "INTERNAL_ERROR", err.Error())
+}
+
+// sendToTarget kirim sekarang, atau masuk outbox / scheduled kalau sendAt / ?async=true.
+// data = field tambahan response (mis. latitude, contacts).
+func sendToTarget(c echo.Context, target *sendTarget, msg *waE2E.Message, sendAt *time.Time, message string, data map[string]interface{}) error {
+	if target.From != "" {
+		data["from"] = target.From
+	}
+	if target.Group {
+		data["groupJid"] = target.To.String()
+	} else {
+		data["to"] = target.To.User
+		data["verified"] = true
+	}
+
+	// Mode async / terjadwal: masuk outbox atau scheduled_messages
+	if sendAt != nil || isAsyncSend(c) {
+		return queueSend(c, target.Session.ID, target.To, msg, sendAt, data)
+	}
+
+	resp, err := service.SendMessage(context.Background(), target.Session.ID, target.To, msg, nil)
+	if err != nil {
+		return sendErrorResponse(c, "Failed to send message", err)
+	}
+
+	data["messageId"] = resp.ID
+	data["timestamp"] = resp.Timestamp.Unix()
+	return SuccessResponse(c, 200, message, data)
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Request body untuk create / update template
+type TemplateRequest struct {
+	Name      string `json:"name"`
+	Body      string `json:"body"`      // teks / caption, placeholder {{nama}}
+	MediaURL  string `json:"mediaUrl"`  // opsional, media default untuk endpoint media-url
+	MediaType string `json:"mediaType"` // image, video, document, audio
+}
+
+// Request body preview render template
+type RenderTemplateRequest struct {
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// POST /templates
+func CreateTemplate(c echo.Context) error {
+	var req TemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || req.Body == "" {
+		return ErrorResponse(c, 400, "Field 'name' and 'body' are required", "VALIDATION_ERROR", "")
+	}
+	if err := validateTemplateMediaType(req.MediaType); err != nil {
+		return ErrorResponse(c, 400, "Invalid media type", "VALIDATION_ERROR", err.Error())
+	}
+
+	var ownerID sql.NullInt64
+	if p := currentPrincipal(c); p != nil && p.UserID > 0 {
+		ownerID = sql.NullInt64{Int64: p.UserID, Valid: true}
+	}
+
+	tmpl := &model.Template{
+		OwnerID:   ownerID,
+		Name:      req.Name,
+		Body:      req.Body,
+		Variables: helper.TemplateVariables(req.Body),
+		MediaURL:  model.NullString(req.MediaURL),
+		MediaType: model.NullString(req.MediaType),
+	}
+	if err := model.InsertTemplate(tmpl); err != nil {
+		if errors.Is(err, model.ErrTemplateNameTaken) {
+			return ErrorResponse(c, 409, "Template name already exists", "TEMPLATE_NAME_TAKEN", "")
+		}
+		return ErrorResponse(c, 500, "Failed to create template", "DB_INSERT_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 201, "Template created", model.ToTemplateResponse(*tmpl))
+}
+
+// GET /templates
+func GetTemplates(c echo.Context) error {
+	templates, err := model.GetTemplates(ownerScope(c))
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get templates", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.TemplateResp, 0, len(templates))
+	for _, t := range templates {
+		result = append(result, model.ToTemplateResponse(t))
+	}
+
+	return SuccessResponse(c, 200, "Templates retrieved", map[string]interface{}{
+		"total":     len(result),
+		"templates": result,
+	})
+}
+
+// GET /templates/:templateId
+func GetTemplate(c echo.Context) error {
+	tmpl, err := templateFromParam(c)
+	if err != nil {
+		return templateErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Template retrieved", model.ToTemplateResponse(*tmpl))
+}
+
+// PUT /templates/:templateId
+func UpdateTemplate(c echo.Context) error {
+	tmpl, err := templateFromParam(c)
+	if err != nil {
+		return templateErrorResponse(c, err)
+	}
+
+	var req TemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		tmpl.Name = name
+	}
+	if req.Body != "" {
+		tmpl.Body = req.Body
+		tmpl.Variables = helper.TemplateVariables(req.Body)
+	}
+	if req.MediaURL != "" {
+		tmpl.MediaURL = model.NullString(req.MediaURL)
+	}
+	if req.MediaType != "" {
+		if err := validateTemplateMediaType(req.MediaType); err != nil {
+			return ErrorResponse(c, 400, "Invalid media type", "VALIDATION_ERROR", err.Error())
+		}
+		tmpl.MediaType = model.NullString(req.MediaType)
+	}
+
+	if err := model.UpdateTemplate(tmpl, ownerScope(c)); err != nil {
+		if errors.Is(err, model.ErrTemplateNameTaken) {
+			return ErrorResponse(c, 409, "Template name already exists", "TEMPLATE_NAME_TAKEN", "")
+		}
+		return templateErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Template updated", model.ToTemplateResponse(*tmpl))
+}
+
+// DELETE /templates/:templateId
+func DeleteTemplate(c echo.Context) error {
+	templateID, err := strconv.ParseInt(c.Param("templateId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid template ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	if err := model.DeleteTemplate(templateID, ownerScope(c)); err != nil {
+		return templateErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Template deleted", map[string]interface{}{
+		"templateId": templateID,
+	})
+}
+
+// POST /templates/:templateId/render (preview hasil render tanpa mengirim)
+func RenderTemplate(c echo.Context) error {
+	templateID, err := strconv.ParseInt(c.Param("templateId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid template ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	var req RenderTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	tmpl, text, err := applyTemplate(c, templateID, req.Variables)
+	if err != nil {
+		return templateErrorResponse(c, err)
+	}
+
+	return SuccessResponse(c, 200, "Template rendered", map[string]interface{}{
+		"templateId": tmpl.ID,
+		"text":       text,
+		"mediaUrl":   tmpl.MediaURL.String,
+		"mediaType":  tmpl.MediaType.String,
+	})
+}
+
+// applyTemplate ambil template (sesuai owner pemanggil) lalu render dengan variabel
+func applyTemplate(c echo.Context, templateID int64, vars map[string]interface{}) (*model.Template, string, error) {
+	tmpl, err := model.GetTemplate(templateID, ownerScope(c))
+	if err != nil {
+		return nil, "", err
+	}
+
+	text, err := helper.RenderTemplate(tmpl.Body, vars)
+	if err != nil {
+		return nil, "", err
+	}
+	return tmpl, text, nil
+}
+
+// formTemplateParams baca `templateId` dan `variables` (JSON object) dari form-data
+func formTemplateParams(c echo.Context) (int64, map[string]interface{}, error) {
+	var templateID int64
+	if v := c.FormValue("templateId"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("templateId must be a number")
+		}
+		templateID = id
+	}
+
+	var vars map[string]interface{}
+	if v := c.FormValue("variables"); v != "" {
+		if err := json.Unmarshal([]byte(v), &vars); err != nil {
+			return 0, nil, fmt.Errorf("variables must be a JSON object: %w", err)
+		}
+	}
+	return templateID, vars, nil
+}
+
+func templateFromParam(c echo.Context) (*model.Template, error) {
+	templateID, err := strconv.ParseInt(c.Param("templateId"), 10, 64)
+	if err != nil {
+		return nil, model.ErrTemplateNotFound
+	}
+	return model.GetTemplate(templateID, ownerScope(c))
+}
+
+func validateTemplateMediaType(mediaType string) error {
+	switch mediaType {
+	case "", "image", "video", "document", "audio":
+		return nil
+	}
+	return fmt.Errorf("mediaType must be one of image, video, document, audio")
+}
+
+func templateErrorResponse(c echo.Context, err error) error {
+	var missing *helper.MissingVariablesError
+	switch {
+	case errors.Is(err, model.ErrTemplateNotFound):
+		return ErrorResponse(c, 404, "Template not found", "TEMPLATE_NOT_FOUND", "")
+	case errors.As(err, &missing):
+		return ErrorResponse(c, 400, "Missing template variables", "TEMPLATE_VARIABLES_MISSING", err.Error())
+	}
+	return ErrorResponse(c, 500, "Failed to process template", "DB_ERROR", err.Error())
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const maxPreviewThumbnailSize = 300 * 1024 // thumbnail preview dikirim inline di pesan
+
+var errInvalidTextOption = errors.New("invalid message option")
+
+// TextMessageOptions field opsional pesan teks (personal, group, pool): reply, mention, preview link
+type TextMessageOptions struct {
+	QuotedMessageID string              `json:"quotedMessageId"` // reply ke pesan di riwayat instance (GET /messages)
+	Mentions        []string            `json:"mentions"`        // nomor HP / JID; tulis juga @nomor di teks supaya tampil
+	LinkPreview     *LinkPreviewRequest `json:"linkPreview"`     // kartu preview link
+}
+
+type LinkPreviewRequest struct {
+	URL          string `json:"url"` // kosong = link pertama di message
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	ThumbnailURL string `json:"thumbnailUrl"` // opsional, gambar JPEG kecil
+}
+
+// buildTextMessage pesan teks + contextInfo (reply / mention) + preview link sesuai opts
+func buildTextMessage(instanceID string, chat types.JID, text string, opts TextMessageOptions) (*waE2E.Message, error) {
+	mentions, err := helper.ParseMentionJIDs(opts.Mentions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidTextOption, err)
+	}
+
+	contextInfo, err := service.BuildContextInfo(instanceID, chat, strings.TrimSpace(opts.QuotedMessageID), mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, err := buildLinkPreview(text, opts.LinkPreview)
+	if err != nil {
+		return nil, err
+	}
+	return helper.BuildTextMessage(text, contextInfo, preview), nil
+}
+
+func buildLinkPreview(text string, req *LinkPreviewRequest) (*helper.LinkPreview, error) {
+	if req == nil {
+		return nil, nil
+	}
+
+	preview := &helper.LinkPreview{
+		URL:         strings.TrimSpace(req.URL),
+		Title:       req.Title,
+		Description: req.Description,
+	}
+	if preview.URL == "" {
+		preview.URL = helper.FirstURL(text)
+	}
+	if preview.URL == "" {
+		return nil, fmt.Errorf("%w: linkPreview.url is required when message contains no link", errInvalidTextOption)
+	}
+	if !strings.Contains(text, preview.URL) {
+		return nil, fmt.Errorf("%w: linkPreview.url must appear in message", errInvalidTextOption)
+	}
+
+	if req.ThumbnailURL != "" {
+		data, _, _, err := helper.DownloadFile(req.ThumbnailURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to download linkPreview.thumbnailUrl: %v", errInvalidTextOption, err)
+		}
+		if len(data) > maxPreviewThumbnailSize {
+			return nil, fmt.Errorf("%w: linkPreview thumbnail too large: %d bytes (max %d)", errInvalidTextOption, len(data), maxPreviewThumbnailSize)
+		}
+		preview.Thumbnail = data
+	}
+	return preview, nil
+}
+
+func textMessageErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, errInvalidTextOption):
+		return ErrorResponse(c, 400, "Invalid message options", "VALIDATION_ERROR", err.Error())
+	case errors.Is(err, service.ErrQuotedMessageNotFound):
+		return ErrorResponse(c, 404, "Quoted message not found", "QUOTED_MESSAGE_NOT_FOUND", err.Error())
+	}
+	return ErrorResponse(c, 500, "Failed to build message", "DB_ERROR", err.Error())
+}
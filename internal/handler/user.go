@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Request body untuk create user
+type CreateUserRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Role     string `json:"role"` // admin / user (default user)
+}
+
+// Request body untuk ganti password
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+// RequireAdmin middleware: hanya token dengan role admin yang boleh lanjut
+func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		p := currentPrincipal(c)
+		if p == nil || !p.IsAdmin() {
+			return ErrorResponse(c, 403, "Admin access required", "FORBIDDEN", "")
+		}
+		return next(c)
+	}
+}
+
+// POST /users (admin)
+func CreateUser(c echo.Context) error {
+	var req CreateUserRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.Username == "" || req.Password == "" {
+		return ErrorResponse(c, 400, "Fields 'username' and 'password' are required", "VALIDATION_ERROR", "")
+	}
+
+	user, err := service.CreateUser(req.Username, req.Password, req.Role)
+	if err != nil {
+		if errors.Is(err, model.ErrUsernameTaken) {
+			return ErrorResponse(c, 409, "Username already exists", "USERNAME_TAKEN", "")
+		}
+		if errors.Is(err, service.ErrWeakPassword) || errors.Is(err, service.ErrInvalidRole) {
+			return ErrorResponse(c, 400, "Invalid user data", "VALIDATION_ERROR", err.Error())
+		}
+		return ErrorResponse(c, 500, "Failed to create user", "DB_INSERT_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 201, "User created", model.ToUserResponse(*user))
+}
+
+// GET /users (admin)
+func GetUsers(c echo.Context) error {
+	users, err := model.GetAllUsers()
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get users", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.UserResp, 0, len(users))
+	for _, u := range users {
+		result = append(result, model.ToUserResponse(u))
+	}
+
+	return SuccessResponse(c, 200, "Users retrieved", map[string]interface{}{
+		"total": len(result),
+		"users": result,
+	})
+}
+
+// POST /users/:userId/disable (admin)
+func DisableUser(c echo.Context) error {
+	return setUserActive(c, false)
+}
+
+// POST /users/:userId/enable (admin)
+func EnableUser(c echo.Context) error {
+	return setUserActive(c, true)
+}
+
+func setUserActive(c echo.Context, isActive bool) error {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid user ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	if claims := currentClaims(c); !isActive && claims != nil && claims.UserID == userID {
+		return ErrorResponse(c, 400, "You cannot disable your own account", "VALIDATION_ERROR", "")
+	}
+
+	if err := service.SetUserActive(userID, isActive); err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return ErrorResponse(c, 404, "User not found", "USER_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to update user", "DB_UPDATE_FAILED", err.Error())
+	}
+
+	message := "User disabled"
+	if isActive {
+		message = "User enabled"
+	}
+	return SuccessResponse(c, 200, message, map[string]interface{}{
+		"userId":   userID,
+		"isActive": isActive,
+	})
+}
+
+// PUT /users/me/password - user ganti password sendiri (wajib oldPassword)
+func ChangeMyPassword(c echo.Context) error {
+	claims := currentClaims(c)
+	if claims == nil || claims.UserID == 0 {
+		return ErrorResponse(c, 401, "Invalid token", "UNAUTHORIZED", "")
+	}
+
+	var req ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.OldPassword == "" || req.NewPassword == "" {
+		return ErrorResponse(c, 400, "Fields 'oldPassword' and 'newPassword' are required", "VALIDATION_ERROR", "")
+	}
+
+	return changePassword(c, claims.UserID, req, true)
+}
+
+// PUT /users/:userId/password (admin) - reset password tanpa oldPassword
+func ResetUserPassword(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid user ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	var req ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if req.NewPassword == "" {
+		return ErrorResponse(c, 400, "Field 'newPassword' is required", "VALIDATION_ERROR", "")
+	}
+
+	return changePassword(c, userID, req, false)
+}
+
+func changePassword(c echo.Context, userID int64, req ChangePasswordRequest, verifyOld bool) error {
+	err := service.ChangePassword(userID, req.OldPassword, req.NewPassword, verifyOld)
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return ErrorResponse(c, 404, "User not found", "USER_NOT_FOUND", "")
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			return ErrorResponse(c, 400, "Old password is incorrect", "INVALID_PASSWORD", "")
+		}
+		if errors.Is(err, service.ErrWeakPassword) {
+			return ErrorResponse(c, 400, "Invalid new password", "VALIDATION_ERROR", err.Error())
+		}
+		return ErrorResponse(c, 500, "Failed to change password", "DB_UPDATE_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Password changed, please login again on other devices", map[string]interface{}{
+		"userId": userID,
+	})
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
+	"gowa-yourself/internal/ws"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Request body untuk create / update webhook
+type WebhookRequest struct {
+	URL      string   `json:"url" validate:"required"`
+	Secret   string   `json:"secret"`
+	Events   []string `json:"events"` // kosong = semua event
+	IsActive *bool    `json:"isActive"`
+}
+
+// POST /webhooks/:instanceId
+func CreateWebhook(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	if err := service.ValidateWebhookURL(req.URL); err != nil {
+		return ErrorResponse(c, 400, "Invalid webhook URL", "VALIDATION_ERROR", err.Error())
+	}
+
+	if _, err := model.GetInstanceByInstanceID(instanceID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, 404, "Instance not found", "INSTANCE_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get instance", "DB_ERROR", err.Error())
+	}
+
+	events, err := normalizeEvents(req.Events)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid webhook events", "VALIDATION_ERROR", err.Error())
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = generateWebhookSecret()
+	}
+
+	webhook := &model.Webhook{
+		InstanceID: instanceID,
+		URL:        req.URL,
+		Secret:     secret,
+		Events:     events,
+		IsActive:   req.IsActive == nil || *req.IsActive,
+	}
+	if err := model.InsertWebhook(webhook); err != nil {
+		return ErrorResponse(c, 500, "Failed to create webhook", "DB_INSERT_FAILED", err.Error())
+	}
+
+	// Secret hanya ditampilkan utuh sekali di sini, response lain memakai versi mask
+	resp := model.ToWebhookResponse(*webhook)
+	resp.Secret = webhook.Secret
+	return SuccessResponse(c, 201, "Webhook created", resp)
+}
+
+// GET /webhooks/:instanceId
+func GetWebhooks(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	webhooks, err := model.GetWebhooksByInstance(instanceID)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get webhooks", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.WebhookResp, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, model.ToWebhookResponse(w))
+	}
+
+	return SuccessResponse(c, 200, "Webhooks retrieved", map[string]interface{}{
+		"instanceId": instanceID,
+		"total":      len(result),
+		"webhooks":   result,
+	})
+}
+
+// PUT /webhooks/:instanceId/:webhookId
+func UpdateWebhook(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	webhookID, err := strconv.ParseInt(c.Param("webhookId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid webhook ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	var req WebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return ErrorResponse(c, 400, "Invalid request body", "INVALID_REQUEST", err.Error())
+	}
+
+	webhook, err := model.GetWebhook(instanceID, webhookID)
+	if err != nil {
+		if errors.Is(err, model.ErrWebhookNotFound) {
+			return ErrorResponse(c, 404, "Webhook not found", "WEBHOOK_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get webhook", "DB_ERROR", err.Error())
+	}
+
+	if req.URL != "" {
+		if err := service.ValidateWebhookURL(req.URL); err != nil {
+			return ErrorResponse(c, 400, "Invalid webhook URL", "VALIDATION_ERROR", err.Error())
+		}
+		webhook.URL = req.URL
+	}
+	if req.Secret != "" {
+		webhook.Secret = req.Secret
+	}
+	if req.Events != nil {
+		events, err := normalizeEvents(req.Events)
+		if err != nil {
+			return ErrorResponse(c, 400, "Invalid webhook events", "VALIDATION_ERROR", err.Error())
+		}
+		webhook.Events = events
+	}
+	if req.IsActive != nil {
+		webhook.IsActive = *req.IsActive
+	}
+
+	if err := model.UpdateWebhook(webhook); err != nil {
+		return ErrorResponse(c, 500, "Failed to update webhook", "DB_UPDATE_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Webhook updated", model.ToWebhookResponse(*webhook))
+}
+
+// DELETE /webhooks/:instanceId/:webhookId
+func DeleteWebhook(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	webhookID, err := strconv.ParseInt(c.Param("webhookId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid webhook ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	if err := model.DeleteWebhook(instanceID, webhookID); err != nil {
+		if errors.Is(err, model.ErrWebhookNotFound) {
+			return ErrorResponse(c, 404, "Webhook not found", "WEBHOOK_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to delete webhook", "DB_DELETE_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 200, "Webhook deleted", map[string]interface{}{
+		"instanceId": instanceID,
+		"webhookId":  webhookID,
+	})
+}
+
+// GET /webhooks/:instanceId/:webhookId/deliveries?status=&page=&limit=
+func GetWebhookDeliveries(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	webhookID, err := strconv.ParseInt(c.Param("webhookId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid webhook ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	if _, err := model.GetWebhook(instanceID, webhookID); err != nil {
+		if errors.Is(err, model.ErrWebhookNotFound) {
+			return ErrorResponse(c, 404, "Webhook not found", "WEBHOOK_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get webhook", "DB_ERROR", err.Error())
+	}
+
+	page, limit := parsePagination(c)
+
+	deliveries, total, err := model.GetWebhookDeliveries(webhookID, c.QueryParam("status"), limit, (page-1)*limit)
+	if err != nil {
+		return ErrorResponse(c, 500, "Failed to get deliveries", "DB_ERROR", err.Error())
+	}
+
+	result := make([]model.WebhookDeliveryResp, 0, len(deliveries))
+	for _, d := range deliveries {
+		result = append(result, model.ToWebhookDeliveryResponse(d))
+	}
+
+	return SuccessResponse(c, 200, "Webhook deliveries retrieved", map[string]interface{}{
+		"webhookId":  webhookID,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"deliveries": result,
+	})
+}
+
+// POST /webhooks/:instanceId/:webhookId/deliveries/:deliveryId/replay
+func ReplayWebhookDelivery(c echo.Context) error {
+	instanceID := c.Param("instanceId")
+
+	webhookID, err := strconv.ParseInt(c.Param("webhookId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid webhook ID", "VALIDATION_ERROR", err.Error())
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		return ErrorResponse(c, 400, "Invalid delivery ID", "VALIDATION_ERROR", err.Error())
+	}
+
+	if _, err := model.GetWebhook(instanceID, webhookID); err != nil {
+		if errors.Is(err, model.ErrWebhookNotFound) {
+			return ErrorResponse(c, 404, "Webhook not found", "WEBHOOK_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to get webhook", "DB_ERROR", err.Error())
+	}
+
+	if service.Webhooks == nil {
+		return ErrorResponse(c, 503, "Webhook dispatcher is not running", "WEBHOOK_DISABLED", "")
+	}
+
+	delivery, err := service.Webhooks.Replay(webhookID, deliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorResponse(c, 404, "Delivery not found", "DELIVERY_NOT_FOUND", "")
+		}
+		return ErrorResponse(c, 500, "Failed to replay delivery", "REPLAY_FAILED", err.Error())
+	}
+
+	return SuccessResponse(c, 202, "Delivery queued for replay", model.ToWebhookDeliveryResponse(*delivery))
+}
+
+// Helper: trim & buang event kosong / duplikat, tolak nama event yang tidak dikenal
+func normalizeEvents(events []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(events))
+	for _, e := range events {
+		e = strings.ToUpper(strings.TrimSpace(e))
+		if e == "" || seen[e] {
+			continue
+		}
+		if !ws.IsWebhookEvent(e) {
+			return nil, fmt.Errorf("unknown event %q", e)
+		}
+		seen[e] = true
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func generateWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
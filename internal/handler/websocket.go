@@ -1,35 +1,78 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/service"
 	"gowa-yourself/internal/ws"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 )
 
+// WSAllowedOrigins daftar origin browser yang boleh membuka /ws (di-set dari config).
+// "*" = semua origin. Request tanpa header Origin (non-browser) selalu diizinkan.
+var WSAllowedOrigins []string
+
+// wsAccessCacheTTL lama cache hasil cek akses instance per koneksi WS
+const wsAccessCacheTTL = time.Minute
+
 // upgrader untuk Gorilla
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// TODO: production: batasi origin
+	CheckOrigin:     checkWSOrigin,
+}
+
+func checkWSOrigin(r *http.Request) bool {
+	origin := r.Header.Get(echo.HeaderOrigin)
+	if origin == "" {
 		return true
-	},
+	}
+	for _, allowed := range WSAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+	log.Printf("ws: origin %q rejected", origin)
+	return false
 }
 
-// WebSocketHandler meng-handle koneksi WS di route /ws
+// WebSocketHandler meng-handle koneksi WS di route /ws.
+// Token (JWT atau API key) bisa dikirim lewat query ?token=, header Authorization: Bearer / X-API-Key,
+// atau pesan pertama {"action":"auth","token":"..."} setelah koneksi terbuka.
 func WebSocketHandler(hub *ws.Hub) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		var auth *ws.ClientAuth
+		if token := wsTokenFromRequest(c.Request()); token != "" {
+			a, err := authenticateWS(token)
+			if err != nil {
+				if errors.Is(err, service.ErrUserDisabled) {
+					return ErrorResponse(c, 401, "User is disabled", "USER_DISABLED", err.Error())
+				}
+				return ErrorResponse(c, 401, "Invalid token", "UNAUTHORIZED", err.Error())
+			}
+			auth = a
+		}
+
 		conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 		if err != nil {
 			log.Printf("ws upgrade error: %v", err)
 			return err
 		}
 
-		client := ws.NewClient(hub, conn)
+		client := ws.NewClient(hub, conn, authenticateWS)
+		if auth != nil {
+			client.Authorize(auth)
+		}
 		hub.Register(client)
 
 		go client.WritePump()
@@ -38,3 +81,124 @@ func WebSocketHandler(hub *ws.Hub) echo.HandlerFunc {
 		return nil
 	}
 }
+
+func wsTokenFromRequest(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+	if h := r.Header.Get(echo.HeaderAuthorization); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return ""
+}
+
+// authenticateWS validasi JWT atau API key (prefix swa_) untuk koneksi WebSocket
+func authenticateWS(token string) (*ws.ClientAuth, error) {
+	if strings.HasPrefix(token, "swa_") {
+		return authenticateWSAPIKey(token)
+	}
+
+	claims := new(Claims)
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return JwtKey, nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	// Token tetap valid sampai expired, jadi user yang di-disable ditolak di sini (seperti API key)
+	active, err := service.IsUserActive(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, service.ErrUserDisabled
+	}
+
+	p := &Principal{UserID: claims.UserID, Username: claims.Username, Role: claims.Role}
+	auth := &ws.ClientAuth{
+		Subject:   claims.Username,
+		CanAccess: wsAccessChecker(p),
+		Active:    wsActiveChecker(p),
+	}
+	if claims.ExpiresAt != nil {
+		auth.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return auth, nil
+}
+
+func authenticateWSAPIKey(raw string) (*ws.ClientAuth, error) {
+	key, user, err := service.AuthenticateAPIKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	// Event WS berisi QR & isi pesan, jadi API key wajib punya permission read
+	if !key.HasPermission(model.PermRead) {
+		return nil, errors.New("api key does not have 'read' permission")
+	}
+
+	p := &Principal{UserID: user.ID, Username: user.Username, Role: user.Role, APIKey: key}
+	auth := &ws.ClientAuth{
+		Subject:   user.Username + " (" + key.KeyPrefix + ")",
+		CanAccess: wsAccessChecker(p),
+		Active:    wsActiveChecker(p),
+	}
+	if key.ExpiresAt.Valid {
+		auth.ExpiresAt = key.ExpiresAt.Time
+	}
+	return auth, nil
+}
+
+// wsActiveChecker cek status aktif user saat event dikirim (di-cache oleh service.IsUserActive);
+// error DB tidak memutus koneksi.
+func wsActiveChecker(p *Principal) func() bool {
+	return func() bool {
+		active, err := service.IsUserActive(p.UserID)
+		if err != nil {
+			log.Printf("ws: failed to check user %d: %v", p.UserID, err)
+			return true
+		}
+		return active
+	}
+}
+
+// wsAccessChecker cek kepemilikan & scope instance untuk setiap event,
+// hasilnya di-cache sebentar supaya hub tidak query DB tiap event.
+func wsAccessChecker(p *Principal) func(instanceID string) bool {
+	if p.OwnerScope() == 0 && (p.APIKey == nil || !p.APIKey.IsScoped()) {
+		return nil // admin tanpa scope: semua instance
+	}
+
+	type entry struct {
+		allowed bool
+		checked time.Time
+	}
+	var (
+		mu    sync.Mutex
+		cache = make(map[string]entry)
+	)
+
+	return func(instanceID string) bool {
+		if instanceID == "" {
+			return false // event tanpa instance hanya untuk admin
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if e, ok := cache[instanceID]; ok && time.Since(e.checked) < wsAccessCacheTTL {
+			return e.allowed
+		}
+
+		inst, err := model.GetInstanceByInstanceID(instanceID)
+		allowed := err == nil && p.OwnsInstance(inst) && p.CanAccessInstance(inst)
+		cache[instanceID] = entry{allowed: allowed, checked: time.Now()}
+		return allowed
+	}
+}
@@ -0,0 +1,67 @@
+package helper
+
+import (
+	"fmt"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// Location isi pesan lokasi (pin) atau live location
+type Location struct {
+	Latitude       float64
+	Longitude      float64
+	Name           string // pin biasa
+	Address        string // pin biasa
+	URL            string // pin biasa
+	Caption        string // live location
+	AccuracyMeters uint32
+	Live           bool
+}
+
+// ValidateCoordinates latitude -90..90, longitude -180..180
+func ValidateCoordinates(lat, long float64) error {
+	if lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if long < -180 || long > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// CreateLocationMessage LocationMessage (pin) atau LiveLocationMessage kalau loc.Live
+func CreateLocationMessage(loc Location) *waE2E.Message {
+	if loc.Live {
+		live := &waE2E.LiveLocationMessage{
+			DegreesLatitude:  proto.Float64(loc.Latitude),
+			DegreesLongitude: proto.Float64(loc.Longitude),
+			SequenceNumber:   proto.Int64(1),
+		}
+		if loc.Caption != "" {
+			live.Caption = proto.String(loc.Caption)
+		}
+		if loc.AccuracyMeters > 0 {
+			live.AccuracyInMeters = proto.Uint32(loc.AccuracyMeters)
+		}
+		return &waE2E.Message{LiveLocationMessage: live}
+	}
+
+	pin := &waE2E.LocationMessage{
+		DegreesLatitude:  proto.Float64(loc.Latitude),
+		DegreesLongitude: proto.Float64(loc.Longitude),
+	}
+	if loc.Name != "" {
+		pin.Name = proto.String(loc.Name)
+	}
+	if loc.Address != "" {
+		pin.Address = proto.String(loc.Address)
+	}
+	if loc.URL != "" {
+		pin.URL = proto.String(loc.URL)
+	}
+	if loc.AccuracyMeters > 0 {
+		pin.AccuracyInMeters = proto.Uint32(loc.AccuracyMeters)
+	}
+	return &waE2E.Message{LocationMessage: pin}
+}
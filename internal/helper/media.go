@@ -12,66 +12,23 @@ import (
 	"go.mau.fi/whatsmeow/proto/waE2E"
 )
 
-// DetectMediaType detects media type from filename extension
-func DetectMediaType(filename string) string {
-	ext := strings.ToLower(filepath.Ext(filename))
-
-	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
-		return "image"
-	case ".mp4", ".mov", ".avi", ".mkv":
-		return "video"
-	case ".mp3", ".ogg", ".m4a", ".opus":
-		return "audio"
-	case ".pdf", ".doc", ".docx", ".xls", ".xlsx", ".txt", ".zip":
-		return "document"
-	default:
-		return "document"
-	}
-}
-
-// GetMimeType returns MIME type based on media type
-func GetMimeType(mediaType, filename string) string {
-	ext := strings.ToLower(filepath.Ext(filename))
-
+// CreateMediaMessage creates WhatsApp media message based on type (mimeType dari DetectMime)
+// WhatsmeowMediaType mapping mediaType ("image"/"video"/"audio"/"document") ke tipe upload whatsmeow
+func WhatsmeowMediaType(mediaType string) whatsmeow.MediaType {
 	switch mediaType {
 	case "image":
-		switch ext {
-		case ".jpg", ".jpeg":
-			return "image/jpeg"
-		case ".png":
-			return "image/png"
-		case ".gif":
-			return "image/gif"
-		case ".webp":
-			return "image/webp"
-		default:
-			return "image/jpeg"
-		}
+		return whatsmeow.MediaImage
 	case "video":
-		return "video/mp4"
+		return whatsmeow.MediaVideo
 	case "audio":
-		return "audio/mpeg"
-	default: // document
-		switch ext {
-		case ".pdf":
-			return "application/pdf"
-		case ".doc", ".docx":
-			return "application/msword"
-		case ".xls", ".xlsx":
-			return "application/vnd.ms-excel"
-		case ".zip":
-			return "application/zip"
-		default:
-			return "application/octet-stream"
-		}
+		return whatsmeow.MediaAudio
+	default:
+		return whatsmeow.MediaDocument
 	}
 }
 
-// CreateMediaMessage creates WhatsApp media message based on type
-func CreateMediaMessage(uploaded whatsmeow.UploadResponse, caption, filename, mediaType string) *waE2E.Message {
+func CreateMediaMessage(uploaded whatsmeow.UploadResponse, caption, filename, mediaType, mimeType string) *waE2E.Message {
 	msg := &waE2E.Message{}
-	mimeType := GetMimeType(mediaType, filename)
 
 	switch mediaType {
 	case "image":
@@ -123,8 +80,8 @@ func CreateMediaMessage(uploaded whatsmeow.UploadResponse, caption, filename, me
 	return msg
 }
 
-// DownloadFile downloads file from URL and returns data and filename
-func DownloadFile(url string) ([]byte, string, error) {
+// DownloadFile downloads file from URL and returns data, filename and Content-Type header
+func DownloadFile(url string) ([]byte, string, string, error) {
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: 30 * time.Second,
@@ -139,7 +96,7 @@ func DownloadFile(url string) ([]byte, string, error) {
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %v", err)
+		return nil, "", "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Add comprehensive headers to avoid 403
@@ -152,27 +109,27 @@ func DownloadFile(url string) ([]byte, string, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to download: %v", err)
+		return nil, "", "", fmt.Errorf("failed to download: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, "", fmt.Errorf("failed to download: status %d (%s)", resp.StatusCode, resp.Status)
+		return nil, "", "", fmt.Errorf("failed to download: status %d (%s)", resp.StatusCode, resp.Status)
 	}
 
 	// Read response body
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read response: %v", err)
+		return nil, "", "", fmt.Errorf("failed to read response: %v", err)
 	}
 
 	// Validate file size (WhatsApp limit ~100MB for documents)
 	if len(data) > 100*1024*1024 {
-		return nil, "", fmt.Errorf("file too large: %d bytes (max 100MB)", len(data))
+		return nil, "", "", fmt.Errorf("file too large: %d bytes (max 100MB)", len(data))
 	}
 
 	if len(data) == 0 {
-		return nil, "", fmt.Errorf("downloaded file is empty")
+		return nil, "", "", fmt.Errorf("downloaded file is empty")
 	}
 
 	// Extract filename from URL or Content-Disposition header
@@ -199,37 +156,20 @@ func DownloadFile(url string) ([]byte, string, error) {
 
 	// Default fallback
 	if filename == "." || filename == "/" || filename == "" {
-		// Try to detect extension from content-type
-		contentType := resp.Header.Get("Content-Type")
-		ext := getExtensionFromContentType(contentType)
-		filename = "document" + ext
+		filename = "document"
 	}
 
-	return data, filename, nil
-}
+	contentType := resp.Header.Get("Content-Type")
 
-// Helper to get file extension from Content-Type
-func getExtensionFromContentType(contentType string) string {
-	contentType = strings.ToLower(strings.Split(contentType, ";")[0])
-
-	switch contentType {
-	case "application/pdf":
-		return ".pdf"
-	case "image/jpeg", "image/jpg":
-		return ".jpg"
-	case "image/png":
-		return ".png"
-	case "image/gif":
-		return ".gif"
-	case "image/webp":
-		return ".webp"
-	case "video/mp4":
-		return ".mp4"
-	case "audio/mpeg":
-		return ".mp3"
-	case "application/zip":
-		return ".zip"
-	default:
-		return ".bin"
+	// Ekstensi kosong / tidak dikenal (mis. .bin, .php) → pakai ekstensi dari isi file / Content-Type
+	if _, known := mimeByExtension[strings.ToLower(filepath.Ext(filename))]; !known {
+		mimeType := DetectMime(data, contentType, filename)
+		ext := ExtensionFromMime(mimeType)
+		if ext == "" {
+			ext = ".bin"
+		}
+		filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + ext
 	}
+
+	return data, filename, contentType, nil
 }
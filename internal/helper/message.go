@@ -0,0 +1,210 @@
+package helper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s]+`)
+
+// LinkPreview isi kartu preview link di pesan teks (ExtendedTextMessage)
+type LinkPreview struct {
+	URL         string
+	Title       string
+	Description string
+	Thumbnail   []byte // JPEG kecil, opsional
+}
+
+// MessageContent adalah ringkasan isi pesan WhatsApp yang disimpan ke DB custom.
+type MessageContent struct {
+	Type     string // text, image, video, audio, ptt, document, sticker, location, contact, poll, reaction, unknown
+	Body     string // isi teks atau caption
+	Mimetype string
+	FileName string
+	FileSize uint64
+}
+
+// HasUploadedMedia true kalau pesan berisi file yang di-upload ke CDN WhatsApp (image, video, audio, document, sticker)
+func HasUploadedMedia(msg *waE2E.Message) bool {
+	return msg.GetImageMessage() != nil ||
+		msg.GetVideoMessage() != nil ||
+		msg.GetAudioMessage() != nil ||
+		msg.GetDocumentMessage() != nil ||
+		msg.GetDocumentWithCaptionMessage().GetMessage().GetDocumentMessage() != nil ||
+		msg.GetStickerMessage() != nil
+}
+
+// ExtractMessageContent membaca tipe dan isi utama dari sebuah waE2E.Message.
+// Return ok=false untuk pesan yang tidak perlu disimpan (protocol, key distribution, dll).
+func ExtractMessageContent(msg *waE2E.Message) (content MessageContent, ok bool) {
+	if msg == nil {
+		return content, false
+	}
+
+	switch {
+	case msg.GetConversation() != "":
+		content.Type = "text"
+		content.Body = msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		content.Type = "text"
+		content.Body = msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		m := msg.GetImageMessage()
+		content.Type = "image"
+		content.Body = m.GetCaption()
+		content.Mimetype = m.GetMimetype()
+		content.FileSize = m.GetFileLength()
+	case msg.GetVideoMessage() != nil:
+		m := msg.GetVideoMessage()
+		content.Type = "video"
+		content.Body = m.GetCaption()
+		content.Mimetype = m.GetMimetype()
+		content.FileSize = m.GetFileLength()
+	case msg.GetAudioMessage() != nil:
+		m := msg.GetAudioMessage()
+		content.Type = "audio"
+		if m.GetPTT() {
+			content.Type = "ptt"
+		}
+		content.Mimetype = m.GetMimetype()
+		content.FileSize = m.GetFileLength()
+	case msg.GetDocumentMessage() != nil:
+		m := msg.GetDocumentMessage()
+		content.Type = "document"
+		content.Body = m.GetCaption()
+		content.Mimetype = m.GetMimetype()
+		content.FileName = m.GetFileName()
+		content.FileSize = m.GetFileLength()
+	case msg.GetStickerMessage() != nil:
+		m := msg.GetStickerMessage()
+		content.Type = "sticker"
+		content.Mimetype = m.GetMimetype()
+		content.FileSize = m.GetFileLength()
+	case msg.GetLocationMessage() != nil:
+		m := msg.GetLocationMessage()
+		content.Type = "location"
+		content.Body = strings.TrimSpace(m.GetName() + " " + m.GetAddress())
+	case msg.GetLiveLocationMessage() != nil:
+		content.Type = "live_location"
+		content.Body = msg.GetLiveLocationMessage().GetCaption()
+	case msg.GetContactMessage() != nil:
+		content.Type = "contact"
+		content.Body = msg.GetContactMessage().GetDisplayName()
+	case msg.GetContactsArrayMessage() != nil:
+		content.Type = "contacts"
+		content.Body = msg.GetContactsArrayMessage().GetDisplayName()
+	case PollCreation(msg) != nil:
+		content.Type = "poll"
+		content.Body = PollCreation(msg).GetName()
+	case msg.GetReactionMessage() != nil:
+		content.Type = "reaction"
+		content.Body = msg.GetReactionMessage().GetText()
+	case msg.GetProtocolMessage() != nil,
+		msg.GetEditedMessage() != nil,
+		msg.GetSenderKeyDistributionMessage() != nil,
+		msg.GetPollUpdateMessage() != nil:
+		// Pesan teknis, tidak disimpan sebagai chat
+		return content, false
+	default:
+		content.Type = "unknown"
+	}
+
+	return content, true
+}
+
+// PollCreation isi polling dari semua versi PollCreationMessage (nil kalau bukan polling)
+func PollCreation(msg *waE2E.Message) *waE2E.PollCreationMessage {
+	switch {
+	case msg.GetPollCreationMessage() != nil:
+		return msg.GetPollCreationMessage()
+	case msg.GetPollCreationMessageV2() != nil:
+		return msg.GetPollCreationMessageV2()
+	case msg.GetPollCreationMessageV3() != nil:
+		return msg.GetPollCreationMessageV3()
+	case msg.GetPollCreationMessageV5() != nil:
+		return msg.GetPollCreationMessageV5()
+	}
+	return nil
+}
+
+// ChatType mengklasifikasikan JID chat: personal, group, status, broadcast, newsletter.
+func ChatType(chat types.JID) string {
+	switch {
+	case chat == types.StatusBroadcastJID:
+		return "status"
+	case chat.Server == types.GroupServer:
+		return "group"
+	case chat.Server == types.BroadcastServer:
+		return "broadcast"
+	case chat.Server == types.NewsletterServer:
+		return "newsletter"
+	default:
+		return "personal"
+	}
+}
+
+// BuildTextMessage pesan teks biasa (Conversation), atau ExtendedTextMessage kalau ada
+// reply / mention (contextInfo) atau preview link.
+func BuildTextMessage(text string, contextInfo *waE2E.ContextInfo, preview *LinkPreview) *waE2E.Message {
+	if contextInfo == nil && preview == nil {
+		return &waE2E.Message{Conversation: proto.String(text)}
+	}
+
+	ext := &waE2E.ExtendedTextMessage{
+		Text:        proto.String(text),
+		ContextInfo: contextInfo,
+	}
+	if preview != nil {
+		ext.MatchedText = proto.String(preview.URL)
+		ext.Title = proto.String(preview.Title)
+		ext.Description = proto.String(preview.Description)
+		ext.PreviewType = waE2E.ExtendedTextMessage_NONE.Enum()
+		if len(preview.Thumbnail) > 0 {
+			ext.JPEGThumbnail = preview.Thumbnail
+		}
+	}
+	return &waE2E.Message{ExtendedTextMessage: ext}
+}
+
+// FirstURL link http(s) pertama di teks (untuk preview link)
+func FirstURL(text string) string {
+	return strings.TrimRight(urlPattern.FindString(text), ".,;:!?)")
+}
+
+// ParseMentionJIDs ubah daftar mention (nomor HP atau JID) ke JID user, duplikat dibuang
+func ParseMentionJIDs(mentions []string) ([]string, error) {
+	jids := make([]string, 0, len(mentions))
+	seen := make(map[string]bool)
+	for _, m := range mentions {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+
+		var jid types.JID
+		var err error
+		if strings.Contains(m, "@") {
+			jid, err = types.ParseJID(m)
+			if err == nil && jid.Server != types.DefaultUserServer && jid.Server != types.HiddenUserServer {
+				err = fmt.Errorf("not a user JID")
+			}
+		} else {
+			jid, err = FormatPhoneNumber(m)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid mention %q: %v", m, err)
+		}
+
+		s := jid.ToNonAD().String()
+		if !seen[s] {
+			seen[s] = true
+			jids = append(jids, s)
+		}
+	}
+	return jids, nil
+}
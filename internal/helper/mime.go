@@ -0,0 +1,331 @@
+package helper
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+const defaultMimeType = "application/octet-stream"
+
+// Mapping ekstensi → MIME type (fallback kalau magic bytes tidak dikenali)
+var mimeByExtension = map[string]string{
+	// image
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".heic": "image/heic",
+	".avif": "image/avif",
+	".svg":  "image/svg+xml",
+	".ico":  "image/x-icon",
+
+	// video
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".3gp":  "video/3gpp",
+	".mov":  "video/quicktime",
+	".avi":  "video/x-msvideo",
+	".mkv":  "video/x-matroska",
+	".webm": "video/webm",
+
+	// audio
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": VoiceNoteMimeType,
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+	".amr":  "audio/amr",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+
+	// document
+	".pdf":  "application/pdf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".rtf":  "application/rtf",
+	".txt":  "text/plain",
+	".csv":  "text/csv",
+	".json": "application/json",
+	".xml":  "application/xml",
+	".html": "text/html",
+	".zip":  "application/zip",
+	".rar":  "application/vnd.rar",
+	".7z":   "application/x-7z-compressed",
+	".gz":   "application/gzip",
+	".apk":  "application/vnd.android.package-archive",
+}
+
+// Ekstensi utama per MIME type (untuk memberi nama file hasil download)
+var extensionByMime = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/tiff":      ".tiff",
+	"video/mp4":       ".mp4",
+	"audio/ogg":       ".ogg",
+	VoiceNoteMimeType: ".opus",
+	"text/plain":      ".txt",
+}
+
+func init() {
+	for ext, m := range mimeByExtension {
+		if _, ok := extensionByMime[m]; !ok {
+			extensionByMime[m] = ext
+		}
+	}
+}
+
+// DetectMime tentukan MIME type file: magic bytes isi file dulu, lalu header Content-Type,
+// lalu ekstensi filename, terakhir sniffing teks (net/http).
+func DetectMime(data []byte, contentType, filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	if m := sniffMime(data, ext); m != "" {
+		return m
+	}
+	if m := normalizeMime(contentType); m != "" && m != defaultMimeType && m != "binary/octet-stream" {
+		return m
+	}
+	if m, ok := mimeByExtension[ext]; ok {
+		return m
+	}
+	if len(data) > 0 {
+		if m := normalizeMime(http.DetectContentType(data)); m != defaultMimeType {
+			return m
+		}
+	}
+	return defaultMimeType
+}
+
+// MediaTypeFromMime pilih jenis pesan WhatsApp; format yang tidak bisa diputar / ditampilkan
+// langsung oleh WhatsApp dikirim sebagai document supaya file tetap utuh.
+func MediaTypeFromMime(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return "image"
+	case "video/mp4", "video/3gpp":
+		return "video"
+	case "audio/mpeg", "audio/ogg", VoiceNoteMimeType, "audio/mp4", "audio/aac", "audio/amr":
+		return "audio"
+	default:
+		return "document"
+	}
+}
+
+// ExtensionFromMime ekstensi file untuk MIME type ("" kalau tidak dikenal)
+func ExtensionFromMime(mimeType string) string {
+	return extensionByMime[normalizeMime(mimeType)]
+}
+
+// normalizeMime lowercase & buang parameter (charset, dll.), kecuali codecs Ogg/Opus
+func normalizeMime(contentType string) string {
+	if contentType == "" {
+		return ""
+	}
+	m, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	if m == "audio/ogg" && strings.EqualFold(params["codecs"], "opus") {
+		return VoiceNoteMimeType
+	}
+	switch m {
+	case "image/jpg", "image/pjpeg":
+		return "image/jpeg"
+	case "audio/mp3":
+		return "audio/mpeg"
+	case "audio/x-wav", "audio/wave":
+		return "audio/wav"
+	case "audio/x-m4a":
+		return "audio/mp4"
+	}
+	return m
+}
+
+// sniffMime deteksi format dari signature (magic bytes); "" kalau tidak dikenali
+func sniffMime(data []byte, ext string) string {
+	switch {
+	case len(data) == 0:
+		return ""
+
+	// image
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return "image/tiff"
+	case len(data) >= 14 && bytes.HasPrefix(data, []byte("BM")) && bytes.Equal(data[6:10], []byte{0, 0, 0, 0}):
+		return "image/bmp"
+
+	// container RIFF: WebP, WAV, AVI
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")):
+		switch string(data[8:12]) {
+		case "WEBP":
+			return "image/webp"
+		case "WAVE":
+			return "audio/wav"
+		case "AVI ":
+			return "video/x-msvideo"
+		}
+		return ""
+
+	// container ISO BMFF (MP4, M4A, MOV, 3GP, HEIC)
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		return sniffISOBMFF(string(data[8:12]), ext)
+
+	// audio
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return sniffOgg(data)
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return "audio/mpeg"
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(data, []byte("#!AMR")):
+		return "audio/amr"
+	case len(data) >= 2 && data[0] == 0xff && data[1]&0xf6 == 0xf0: // ADTS (layer 00)
+		return "audio/aac"
+	case len(data) >= 2 && data[0] == 0xff && data[1]&0xe0 == 0xe0 && data[1]&0x06 != 0: // MPEG audio frame sync
+		return "audio/mpeg"
+
+	// video Matroska / WebM (EBML)
+	case bytes.HasPrefix(data, []byte("\x1a\x45\xdf\xa3")):
+		if bytes.Contains(data[:min(len(data), 64)], []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+
+	// document & archive
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return "application/pdf"
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return sniffZip(data)
+	case bytes.HasPrefix(data, []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1")):
+		return sniffOLE(data, ext)
+	case bytes.HasPrefix(data, []byte(`{\rtf`)):
+		return "application/rtf"
+	case bytes.HasPrefix(data, []byte("Rar!\x1a\x07")):
+		return "application/vnd.rar"
+	case bytes.HasPrefix(data, []byte("7z\xbc\xaf\x27\x1c")):
+		return "application/x-7z-compressed"
+	case bytes.HasPrefix(data, []byte("\x1f\x8b")):
+		return "application/gzip"
+	}
+	return ""
+}
+
+// sniffISOBMFF bedakan MP4 / M4A / MOV / 3GP / HEIC dari major brand box ftyp
+func sniffISOBMFF(brand, ext string) string {
+	switch {
+	case brand == "M4A " || brand == "M4B " || brand == "M4P ":
+		return "audio/mp4"
+	case brand == "qt  ":
+		return "video/quicktime"
+	case strings.HasPrefix(brand, "3gp"), strings.HasPrefix(brand, "3g2"):
+		return "video/3gpp"
+	case brand == "avif" || brand == "avis":
+		return "image/avif"
+	case brand == "heic" || brand == "heix" || brand == "heim" || brand == "heis" || brand == "mif1" || brand == "msf1":
+		return "image/heic"
+	}
+	// Beberapa encoder menulis M4A dengan brand generik (isom / mp42)
+	if ext == ".m4a" {
+		return "audio/mp4"
+	}
+	return "video/mp4"
+}
+
+// sniffOgg cek codec dari paket pertama stream Ogg
+func sniffOgg(data []byte) string {
+	if len(data) > 27 && len(data) >= 27+int(data[26]) {
+		packet := data[27+int(data[26]):]
+		switch {
+		case bytes.HasPrefix(packet, []byte("OpusHead")):
+			return VoiceNoteMimeType
+		case bytes.HasPrefix(packet, []byte("\x80theora")):
+			return "video/ogg"
+		}
+	}
+	return "audio/ogg"
+}
+
+// sniffZip bedakan Office Open XML, OpenDocument, APK dan ZIP biasa dari isi arsip
+func sniffZip(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "application/zip"
+	}
+
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "mimetype": // OpenDocument menyimpan MIME type di entry pertama
+			rc, err := f.Open()
+			if err != nil {
+				continue
+			}
+			b, _ := io.ReadAll(io.LimitReader(rc, 128))
+			rc.Close()
+			if m := strings.TrimSpace(string(b)); strings.HasPrefix(m, "application/vnd.oasis.opendocument.") {
+				return m
+			}
+		case strings.HasPrefix(f.Name, "word/"):
+			return mimeByExtension[".docx"]
+		case strings.HasPrefix(f.Name, "xl/"):
+			return mimeByExtension[".xlsx"]
+		case strings.HasPrefix(f.Name, "ppt/"):
+			return mimeByExtension[".pptx"]
+		case f.Name == "AndroidManifest.xml":
+			return mimeByExtension[".apk"]
+		}
+	}
+	return "application/zip"
+}
+
+// sniffOLE bedakan dokumen Office lama (.doc / .xls / .ppt) dari nama stream (UTF-16) di compound file
+func sniffOLE(data []byte, ext string) string {
+	streams := []struct {
+		name string
+		mime string
+	}{
+		{"WordDocument", mimeByExtension[".doc"]},
+		{"Workbook", mimeByExtension[".xls"]},
+		{"Book", mimeByExtension[".xls"]},
+		{"PowerPoint Document", mimeByExtension[".ppt"]},
+	}
+	for _, s := range streams {
+		if bytes.Contains(data, utf16LE(s.name)) {
+			return s.mime
+		}
+	}
+	if m, ok := mimeByExtension[ext]; ok {
+		return m
+	}
+	return "application/x-ole-storage"
+}
+
+func utf16LE(s string) []byte {
+	codes := utf16.Encode([]rune(s))
+	b := make([]byte, 0, len(codes)*2)
+	for _, c := range codes {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return b
+}
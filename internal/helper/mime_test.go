@@ -0,0 +1,166 @@
+package helper
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+// zipFixture arsip zip kecil berisi entry (nama → isi) sesuai urutan
+func zipFixture(t *testing.T, entries ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		f, err := w.Create(e[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(e[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// oleFixture header compound file + nama stream UTF-16LE
+func oleFixture(stream string) []byte {
+	data := []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1")
+	data = append(data, make([]byte, 56)...)
+	return append(data, utf16LE(stream)...)
+}
+
+func ftypFixture(brand string) []byte {
+	return []byte("\x00\x00\x00\x18ftyp" + brand + "\x00\x00\x02\x00isommp41")
+}
+
+// oggFixture page Ogg pertama dengan satu paket header
+func oggFixture(packet string) []byte {
+	page := []byte("OggS\x00\x02")
+	page = append(page, make([]byte, 20)...)
+	page = append(page, 1, byte(len(packet)))
+	return append(page, packet...)
+}
+
+func TestSniffMime(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		ext  string
+		want string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "", "image/png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), "", "image/jpeg"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "", "image/gif"},
+		{"webp", []byte("RIFF\x24\x00\x00\x00WEBPVP8L"), "", "image/webp"},
+		{"wav", []byte("RIFF\x24\x00\x00\x00WAVEfmt "), "", "audio/wav"},
+		{"riff unknown", []byte("RIFF\x24\x00\x00\x00XXXX"), "", ""},
+		{"m4a brand", ftypFixture("M4A "), "", "audio/mp4"},
+		{"mp4 brand", ftypFixture("isom"), "", "video/mp4"},
+		{"generic brand with m4a extension", ftypFixture("mp42"), ".m4a", "audio/mp4"},
+		{"quicktime", ftypFixture("qt  "), "", "video/quicktime"},
+		{"3gp", ftypFixture("3gp4"), "", "video/3gpp"},
+		{"heic", ftypFixture("heic"), "", "image/heic"},
+		{"ogg opus", oggFixture("OpusHead\x01\x01\x38\x01"), "", VoiceNoteMimeType},
+		{"ogg vorbis", oggFixture("\x01vorbis\x00\x00\x00\x00"), "", "audio/ogg"},
+		{"ogg truncated segment table", []byte("OggS\x00\x02" + string(make([]byte, 20)) + "\xff"), "", "audio/ogg"},
+		{"mp3 id3", []byte("ID3\x03\x00\x00\x00"), "", "audio/mpeg"},
+		{"mp3 frame", []byte("\xff\xfb\x90\x00"), "", "audio/mpeg"},
+		{"aac adts", []byte("\xff\xf1\x50\x80"), "", "audio/aac"},
+		{"webm", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm"), "", "video/webm"},
+		{"matroska", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x88matroska"), "", "video/x-matroska"},
+		{"pdf", []byte("%PDF-1.7\n"), "", "application/pdf"},
+		{"docx", zipFixture(t, [2]string{"[Content_Types].xml", "<Types/>"}, [2]string{"word/document.xml", "<w/>"}), "", mimeByExtension[".docx"]},
+		{"xlsx", zipFixture(t, [2]string{"[Content_Types].xml", "<Types/>"}, [2]string{"xl/workbook.xml", "<x/>"}), "", mimeByExtension[".xlsx"]},
+		{"pptx", zipFixture(t, [2]string{"ppt/presentation.xml", "<p/>"}), "", mimeByExtension[".pptx"]},
+		{"odt", zipFixture(t, [2]string{"mimetype", "application/vnd.oasis.opendocument.text"}, [2]string{"content.xml", "<o/>"}), "", "application/vnd.oasis.opendocument.text"},
+		{"plain zip", zipFixture(t, [2]string{"readme.txt", "hi"}), "", "application/zip"},
+		{"corrupt zip", []byte("PK\x03\x04broken"), "", "application/zip"},
+		{"ole doc", oleFixture("WordDocument"), "", "application/msword"},
+		{"ole xls", oleFixture("Workbook"), "", "application/vnd.ms-excel"},
+		{"ole unknown with extension", oleFixture("Other"), ".ppt", "application/vnd.ms-powerpoint"},
+		{"ole unknown", oleFixture("Other"), "", "application/x-ole-storage"},
+		{"text", []byte("hello world"), "", ""},
+		{"empty", nil, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffMime(tt.data, tt.ext); got != tt.want {
+				t.Errorf("sniffMime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeMime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"text/plain; charset=utf-8", "text/plain"},
+		{"IMAGE/JPG", "image/jpeg"},
+		{"audio/mp3", "audio/mpeg"},
+		{"audio/x-m4a", "audio/mp4"},
+		{"audio/ogg; codecs=opus", VoiceNoteMimeType},
+		{"audio/ogg", "audio/ogg"},
+		{"not a mime;;", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeMime(tt.in); got != tt.want {
+			t.Errorf("normalizeMime(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDetectMimeFallbackOrder(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	unknown := []byte{0x01, 0x02, 0x03, 0x04}
+	csv := []byte("a,b\n1,2\n")
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		filename    string
+		want        string
+	}{
+		{"magic bytes beat header and extension", png, "application/pdf", "file.pdf", "image/png"},
+		{"header beats extension", unknown, "application/pdf", "file.jpg", "application/pdf"},
+		{"generic header ignored", unknown, "application/octet-stream", "file.docx", mimeByExtension[".docx"]},
+		{"extension beats text sniffing", csv, "", "export.csv", "text/csv"},
+		{"text sniffing", csv, "", "export", "text/plain"},
+		{"nothing matches", unknown, "", "file.bin", defaultMimeType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectMime(tt.data, tt.contentType, tt.filename); got != tt.want {
+				t.Errorf("DetectMime() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaTypeFromMime(t *testing.T) {
+	tests := map[string]string{
+		"image/png":              "image",
+		"image/heic":             "document",
+		"video/mp4":              "video",
+		"video/quicktime":        "document",
+		VoiceNoteMimeType:        "audio",
+		"audio/wav":              "document",
+		mimeByExtension[".xlsx"]: "document",
+	}
+
+	for mimeType, want := range tests {
+		if got := MediaTypeFromMime(mimeType); got != want {
+			t.Errorf("MediaTypeFromMime(%q) = %q, want %q", mimeType, got, want)
+		}
+	}
+}
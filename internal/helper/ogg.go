@@ -0,0 +1,214 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// Mimetype voice note (PTT) yang dikenali WhatsApp
+const VoiceNoteMimeType = "audio/ogg; codecs=opus"
+
+// Jumlah sampel waveform voice note (nilai 0-100 per sampel)
+const waveformSamples = 64
+
+// Opus selalu memakai clock 48kHz untuk granule position
+const opusSampleRate = 48000
+
+var ErrNotOggOpus = errors.New("voice note must be Ogg/Opus audio")
+
+// OpusInfo metadata voice note hasil parsing container Ogg
+type OpusInfo struct {
+	Seconds  uint32
+	Waveform []byte
+}
+
+// opusPacket ukuran & durasi (sampel 48kHz) satu paket audio Opus
+type opusPacket struct {
+	size    int
+	samples int
+}
+
+// ParseOggOpus validasi file Ogg/Opus lalu hitung durasi (granule position terakhir - pre-skip)
+// dan waveform. Tanpa decoder Opus, waveform diestimasi dari bitrate per paket: Opus VBR
+// memakai lebih banyak byte untuk bagian yang ramai dan hampir nol untuk hening.
+func ParseOggOpus(data []byte) (*OpusInfo, error) {
+	var (
+		serial    uint32
+		granule   int64
+		packet    []byte
+		packetNum int
+		preSkip   int64
+		packets   []opusPacket
+	)
+
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < 27 || !bytes.Equal(data[offset:offset+4], []byte("OggS")) {
+			if offset == 0 {
+				return nil, fmt.Errorf("%w: not an Ogg container", ErrNotOggOpus)
+			}
+			return nil, fmt.Errorf("%w: corrupt Ogg page at byte %d", ErrNotOggOpus, offset)
+		}
+
+		header := data[offset : offset+27]
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		segments := int(header[26])
+		if len(data)-offset < 27+segments {
+			return nil, fmt.Errorf("%w: truncated Ogg page", ErrNotOggOpus)
+		}
+		lacing := data[offset+27 : offset+27+segments]
+
+		bodySize := 0
+		for _, l := range lacing {
+			bodySize += int(l)
+		}
+		bodyStart := offset + 27 + segments
+		if len(data)-bodyStart < bodySize {
+			return nil, fmt.Errorf("%w: truncated Ogg page", ErrNotOggOpus)
+		}
+		offset = bodyStart + bodySize
+
+		// Hanya logical stream pertama yang dipakai
+		if packetNum == 0 && packet == nil {
+			serial = pageSerial
+		} else if pageSerial != serial {
+			continue
+		}
+		if g := int64(binary.LittleEndian.Uint64(header[6:14])); g > 0 {
+			granule = g
+		}
+
+		pos := bodyStart
+		for _, l := range lacing {
+			packet = append(packet, data[pos:pos+int(l)]...)
+			pos += int(l)
+			if l == 255 {
+				continue // paket berlanjut ke segment / page berikutnya
+			}
+
+			switch packetNum {
+			case 0:
+				if len(packet) < 19 || !bytes.HasPrefix(packet, []byte("OpusHead")) {
+					return nil, fmt.Errorf("%w: Ogg stream is not Opus", ErrNotOggOpus)
+				}
+				preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+			case 1:
+				if !bytes.HasPrefix(packet, []byte("OpusTags")) {
+					return nil, fmt.Errorf("%w: missing OpusTags header", ErrNotOggOpus)
+				}
+			default:
+				if len(packet) > 0 {
+					packets = append(packets, opusPacket{size: len(packet), samples: opusPacketSamples(packet)})
+				}
+			}
+			packetNum++
+			packet = nil
+		}
+	}
+
+	if packetNum < 2 {
+		return nil, fmt.Errorf("%w: missing Opus headers", ErrNotOggOpus)
+	}
+	if len(packets) == 0 || granule <= preSkip {
+		return nil, fmt.Errorf("%w: no audio data", ErrNotOggOpus)
+	}
+
+	seconds := math.Round(float64(granule-preSkip) / opusSampleRate)
+	return &OpusInfo{
+		Seconds:  uint32(max(1, seconds)),
+		Waveform: opusWaveform(packets),
+	}, nil
+}
+
+// opusPacketSamples durasi paket (sampel 48kHz) dari TOC byte, RFC 6716 section 3.1
+func opusPacketSamples(packet []byte) int {
+	toc := packet[0]
+	config := int(toc >> 3)
+
+	var frame int // durasi satu frame dalam sampel 48kHz
+	switch {
+	case config < 12: // SILK: 10, 20, 40, 60 ms
+		frame = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16: // Hybrid: 10, 20 ms
+		frame = []int{480, 960}[config%2]
+	default: // CELT: 2.5, 5, 10, 20 ms
+		frame = []int{120, 240, 480, 960}[config%4]
+	}
+
+	frames := 1
+	switch toc & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) > 1 {
+			frames = int(packet[1] & 0x3F)
+		}
+	}
+	return frame * frames
+}
+
+// opusWaveform bagi audio jadi 64 bucket waktu, nilai = bitrate rata-rata bucket (skala 0-100)
+func opusWaveform(packets []opusPacket) []byte {
+	total := 0
+	for _, p := range packets {
+		total += p.samples
+	}
+
+	var sizes, samples [waveformSamples]float64
+	elapsed := 0
+	for _, p := range packets {
+		bucket := 0
+		if total > 0 {
+			bucket = min(waveformSamples-1, elapsed*waveformSamples/total)
+		}
+		sizes[bucket] += float64(p.size)
+		samples[bucket] += float64(max(1, p.samples))
+		elapsed += p.samples
+	}
+
+	var rates [waveformSamples]float64
+	peak := 0.0
+	for i := range rates {
+		if samples[i] > 0 {
+			rates[i] = sizes[i] / samples[i]
+		} else if i > 0 {
+			rates[i] = rates[i-1] // bucket kosong (audio sangat pendek) ikut bucket sebelumnya
+		}
+		peak = max(peak, rates[i])
+	}
+
+	waveform := make([]byte, waveformSamples)
+	if peak == 0 {
+		return waveform
+	}
+	for i, r := range rates {
+		waveform[i] = byte(math.Round(r / peak * 100))
+	}
+	return waveform
+}
+
+// CreateVoiceNoteMessage buat AudioMessage PTT (voice note) dari hasil upload Ogg/Opus
+func CreateVoiceNoteMessage(uploaded whatsmeow.UploadResponse, info *OpusInfo) *waE2E.Message {
+	mimeType := VoiceNoteMimeType
+	ptt := true
+
+	return &waE2E.Message{
+		AudioMessage: &waE2E.AudioMessage{
+			URL:           &uploaded.URL,
+			DirectPath:    &uploaded.DirectPath,
+			MediaKey:      uploaded.MediaKey,
+			Mimetype:      &mimeType,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    &uploaded.FileLength,
+			Seconds:       &info.Seconds,
+			PTT:           &ptt,
+			Waveform:      info.Waveform,
+		},
+	}
+}
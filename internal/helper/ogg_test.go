@@ -0,0 +1,159 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// oggPage satu page Ogg (tanpa CRC, tidak dicek parser) berisi paket-paket utuh
+func oggPage(serial uint32, granule int64, packets ...[]byte) []byte {
+	var lacing, body []byte
+	for _, p := range packets {
+		n := len(p)
+		for ; n >= 255; n -= 255 {
+			lacing = append(lacing, 255)
+		}
+		lacing = append(lacing, byte(n))
+		body = append(body, p...)
+	}
+
+	header := make([]byte, 27)
+	copy(header, "OggS")
+	binary.LittleEndian.PutUint64(header[6:14], uint64(granule))
+	binary.LittleEndian.PutUint32(header[14:18], serial)
+	header[26] = byte(len(lacing))
+	return append(append(header, lacing...), body...)
+}
+
+func opusHead(preSkip uint16) []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = 1 // channels
+	binary.LittleEndian.PutUint16(head[10:12], preSkip)
+	return head
+}
+
+// opusFrame paket CELT 20ms (config 31, code 0) sebesar size byte
+func opusFrame(size int) []byte {
+	p := make([]byte, size)
+	p[0] = 31 << 3
+	return p
+}
+
+// opusStream 3 detik audio: 1 detik ramai, 1 detik hening, 1 detik ramai
+func opusStream(preSkip uint16) []byte {
+	var buf bytes.Buffer
+	buf.Write(oggPage(1, 0, opusHead(preSkip)))
+	buf.Write(oggPage(1, 0, []byte("OpusTags\x00\x00\x00\x00\x00\x00\x00\x00")))
+
+	frames := 0
+	for _, size := range []int{300, 3, 300} { // paket 300 byte melewati batas lacing 255
+		var packets [][]byte
+		for i := 0; i < 50; i++ {
+			packets = append(packets, opusFrame(size))
+			frames++
+		}
+		buf.Write(oggPage(1, int64(preSkip)+int64(frames*960), packets...))
+	}
+	return buf.Bytes()
+}
+
+func TestParseOggOpus(t *testing.T) {
+	info, err := ParseOggOpus(opusStream(312))
+	if err != nil {
+		t.Fatalf("ParseOggOpus() error = %v", err)
+	}
+	// (preSkip + 150*960 - preSkip) / 48000 = 3 detik
+	if info.Seconds != 3 {
+		t.Errorf("Seconds = %d, want 3", info.Seconds)
+	}
+	if len(info.Waveform) != waveformSamples {
+		t.Fatalf("len(Waveform) = %d, want %d", len(info.Waveform), waveformSamples)
+	}
+	if info.Waveform[0] != 100 || info.Waveform[waveformSamples-1] != 100 {
+		t.Errorf("loud edges = %d, %d, want 100", info.Waveform[0], info.Waveform[waveformSamples-1])
+	}
+	if mid := info.Waveform[waveformSamples/2]; mid > 5 {
+		t.Errorf("silent middle = %d, want <= 5", mid)
+	}
+	for i, v := range info.Waveform {
+		if v > 100 {
+			t.Errorf("Waveform[%d] = %d, want <= 100", i, v)
+		}
+	}
+}
+
+func TestParseOggOpusInvalid(t *testing.T) {
+	valid := opusStream(312)
+
+	headerOnly := oggPage(1, 0, opusHead(312))
+	segmentOverflow := append([]byte(nil), headerOnly[:27]...)
+	segmentOverflow[26] = 200 // segment table 200 byte, buffer jauh lebih pendek
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not ogg", []byte("ID3\x03\x00\x00\x00\x00\x00\x00mp3 data")},
+		{"vorbis stream", oggPage(1, 0, []byte("\x01vorbis\x00\x00\x00\x00\x01\x44\xac\x00\x00"))},
+		{"short opus head", oggPage(1, 0, []byte("OpusHead\x01"))},
+		{"missing opus tags", append(oggPage(1, 0, opusHead(312)), oggPage(1, 0, []byte("Comments"))...)},
+		{"headers only", append(oggPage(1, 0, opusHead(312)), oggPage(1, 0, []byte("OpusTags"))...)},
+		{"truncated page body", valid[:len(valid)-10]},
+		{"truncated page header", append(append([]byte(nil), headerOnly...), "OggS\x00"...)},
+		{"segment table past buffer", segmentOverflow},
+		{"garbage after page", append(append([]byte(nil), valid...), "junk data here that is not a page"...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ParseOggOpus(tt.data)
+			if err == nil {
+				t.Fatalf("ParseOggOpus() = %+v, want error", info)
+			}
+			if !errors.Is(err, ErrNotOggOpus) {
+				t.Errorf("error = %v, want ErrNotOggOpus", err)
+			}
+		})
+	}
+}
+
+// Potongan stream di sembarang offset harus jadi error / hasil valid, tidak boleh panic
+func TestParseOggOpusTruncatedNoPanic(t *testing.T) {
+	valid := opusStream(312)
+	for n := 0; n < len(valid); n++ {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("ParseOggOpus(valid[:%d]) panic: %v", n, r)
+				}
+			}()
+			_, _ = ParseOggOpus(valid[:n])
+		}()
+	}
+}
+
+func TestOpusPacketSamples(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   int
+	}{
+		{"silk 20ms", []byte{1 << 3}, 960},
+		{"silk 60ms", []byte{3 << 3}, 2880},
+		{"hybrid 10ms", []byte{12 << 3}, 480},
+		{"celt 2.5ms", []byte{16 << 3}, 120},
+		{"celt 20ms two frames", []byte{31<<3 | 1}, 1920},
+		{"celt 20ms code 3 with 3 frames", []byte{31<<3 | 3, 3}, 2880},
+	}
+
+	for _, tt := range tests {
+		if got := opusPacketSamples(tt.packet); got != tt.want {
+			t.Errorf("%s: opusPacketSamples() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
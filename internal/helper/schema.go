@@ -42,6 +42,316 @@ func InitCustomSchema() {
 		CREATE INDEX IF NOT EXISTS idx_instances_instance_id ON instances(instance_id);
 		CREATE INDEX IF NOT EXISTS idx_instances_phone_number ON instances(phone_number);
 		CREATE INDEX IF NOT EXISTS idx_instances_status ON instances(status);
+
+		CREATE TABLE IF NOT EXISTS messages (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255)  NOT NULL,
+			message_id        VARCHAR(255)  NOT NULL,
+
+			chat_jid          VARCHAR(255)  NOT NULL,
+			chat_type         VARCHAR(20)   NOT NULL DEFAULT 'personal',
+			sender_jid        VARCHAR(255),
+			sender_name       VARCHAR(255),
+			direction         VARCHAR(10)   NOT NULL,
+
+			message_type      VARCHAR(30)   NOT NULL,
+			body              TEXT,
+			media_mimetype    VARCHAR(255),
+			media_file_name   TEXT,
+			media_size        BIGINT,
+			raw_message       BYTEA,
+
+			timestamp         TIMESTAMP(6) WITH TIME ZONE NOT NULL,
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			UNIQUE (instance_id, message_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_messages_instance_timestamp ON messages(instance_id, timestamp DESC);
+		CREATE INDEX IF NOT EXISTS idx_messages_chat_jid ON messages(instance_id, chat_jid);
+		CREATE INDEX IF NOT EXISTS idx_messages_sender_jid ON messages(instance_id, sender_jid);
+
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id                SERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255)  NOT NULL,
+			url               TEXT          NOT NULL,
+			secret            VARCHAR(255)  NOT NULL,
+			events            TEXT[]        NOT NULL DEFAULT '{}',
+			is_active         BOOLEAN       NOT NULL DEFAULT TRUE,
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_webhooks_instance_id ON webhooks(instance_id);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id                BIGSERIAL PRIMARY KEY,
+			webhook_id        INT           NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+			instance_id       VARCHAR(255)  NOT NULL,
+			event             VARCHAR(50)   NOT NULL,
+			payload           TEXT          NOT NULL,
+
+			status            VARCHAR(20)   NOT NULL DEFAULT 'pending',
+			attempts          INT           NOT NULL DEFAULT 0,
+			next_attempt_at   TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			last_status_code  INT,
+			last_error        TEXT,
+			replay_of         BIGINT,
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			delivered_at      TIMESTAMP(6) WITH TIME ZONE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+		CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at DESC);
+
+		CREATE TABLE IF NOT EXISTS users (
+			id                SERIAL PRIMARY KEY,
+			username          VARCHAR(100)  NOT NULL UNIQUE,
+			password_hash     VARCHAR(255)  NOT NULL,
+			role              VARCHAR(20)   NOT NULL DEFAULT 'user',
+			is_active         BOOLEAN       NOT NULL DEFAULT TRUE,
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			last_login_at     TIMESTAMP(6) WITH TIME ZONE
+		);
+
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id                BIGSERIAL PRIMARY KEY,
+			user_id           INT           NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash        VARCHAR(64)   NOT NULL UNIQUE,
+			expires_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL,
+			revoked_at        TIMESTAMP(6) WITH TIME ZONE,
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id                SERIAL PRIMARY KEY,
+			user_id           INT           NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name              VARCHAR(100)  NOT NULL,
+			key_prefix        VARCHAR(16)   NOT NULL,
+			key_hash          VARCHAR(64)   NOT NULL UNIQUE,
+
+			instance_ids      TEXT[]        NOT NULL DEFAULT '{}',
+			phone_numbers     TEXT[]        NOT NULL DEFAULT '{}',
+			permissions       TEXT[]        NOT NULL DEFAULT '{}',
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			expires_at        TIMESTAMP(6) WITH TIME ZONE,
+			last_used_at      TIMESTAMP(6) WITH TIME ZONE,
+			revoked_at        TIMESTAMP(6) WITH TIME ZONE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+
+		ALTER TABLE instances ADD COLUMN IF NOT EXISTS owner_id INT REFERENCES users(id) ON DELETE SET NULL;
+		CREATE INDEX IF NOT EXISTS idx_instances_owner_id ON instances(owner_id);
+
+		ALTER TABLE instances ADD COLUMN IF NOT EXISTS pair_code VARCHAR(20);
+		ALTER TABLE instances ADD COLUMN IF NOT EXISTS pair_code_expires_at TIMESTAMP(6) WITH TIME ZONE;
+
+		ALTER TABLE instances ADD COLUMN IF NOT EXISTS reconnect_attempts INT NOT NULL DEFAULT 0;
+		ALTER TABLE instances ADD COLUMN IF NOT EXISTS last_error TEXT;
+		ALTER TABLE instances ADD COLUMN IF NOT EXISTS next_reconnect_at TIMESTAMP(6) WITH TIME ZONE;
+
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS status VARCHAR(20);
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS status_updated_at TIMESTAMP(6) WITH TIME ZONE;
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP(6) WITH TIME ZONE;
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMP(6) WITH TIME ZONE;
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS played_at TIMESTAMP(6) WITH TIME ZONE;
+
+		CREATE TABLE IF NOT EXISTS message_receipts (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255)  NOT NULL,
+			message_id        VARCHAR(255)  NOT NULL,
+			recipient_jid     VARCHAR(255)  NOT NULL,
+			status            VARCHAR(20)   NOT NULL,
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			UNIQUE (instance_id, message_id, recipient_jid)
+		);
+
+		CREATE TABLE IF NOT EXISTS outbox_jobs (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255)  NOT NULL,
+			recipient_jid     VARCHAR(255)  NOT NULL,
+			message_type      VARCHAR(30)   NOT NULL,
+			payload           BYTEA         NOT NULL,
+			message_id        VARCHAR(255),
+
+			status            VARCHAR(20)   NOT NULL DEFAULT 'pending',
+			attempts          INT           NOT NULL DEFAULT 0,
+			max_attempts      INT           NOT NULL DEFAULT 5,
+			next_attempt_at   TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			last_error        TEXT,
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			sent_at           TIMESTAMP(6) WITH TIME ZONE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_outbox_jobs_due ON outbox_jobs(status, next_attempt_at);
+		CREATE INDEX IF NOT EXISTS idx_outbox_jobs_instance_id ON outbox_jobs(instance_id, id);
+
+		CREATE TABLE IF NOT EXISTS scheduled_messages (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255)  NOT NULL,
+			recipient_jid     VARCHAR(255)  NOT NULL,
+			message_type      VARCHAR(30)   NOT NULL,
+			payload           BYTEA         NOT NULL,
+			message_id        VARCHAR(255),
+
+			send_at           TIMESTAMP(6) WITH TIME ZONE NOT NULL,
+			status            VARCHAR(20)   NOT NULL DEFAULT 'scheduled',
+			outbox_job_id     BIGINT,
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			dispatched_at     TIMESTAMP(6) WITH TIME ZONE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages(status, send_at);
+		CREATE INDEX IF NOT EXISTS idx_scheduled_messages_instance_id ON scheduled_messages(instance_id, send_at);
+
+		ALTER TABLE outbox_jobs ADD COLUMN IF NOT EXISTS scheduled_message_id BIGINT;
+
+		CREATE TABLE IF NOT EXISTS campaigns (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255)  NOT NULL,
+			name              VARCHAR(255)  NOT NULL,
+			message_type      VARCHAR(30)   NOT NULL,
+			payload           BYTEA         NOT NULL,
+
+			status            VARCHAR(20)   NOT NULL DEFAULT 'running',
+			delay_ms          INT           NOT NULL DEFAULT 3000,
+			jitter_ms         INT           NOT NULL DEFAULT 2000,
+			last_error        TEXT,
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			started_at        TIMESTAMP(6) WITH TIME ZONE,
+			completed_at      TIMESTAMP(6) WITH TIME ZONE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_campaigns_instance_id ON campaigns(instance_id, id DESC);
+		CREATE INDEX IF NOT EXISTS idx_campaigns_status ON campaigns(status);
+
+		CREATE TABLE IF NOT EXISTS campaign_recipients (
+			id                BIGSERIAL PRIMARY KEY,
+			campaign_id       BIGINT        NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+			position          INT           NOT NULL,
+			phone             VARCHAR(50)   NOT NULL,
+			jid               VARCHAR(255),
+
+			status            VARCHAR(20)   NOT NULL DEFAULT 'pending',
+			message_id        VARCHAR(255),
+			error             TEXT,
+
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			sent_at           TIMESTAMP(6) WITH TIME ZONE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_campaign_recipients_campaign ON campaign_recipients(campaign_id, status, position);
+
+		CREATE TABLE IF NOT EXISTS templates (
+			id                BIGSERIAL PRIMARY KEY,
+			owner_id          INT           REFERENCES users(id) ON DELETE CASCADE,
+			name              VARCHAR(255)  NOT NULL,
+			body              TEXT          NOT NULL,
+			variables         TEXT[]        NOT NULL DEFAULT '{}',
+			media_url         TEXT,
+			media_type        VARCHAR(20),
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			UNIQUE (owner_id, name)
+		);
+
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS template_body TEXT;
+		ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS variables JSONB;
+		ALTER TABLE campaign_recipients ADD COLUMN IF NOT EXISTS variables JSONB;
+
+		ALTER TABLE instances ADD COLUMN IF NOT EXISTS first_connected_at TIMESTAMP(6) WITH TIME ZONE;
+		UPDATE instances SET first_connected_at = created_at
+		WHERE first_connected_at IS NULL AND connected_at IS NOT NULL;
+
+		CREATE TABLE IF NOT EXISTS sender_pools (
+			id                BIGSERIAL PRIMARY KEY,
+			owner_id          INT           REFERENCES users(id) ON DELETE CASCADE,
+			name              VARCHAR(100)  NOT NULL,
+			strategy          VARCHAR(20)   NOT NULL DEFAULT 'round_robin',
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			UNIQUE (owner_id, name)
+		);
+
+		CREATE TABLE IF NOT EXISTS sender_pool_members (
+			pool_id           BIGINT        NOT NULL REFERENCES sender_pools(id) ON DELETE CASCADE,
+			instance_id       VARCHAR(255)  NOT NULL REFERENCES instances(instance_id) ON DELETE CASCADE,
+			position          INT           NOT NULL,
+			last_used_at      TIMESTAMP(6) WITH TIME ZONE,
+
+			PRIMARY KEY (pool_id, instance_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS sender_pool_sticky (
+			pool_id           BIGINT        NOT NULL REFERENCES sender_pools(id) ON DELETE CASCADE,
+			recipient         VARCHAR(255)  NOT NULL,
+			instance_id       VARCHAR(255)  NOT NULL,
+			updated_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			PRIMARY KEY (pool_id, recipient)
+		);
+
+		CREATE TABLE IF NOT EXISTS idempotency_keys (
+			user_id           INT           NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			idempotency_key   VARCHAR(255)  NOT NULL,
+			request_hash      VARCHAR(64)   NOT NULL,
+			status            VARCHAR(20)   NOT NULL DEFAULT 'processing',
+			status_code       INT,
+			response_body     BYTEA,
+			message_id        VARCHAR(255),
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+			expires_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL,
+
+			PRIMARY KEY (user_id, idempotency_key)
+		);
+		CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP(6) WITH TIME ZONE;
+		ALTER TABLE messages ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP(6) WITH TIME ZONE;
+
+		CREATE TABLE IF NOT EXISTS polls (
+			id                BIGSERIAL PRIMARY KEY,
+			instance_id       VARCHAR(255)  NOT NULL,
+			message_id        VARCHAR(255)  NOT NULL,
+			chat_jid          VARCHAR(255)  NOT NULL,
+			creator_jid       VARCHAR(255),
+			question          TEXT          NOT NULL,
+			options           TEXT[]        NOT NULL,
+			selectable_count  INT           NOT NULL DEFAULT 0,
+
+			created_at        TIMESTAMP(6) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+			UNIQUE (instance_id, message_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS poll_votes (
+			poll_id           BIGINT        NOT NULL REFERENCES polls(id) ON DELETE CASCADE,
+			voter_jid         VARCHAR(255)  NOT NULL,
+			selected_options  TEXT[]        NOT NULL DEFAULT '{}',
+			voted_at          TIMESTAMP(6) WITH TIME ZONE NOT NULL,
+
+			PRIMARY KEY (poll_id, voter_jid)
+		);
 `
 	if _, err := db.Exec(schema); err != nil {
 		log.Fatalf("failed to init custom schema: %v", err)
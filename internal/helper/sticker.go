@@ -0,0 +1,116 @@
+package helper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decoder JPEG
+	_ "image/png"  // decoder PNG
+
+	"github.com/HugoSmits86/nativewebp"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // decoder WebP
+)
+
+const (
+	// Ukuran sticker yang diwajibkan WhatsApp (persegi, WebP)
+	StickerSize = 512
+	// Batas ukuran file sticker statis WhatsApp
+	StickerMaxFileSize = 100 * 1024
+	// Batas dimensi input sebelum decode: file kecil (mis. PNG 16MB) bisa mengklaim puluhan ribu
+	// pixel per sisi dan decode-nya butuh memori bergiga-giga
+	StickerMaxInputSide   = 10000
+	StickerMaxInputPixels = 25_000_000
+)
+
+var (
+	ErrUnsupportedStickerFormat = errors.New("unsupported sticker format (use PNG, JPEG or static WebP)")
+	ErrStickerTooLarge          = errors.New("sticker image too large")
+)
+
+// ConvertToSticker ubah PNG/JPEG/WebP jadi WebP 512x512: gambar di-resize proporsional
+// lalu diletakkan di tengah kanvas transparan.
+func ConvertToSticker(data []byte) ([]byte, error) {
+	// Cek dimensi dari header dulu, baru decode penuh
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedStickerFormat, err)
+	}
+	if format != "png" && format != "jpeg" && format != "webp" {
+		return nil, fmt.Errorf("%w: got %s", ErrUnsupportedStickerFormat, format)
+	}
+	if cfg.Width > StickerMaxInputSide || cfg.Height > StickerMaxInputSide || cfg.Width*cfg.Height > StickerMaxInputPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels (max %d per side, %d total)",
+			ErrStickerTooLarge, cfg.Width, cfg.Height, StickerMaxInputSide, StickerMaxInputPixels)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		// WebP animasi tidak didukung decoder x/image/webp
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedStickerFormat, err)
+	}
+
+	bounds := src.Bounds()
+	if bounds.Dx() < 1 || bounds.Dy() < 1 {
+		return nil, errors.New("image is empty")
+	}
+
+	// Fit ke 512x512 tanpa mengubah aspect ratio
+	w, h := StickerSize, StickerSize
+	if bounds.Dx() > bounds.Dy() {
+		h = max(1, bounds.Dy()*StickerSize/bounds.Dx())
+	} else if bounds.Dy() > bounds.Dx() {
+		w = max(1, bounds.Dx()*StickerSize/bounds.Dy())
+	}
+	offsetX, offsetY := (StickerSize-w)/2, (StickerSize-h)/2
+
+	canvas := image.NewNRGBA(image.Rect(0, 0, StickerSize, StickerSize))
+	draw.CatmullRom.Scale(canvas, image.Rect(offsetX, offsetY, offsetX+w, offsetY+h), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := encodeWebP(&buf, canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode webp: %w", err)
+	}
+	// WebP lossless dari foto bisa melewati batas WhatsApp, sticker seperti itu tidak akan tampil
+	if buf.Len() > StickerMaxFileSize {
+		return nil, fmt.Errorf("%w: converted sticker is %d bytes (max %d), use a simpler image (flat colors / transparent background)",
+			ErrStickerTooLarge, buf.Len(), StickerMaxFileSize)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeWebP nativewebp bisa panic untuk gambar yang sangat ramai (huffman code terlalu panjang),
+// panic diubah jadi error supaya request tidak crash
+func encodeWebP(buf *bytes.Buffer, img image.Image) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: image too complex to encode (%v)", ErrStickerTooLarge, r)
+		}
+	}()
+	return nativewebp.Encode(buf, img, nil)
+}
+
+// CreateStickerMessage buat StickerMessage dari hasil upload WebP 512x512
+func CreateStickerMessage(uploaded whatsmeow.UploadResponse) *waE2E.Message {
+	mimeType := "image/webp"
+	size := uint32(StickerSize)
+	animated := false
+
+	return &waE2E.Message{
+		StickerMessage: &waE2E.StickerMessage{
+			URL:           &uploaded.URL,
+			DirectPath:    &uploaded.DirectPath,
+			MediaKey:      uploaded.MediaKey,
+			Mimetype:      &mimeType,
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    &uploaded.FileLength,
+			Width:         &size,
+			Height:        &size,
+			IsAnimated:    &animated,
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// Placeholder template: {{nama}}, {{ order_id }}, {{user.city}} (titik = lookup ke object nested)
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// MissingVariablesError dikembalikan RenderTemplate kalau ada placeholder tanpa nilai
+type MissingVariablesError struct {
+	Missing []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return "missing template variables: " + strings.Join(e.Missing, ", ")
+}
+
+// TemplateVariables list nama variabel unik di body (urut kemunculan)
+func TemplateVariables(body string) []string {
+	seen := make(map[string]bool)
+	vars := make([]string, 0)
+	for _, m := range templateVarPattern.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			vars = append(vars, m[1])
+		}
+	}
+	return vars
+}
+
+// MissingTemplateVariables variabel di body yang tidak ada (atau kosong) di vars
+func MissingTemplateVariables(body string, vars map[string]interface{}) []string {
+	missing := make([]string, 0)
+	for _, name := range TemplateVariables(body) {
+		if v, ok := lookupTemplateVar(vars, name); !ok || v == nil || formatTemplateValue(v) == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// RenderTemplate ganti semua placeholder dengan nilai dari vars (angka / bool di-format apa adanya)
+func RenderTemplate(body string, vars map[string]interface{}) (string, error) {
+	if missing := MissingTemplateVariables(body, vars); len(missing) > 0 {
+		return "", &MissingVariablesError{Missing: missing}
+	}
+
+	return templateVarPattern.ReplaceAllStringFunc(body, func(placeholder string) string {
+		name := templateVarPattern.FindStringSubmatch(placeholder)[1]
+		v, _ := lookupTemplateVar(vars, name)
+		return formatTemplateValue(v)
+	}), nil
+}
+
+// lookupTemplateVar cari nilai variabel: key persis dulu (mis. kolom CSV "user.city"),
+// lalu per segmen titik ke object nested ({"user": {"city": "Bandung"}})
+func lookupTemplateVar(vars map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := vars[name]; ok {
+		return v, true
+	}
+
+	parts := strings.Split(name, ".")
+	current := vars
+	for i, part := range parts {
+		v, ok := current[part]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return v, true
+		}
+		if current, ok = v.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// formatTemplateValue angka hasil decode JSON (float64) ditulis tanpa notasi eksponen:
+// 1500000 → "1500000", bukan "1.5e+06"
+func formatTemplateValue(v interface{}) string {
+	switch n := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(n), 'f', -1, 32)
+	case json.Number:
+		return n.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// MergeVariables gabungkan beberapa map variabel, map belakang menimpa map depan
+func MergeVariables(maps ...map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// SetMessageText ganti teks pesan: body untuk teks, caption untuk image / video / document
+func SetMessageText(msg *waE2E.Message, text string) {
+	switch {
+	case msg.GetExtendedTextMessage() != nil:
+		msg.ExtendedTextMessage.Text = &text
+	case msg.GetImageMessage() != nil:
+		msg.ImageMessage.Caption = &text
+	case msg.GetVideoMessage() != nil:
+		msg.VideoMessage.Caption = &text
+	case msg.GetDocumentMessage() != nil:
+		msg.DocumentMessage.Caption = &text
+	default:
+		msg.Conversation = &text
+	}
+}
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// decodeVars variabel seperti dari request body (angka JSON → float64)
+func decodeVars(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &vars); err != nil {
+		t.Fatal(err)
+	}
+	return vars
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		vars string
+		want string
+	}{
+		{"string", "Halo {{name}}", `{"name": "Budi"}`, "Halo Budi"},
+		{"spaces in placeholder", "Halo {{ name }}!", `{"name": "Budi"}`, "Halo Budi!"},
+		{"large integer", "Total Rp{{amount}}", `{"amount": 1500000}`, "Total Rp1500000"},
+		{"order id", "Order #{{order_id}}", `{"order_id": 12345678}`, "Order #12345678"},
+		{"decimal", "Berat {{kg}} kg", `{"kg": 2.75}`, "Berat 2.75 kg"},
+		{"bool", "Aktif: {{active}}", `{"active": true}`, "Aktif: true"},
+		{"repeated", "{{a}}-{{a}}", `{"a": "x"}`, "x-x"},
+		{"nested", "Kota {{user.city}}", `{"user": {"city": "Bandung"}}`, "Kota Bandung"},
+		{"dotted key wins", "Kota {{user.city}}", `{"user.city": "Depok", "user": {"city": "Bandung"}}`, "Kota Depok"},
+		{"no placeholder", "Halo semua", `{}`, "Halo semua"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.body, decodeVars(t, tt.vars))
+			if err != nil {
+				t.Fatalf("RenderTemplate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateMissing(t *testing.T) {
+	body := "Halo {{name}}, pesanan {{order_id}} ke {{user.city}} ({{note}})"
+	vars := decodeVars(t, `{"name": "Budi", "user": {"zip": "40111"}, "note": ""}`)
+
+	_, err := RenderTemplate(body, vars)
+	var missingErr *MissingVariablesError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("RenderTemplate() error = %v, want *MissingVariablesError", err)
+	}
+	want := []string{"order_id", "user.city", "note"}
+	if !reflect.DeepEqual(missingErr.Missing, want) {
+		t.Errorf("Missing = %v, want %v", missingErr.Missing, want)
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	got := TemplateVariables("{{b}} {{ a }} {{b}} {{user.city}} {not}")
+	want := []string{"b", "a", "user.city"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TemplateVariables() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeVariables(t *testing.T) {
+	got := MergeVariables(
+		map[string]interface{}{"name": "Global", "promo": "A"},
+		map[string]interface{}{"name": "Budi"},
+	)
+	want := map[string]interface{}{"name": "Budi", "promo": "A"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeVariables() = %v, want %v", got, want)
+	}
+}
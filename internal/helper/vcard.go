@@ -0,0 +1,142 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContactCard data kontak terstruktur untuk dibuat vCard (atau VCard mentah yang sudah jadi)
+type ContactCard struct {
+	FullName     string
+	FirstName    string
+	LastName     string
+	Organization string
+	Title        string
+	Phones       []ContactPhone
+	Emails       []string
+	URL          string
+	Note         string
+	VCard        string // opsional, dipakai apa adanya (field lain diabaikan kecuali FullName)
+}
+
+type ContactPhone struct {
+	Number string
+	Type   string // CELL (default), WORK, HOME, MAIN, ...
+}
+
+// DisplayName nama yang tampil di chat
+func (c ContactCard) DisplayName() string {
+	if name := strings.TrimSpace(c.FullName); name != "" {
+		return name
+	}
+	return strings.TrimSpace(c.FirstName + " " + c.LastName)
+}
+
+// BuildVCard buat vCard 3.0; nomor dinormalisasi dan diberi waid supaya tombol chat WhatsApp muncul
+func BuildVCard(c ContactCard) (string, error) {
+	if c.VCard != "" {
+		if !strings.HasPrefix(strings.TrimSpace(strings.ToUpper(c.VCard)), "BEGIN:VCARD") {
+			return "", fmt.Errorf("vcard must start with BEGIN:VCARD")
+		}
+		return c.VCard, nil
+	}
+
+	name := c.DisplayName()
+	if name == "" {
+		return "", fmt.Errorf("fullName or firstName is required")
+	}
+	if len(c.Phones) == 0 && len(c.Emails) == 0 {
+		return "", fmt.Errorf("at least one phone or email is required")
+	}
+
+	var b strings.Builder
+	b.WriteString("BEGIN:VCARD\nVERSION:3.0\n")
+	fmt.Fprintf(&b, "N:%s;%s;;;\n", vcardEscape(c.LastName), vcardEscape(c.FirstName))
+	fmt.Fprintf(&b, "FN:%s\n", vcardEscape(name))
+	if c.Organization != "" {
+		fmt.Fprintf(&b, "ORG:%s\n", vcardEscape(c.Organization))
+	}
+	if c.Title != "" {
+		fmt.Fprintf(&b, "TITLE:%s\n", vcardEscape(c.Title))
+	}
+
+	for _, phone := range c.Phones {
+		jid, err := FormatPhoneNumber(phone.Number)
+		if err != nil {
+			return "", err
+		}
+		phoneType := strings.ToUpper(strings.TrimSpace(phone.Type))
+		if phoneType == "" {
+			phoneType = "CELL"
+		}
+		fmt.Fprintf(&b, "TEL;type=%s;waid=%s:+%s\n", vcardEscape(phoneType), jid.User, jid.User)
+	}
+	for _, email := range c.Emails {
+		if email = strings.TrimSpace(email); email != "" {
+			fmt.Fprintf(&b, "EMAIL;type=INTERNET:%s\n", vcardEscape(email))
+		}
+	}
+	if c.URL != "" {
+		fmt.Fprintf(&b, "URL:%s\n", vcardEscape(c.URL))
+	}
+	if c.Note != "" {
+		fmt.Fprintf(&b, "NOTE:%s\n", vcardEscape(c.Note))
+	}
+	b.WriteString("END:VCARD")
+	return b.String(), nil
+}
+
+// CreateContactMessage ContactMessage untuk satu kontak, ContactsArrayMessage untuk beberapa kontak
+func CreateContactMessage(cards []ContactCard) (*waE2E.Message, error) {
+	contacts := make([]*waE2E.ContactMessage, 0, len(cards))
+	for i, card := range cards {
+		vcard, err := BuildVCard(card)
+		if err != nil {
+			return nil, fmt.Errorf("contact %d: %v", i+1, err)
+		}
+		name := card.DisplayName()
+		if name == "" {
+			name = vcardField(vcard, "FN")
+		}
+		contacts = append(contacts, &waE2E.ContactMessage{
+			DisplayName: proto.String(name),
+			Vcard:       proto.String(vcard),
+		})
+	}
+
+	switch len(contacts) {
+	case 0:
+		return nil, fmt.Errorf("at least one contact is required")
+	case 1:
+		return &waE2E.Message{ContactMessage: contacts[0]}, nil
+	}
+	return &waE2E.Message{
+		ContactsArrayMessage: &waE2E.ContactsArrayMessage{
+			DisplayName: proto.String(fmt.Sprintf("%d contacts", len(contacts))),
+			Contacts:    contacts,
+		},
+	}, nil
+}
+
+// vcardEscape escape karakter khusus nilai vCard (RFC 6350)
+func vcardEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// vcardField ambil nilai property pertama (mis. FN) dari vCard mentah
+func vcardField(vcard, name string) string {
+	for _, line := range strings.Split(vcard, "\n") {
+		line = strings.TrimRight(line, "\r")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if key, _, _ = strings.Cut(key, ";"); strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
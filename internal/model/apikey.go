@@ -0,0 +1,220 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Permission API key
+const (
+	PermSend   = "send"   // kirim pesan / media
+	PermRead   = "read"   // baca status, instance, group, riwayat pesan
+	PermManage = "manage" // login/QR, logout, delete instance, webhook
+)
+
+var AllPermissions = []string{PermSend, PermRead, PermManage}
+
+// Struct APIKey sesuai field table api_keys (key asli tidak disimpan, hanya hash)
+type APIKey struct {
+	ID           int64
+	UserID       int64
+	Name         string
+	KeyPrefix    string
+	KeyHash      string
+	InstanceIDs  []string // kosong + PhoneNumbers kosong = semua instance
+	PhoneNumbers []string
+	Permissions  []string
+	CreatedAt    time.Time
+	ExpiresAt    sql.NullTime
+	LastUsedAt   sql.NullTime
+	RevokedAt    sql.NullTime
+}
+
+type APIKeyResp struct {
+	ID           int64      `json:"id"`
+	UserID       int64      `json:"userId"`
+	Name         string     `json:"name"`
+	KeyPrefix    string     `json:"keyPrefix"`
+	InstanceIDs  []string   `json:"instanceIds"`
+	PhoneNumbers []string   `json:"phoneNumbers"`
+	Permissions  []string   `json:"permissions"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+}
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+const apiKeyColumns = `
+            id,
+            user_id,
+            name,
+            key_prefix,
+            key_hash,
+            instance_ids,
+            phone_numbers,
+            permissions,
+            created_at,
+            expires_at,
+            last_used_at,
+            revoked_at`
+
+// HasPermission cek apakah key punya permission tertentu
+func (k *APIKey) HasPermission(perm string) bool {
+	for _, p := range k.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// IsScoped true kalau key dibatasi ke instance / nomor tertentu
+func (k *APIKey) IsScoped() bool {
+	return len(k.InstanceIDs) > 0 || len(k.PhoneNumbers) > 0
+}
+
+// InsertAPIKey simpan API key baru, ID & created_at diisi balik ke struct
+func InsertAPIKey(k *APIKey) error {
+	query := `
+    INSERT INTO api_keys (user_id, name, key_prefix, key_hash, instance_ids, phone_numbers, permissions, expires_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    RETURNING id, created_at`
+	return database.AppDB.QueryRow(
+		query,
+		k.UserID,
+		k.Name,
+		k.KeyPrefix,
+		k.KeyHash,
+		pq.Array(k.InstanceIDs),
+		pq.Array(k.PhoneNumbers),
+		pq.Array(k.Permissions),
+		k.ExpiresAt,
+	).Scan(&k.ID, &k.CreatedAt)
+}
+
+// GetActiveAPIKeyByHash ambil key yang belum di-revoke dan belum expired
+func GetActiveAPIKeyByHash(keyHash string) (*APIKey, error) {
+	query := `SELECT` + apiKeyColumns + `
+        FROM api_keys
+        WHERE key_hash = $1
+          AND revoked_at IS NULL
+          AND (expires_at IS NULL OR expires_at > NOW())
+    `
+	return scanAPIKey(database.AppDB.QueryRow(query, keyHash))
+}
+
+func GetAPIKey(id int64) (*APIKey, error) {
+	query := `SELECT` + apiKeyColumns + `
+        FROM api_keys
+        WHERE id = $1
+    `
+	return scanAPIKey(database.AppDB.QueryRow(query, id))
+}
+
+// GetAPIKeys list key milik user; userID 0 = semua user (admin)
+func GetAPIKeys(userID int64) ([]APIKey, error) {
+	query := `SELECT` + apiKeyColumns + `
+        FROM api_keys
+        WHERE ($1 = 0 OR user_id = $1)
+        ORDER BY id
+    `
+	rows, err := database.AppDB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func RevokeAPIKey(id int64) error {
+	res, err := database.AppDB.Exec(
+		`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchAPIKey update last_used_at, maksimal sekali per menit supaya tidak write tiap request
+func TouchAPIKey(id int64) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE api_keys SET last_used_at = NOW()
+        WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+    `, id)
+	return err
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	k := &APIKey{}
+	err := row.Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.KeyPrefix,
+		&k.KeyHash,
+		pq.Array(&k.InstanceIDs),
+		pq.Array(&k.PhoneNumbers),
+		pq.Array(&k.Permissions),
+		&k.CreatedAt,
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func ToAPIKeyResponse(k APIKey) APIKeyResp {
+	resp := APIKeyResp{
+		ID:           k.ID,
+		UserID:       k.UserID,
+		Name:         k.Name,
+		KeyPrefix:    k.KeyPrefix,
+		InstanceIDs:  nonNilStrings(k.InstanceIDs),
+		PhoneNumbers: nonNilStrings(k.PhoneNumbers),
+		Permissions:  nonNilStrings(k.Permissions),
+		CreatedAt:    k.CreatedAt,
+	}
+	if k.ExpiresAt.Valid {
+		resp.ExpiresAt = &k.ExpiresAt.Time
+	}
+	if k.LastUsedAt.Valid {
+		resp.LastUsedAt = &k.LastUsedAt.Time
+	}
+	if k.RevokedAt.Valid {
+		resp.RevokedAt = &k.RevokedAt.Time
+	}
+	return resp
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,460 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Status campaign
+const (
+	CampaignStatusRunning   = "running"
+	CampaignStatusPaused    = "paused"
+	CampaignStatusCompleted = "completed"
+	CampaignStatusCancelled = "cancelled"
+)
+
+// Status per penerima campaign
+const (
+	RecipientStatusPending       = "pending"        // belum dicek IsOnWhatsApp
+	RecipientStatusValid         = "valid"          // terdaftar di WhatsApp, menunggu giliran kirim
+	RecipientStatusInvalid       = "invalid"        // format nomor salah
+	RecipientStatusNotRegistered = "not_registered" // nomor tidak terdaftar di WhatsApp
+	RecipientStatusSent          = "sent"
+	RecipientStatusFailed        = "failed"
+	RecipientStatusCancelled     = "cancelled" // campaign dibatalkan sebelum giliran kirim
+)
+
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+// Struct Campaign sesuai field table campaigns
+type Campaign struct {
+	ID          int64
+	InstanceID  string
+	Name        string
+	MessageType string
+	Payload     []byte // waE2E.Message hasil proto.Marshal (media sudah di-upload sekali)
+	Status      string
+	DelayMs     int // jeda minimal antar pesan
+	JitterMs    int // tambahan jeda acak 0..JitterMs
+	LastError   sql.NullString
+	// Template: body di-render per penerima (Variables campaign + Variables penerima, JSON object)
+	TemplateBody sql.NullString
+	Variables    []byte
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	StartedAt    sql.NullTime
+	CompletedAt  sql.NullTime
+}
+
+// Struct CampaignRecipient sesuai field table campaign_recipients
+type CampaignRecipient struct {
+	ID         int64
+	CampaignID int64
+	Position   int
+	Phone      string
+	JID        sql.NullString
+	Status     string
+	MessageID  sql.NullString
+	Error      sql.NullString
+	Variables  []byte // JSON object variabel template khusus penerima ini
+	UpdatedAt  time.Time
+	SentAt     sql.NullTime
+}
+
+// CampaignStats jumlah penerima per status (progress campaign)
+type CampaignStats struct {
+	Total         int `json:"total"`
+	Pending       int `json:"pending"`
+	Valid         int `json:"valid"`
+	Invalid       int `json:"invalid"`
+	NotRegistered int `json:"notRegistered"`
+	Sent          int `json:"sent"`
+	Failed        int `json:"failed"`
+	Cancelled     int `json:"cancelled"`
+}
+
+type CampaignResp struct {
+	CampaignID  int64         `json:"campaignId"`
+	InstanceID  string        `json:"instanceId"`
+	Name        string        `json:"name"`
+	MessageType string        `json:"messageType"`
+	Status      string        `json:"status"`
+	DelayMs     int           `json:"delayMs"`
+	JitterMs    int           `json:"jitterMs"`
+	LastError   string        `json:"lastError,omitempty"`
+	Progress    CampaignStats `json:"progress"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+	StartedAt   *time.Time    `json:"startedAt,omitempty"`
+	CompletedAt *time.Time    `json:"completedAt,omitempty"`
+}
+
+type CampaignRecipientResp struct {
+	ID        int64           `json:"id"`
+	Position  int             `json:"position"`
+	Phone     string          `json:"phone"`
+	JID       string          `json:"jid,omitempty"`
+	Status    string          `json:"status"`
+	MessageID string          `json:"messageId,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Variables json.RawMessage `json:"variables,omitempty"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	SentAt    *time.Time      `json:"sentAt,omitempty"`
+}
+
+const campaignColumns = `
+            id,
+            instance_id,
+            name,
+            message_type,
+            payload,
+            status,
+            delay_ms,
+            jitter_ms,
+            last_error,
+            template_body,
+            variables,
+            created_at,
+            updated_at,
+            started_at,
+            completed_at`
+
+const campaignRecipientColumns = `
+            id,
+            campaign_id,
+            position,
+            phone,
+            jid,
+            status,
+            message_id,
+            error,
+            variables,
+            updated_at,
+            sent_at`
+
+// InsertCampaign simpan campaign + semua penerima dalam satu transaksi
+func InsertCampaign(c *Campaign, recipients []CampaignRecipient) error {
+	tx, err := database.AppDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO campaigns (instance_id, name, message_type, payload, status, delay_ms, jitter_ms, template_body, variables, started_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::jsonb, NOW())
+    RETURNING id, created_at, updated_at, started_at`
+	err = tx.QueryRow(
+		query,
+		c.InstanceID,
+		c.Name,
+		c.MessageType,
+		c.Payload,
+		c.Status,
+		c.DelayMs,
+		c.JitterMs,
+		c.TemplateBody,
+		NullString(string(c.Variables)),
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.StartedAt)
+	if err != nil {
+		return err
+	}
+
+	phones := make([]string, len(recipients))
+	statuses := make([]string, len(recipients))
+	errs := make([]string, len(recipients))
+	vars := make([]string, len(recipients))
+	for i, r := range recipients {
+		phones[i] = r.Phone
+		statuses[i] = r.Status
+		errs[i] = r.Error.String
+		vars[i] = string(r.Variables)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO campaign_recipients (campaign_id, position, phone, status, error, variables)
+        SELECT $1, t.position, t.phone, t.status, NULLIF(t.error, ''), NULLIF(t.variables, '')::jsonb
+        FROM unnest($2::text[], $3::text[], $4::text[], $5::text[]) WITH ORDINALITY AS t(phone, status, error, variables, position)
+    `, c.ID, pq.Array(phones), pq.Array(statuses), pq.Array(errs), pq.Array(vars))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func GetCampaign(instanceID string, id int64) (*Campaign, error) {
+	query := `SELECT` + campaignColumns + `
+        FROM campaigns
+        WHERE id = $1 AND instance_id = $2
+    `
+	c, err := scanCampaign(database.AppDB.QueryRow(query, id, instanceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCampaignNotFound
+	}
+	return c, err
+}
+
+// GetCampaigns list campaign sebuah instance (terbaru dulu), status opsional
+func GetCampaigns(instanceID, status string, limit, offset int) ([]Campaign, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM campaigns WHERE instance_id = $1 AND ($2 = '' OR status = $2)`
+	if err := database.AppDB.QueryRow(countQuery, instanceID, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT` + campaignColumns + `
+        FROM campaigns
+        WHERE instance_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY id DESC
+        LIMIT $3 OFFSET $4
+    `
+	campaigns, err := queryCampaigns(query, instanceID, status, limit, offset)
+	return campaigns, total, err
+}
+
+// GetRunningCampaigns dipakai saat startup untuk melanjutkan campaign yang terputus restart
+func GetRunningCampaigns() ([]Campaign, error) {
+	query := `SELECT` + campaignColumns + `
+        FROM campaigns
+        WHERE status = 'running'
+        ORDER BY id
+    `
+	return queryCampaigns(query)
+}
+
+func queryCampaigns(query string, args ...interface{}) ([]Campaign, error) {
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := make([]Campaign, 0)
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, *c)
+	}
+	return campaigns, rows.Err()
+}
+
+// GetCampaignStatus ambil status terbaru (dicek runner sebelum setiap pengiriman)
+func GetCampaignStatus(id int64) (string, error) {
+	var status string
+	err := database.AppDB.QueryRow(`SELECT status FROM campaigns WHERE id = $1`, id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrCampaignNotFound
+	}
+	return status, err
+}
+
+// TransitionCampaignStatus ubah status hanya kalau status sekarang salah satu dari `from`.
+// Return false kalau transisi tidak berlaku (mis. resume campaign yang sudah completed).
+func TransitionCampaignStatus(id int64, from []string, to, lastError string) (bool, error) {
+	query := `
+        UPDATE campaigns
+        SET status = $1,
+            last_error = $2,
+            updated_at = NOW(),
+            completed_at = CASE WHEN $1 IN ('completed', 'cancelled') THEN NOW() ELSE completed_at END
+        WHERE id = $3 AND status = ANY($4)
+    `
+	res, err := database.AppDB.Exec(query, to, NullString(lastError), id, pq.Array(from))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func GetCampaignStats(campaignID int64) (CampaignStats, error) {
+	var s CampaignStats
+	err := database.AppDB.QueryRow(`
+        SELECT
+            COUNT(*),
+            COUNT(*) FILTER (WHERE status = 'pending'),
+            COUNT(*) FILTER (WHERE status = 'valid'),
+            COUNT(*) FILTER (WHERE status = 'invalid'),
+            COUNT(*) FILTER (WHERE status = 'not_registered'),
+            COUNT(*) FILTER (WHERE status = 'sent'),
+            COUNT(*) FILTER (WHERE status = 'failed'),
+            COUNT(*) FILTER (WHERE status = 'cancelled')
+        FROM campaign_recipients
+        WHERE campaign_id = $1
+    `, campaignID).Scan(
+		&s.Total,
+		&s.Pending,
+		&s.Valid,
+		&s.Invalid,
+		&s.NotRegistered,
+		&s.Sent,
+		&s.Failed,
+		&s.Cancelled,
+	)
+	return s, err
+}
+
+// GetCampaignRecipientsByStatus ambil penerima sesuai status urut posisi (untuk batch validasi & kirim)
+func GetCampaignRecipientsByStatus(campaignID int64, status string, limit int) ([]CampaignRecipient, error) {
+	query := `SELECT` + campaignRecipientColumns + `
+        FROM campaign_recipients
+        WHERE campaign_id = $1 AND status = $2
+        ORDER BY position
+        LIMIT $3
+    `
+	return queryCampaignRecipients(query, campaignID, status, limit)
+}
+
+// GetCampaignRecipients list hasil per penerima, status opsional
+func GetCampaignRecipients(campaignID int64, status string, limit, offset int) ([]CampaignRecipient, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM campaign_recipients WHERE campaign_id = $1 AND ($2 = '' OR status = $2)`
+	if err := database.AppDB.QueryRow(countQuery, campaignID, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT` + campaignRecipientColumns + `
+        FROM campaign_recipients
+        WHERE campaign_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY position
+        LIMIT $3 OFFSET $4
+    `
+	recipients, err := queryCampaignRecipients(query, campaignID, status, limit, offset)
+	return recipients, total, err
+}
+
+func queryCampaignRecipients(query string, args ...interface{}) ([]CampaignRecipient, error) {
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := make([]CampaignRecipient, 0)
+	for rows.Next() {
+		r, err := scanCampaignRecipient(rows)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, *r)
+	}
+	return recipients, rows.Err()
+}
+
+// UpdateCampaignRecipientValidation simpan hasil IsOnWhatsApp (valid + JID, atau not_registered)
+func UpdateCampaignRecipientValidation(id int64, status, jid string) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE campaign_recipients
+        SET status = $1, jid = $2, updated_at = NOW()
+        WHERE id = $3
+    `, status, NullString(jid), id)
+	return err
+}
+
+// MarkCampaignRecipientResult simpan hasil kirim satu penerima
+func MarkCampaignRecipientResult(id int64, status, messageID, lastError string) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE campaign_recipients
+        SET status = $1, message_id = $2, error = $3, updated_at = NOW(),
+            sent_at = CASE WHEN $1 = 'sent' THEN NOW() ELSE sent_at END
+        WHERE id = $4
+    `, status, NullString(messageID), NullString(lastError), id)
+	return err
+}
+
+// CancelCampaignRecipients tandai penerima yang belum dikirim sebagai cancelled
+func CancelCampaignRecipients(campaignID int64) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE campaign_recipients
+        SET status = 'cancelled', updated_at = NOW()
+        WHERE campaign_id = $1 AND status IN ('pending', 'valid')
+    `, campaignID)
+	return err
+}
+
+func scanCampaign(row rowScanner) (*Campaign, error) {
+	c := &Campaign{}
+	err := row.Scan(
+		&c.ID,
+		&c.InstanceID,
+		&c.Name,
+		&c.MessageType,
+		&c.Payload,
+		&c.Status,
+		&c.DelayMs,
+		&c.JitterMs,
+		&c.LastError,
+		&c.TemplateBody,
+		&c.Variables,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+		&c.StartedAt,
+		&c.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func scanCampaignRecipient(row rowScanner) (*CampaignRecipient, error) {
+	r := &CampaignRecipient{}
+	err := row.Scan(
+		&r.ID,
+		&r.CampaignID,
+		&r.Position,
+		&r.Phone,
+		&r.JID,
+		&r.Status,
+		&r.MessageID,
+		&r.Error,
+		&r.Variables,
+		&r.UpdatedAt,
+		&r.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func ToCampaignResponse(c Campaign, stats CampaignStats) CampaignResp {
+	return CampaignResp{
+		CampaignID:  c.ID,
+		InstanceID:  c.InstanceID,
+		Name:        c.Name,
+		MessageType: c.MessageType,
+		Status:      c.Status,
+		DelayMs:     c.DelayMs,
+		JitterMs:    c.JitterMs,
+		LastError:   c.LastError.String,
+		Progress:    stats,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+		StartedAt:   timePtr(c.StartedAt),
+		CompletedAt: timePtr(c.CompletedAt),
+	}
+}
+
+func ToCampaignRecipientResponse(r CampaignRecipient) CampaignRecipientResp {
+	return CampaignRecipientResp{
+		ID:        r.ID,
+		Position:  r.Position,
+		Phone:     r.Phone,
+		JID:       r.JID.String,
+		Status:    r.Status,
+		MessageID: r.MessageID.String,
+		Error:     r.Error.String,
+		Variables: r.Variables,
+		UpdatedAt: r.UpdatedAt,
+		SentAt:    timePtr(r.SentAt),
+	}
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+)
+
+// Status idempotency key
+const (
+	IdempotencyStatusProcessing = "processing" // request pertama masih diproses
+	IdempotencyStatusCompleted  = "completed"  // response tersimpan, request ulang di-replay
+)
+
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+// Struct IdempotencyKey sesuai field table idempotency_keys
+type IdempotencyKey struct {
+	UserID       int64
+	Key          string
+	RequestHash  string // sha256 method + path + body request pertama
+	Status       string
+	StatusCode   sql.NullInt64
+	ResponseBody []byte
+	MessageID    sql.NullString
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+const idempotencyKeyColumns = `
+            user_id,
+            idempotency_key,
+            request_hash,
+            status,
+            status_code,
+            response_body,
+            message_id,
+            created_at,
+            expires_at`
+
+// ReserveIdempotencyKey klaim key untuk request baru (status processing). Key yang sudah expired,
+// atau masih processing sejak sebelum staleBefore (mis. server mati di tengah request), boleh diklaim ulang.
+// Return false kalau key masih dipakai request lain / sudah punya response.
+func ReserveIdempotencyKey(userID int64, key, requestHash string, expiresAt, staleBefore time.Time) (bool, error) {
+	var createdAt time.Time
+	err := database.AppDB.QueryRow(`
+        INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id, idempotency_key) DO UPDATE
+        SET request_hash = EXCLUDED.request_hash,
+            status = 'processing',
+            status_code = NULL,
+            response_body = NULL,
+            message_id = NULL,
+            created_at = NOW(),
+            expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= NOW()
+           OR (idempotency_keys.status = 'processing' AND idempotency_keys.created_at < $5)
+        RETURNING created_at
+    `, userID, key, requestHash, expiresAt, staleBefore).Scan(&createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetIdempotencyKey ambil key yang belum expired
+func GetIdempotencyKey(userID int64, key string) (*IdempotencyKey, error) {
+	query := `SELECT` + idempotencyKeyColumns + `
+        FROM idempotency_keys
+        WHERE user_id = $1 AND idempotency_key = $2 AND expires_at > NOW()
+    `
+	k := &IdempotencyKey{}
+	err := database.AppDB.QueryRow(query, userID, key).Scan(
+		&k.UserID,
+		&k.Key,
+		&k.RequestHash,
+		&k.Status,
+		&k.StatusCode,
+		&k.ResponseBody,
+		&k.MessageID,
+		&k.CreatedAt,
+		&k.ExpiresAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// CompleteIdempotencyKey simpan hasil request pertama untuk di-replay
+func CompleteIdempotencyKey(userID int64, key string, statusCode int, body []byte, messageID string) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE idempotency_keys
+        SET status = 'completed', status_code = $3, response_body = $4, message_id = $5
+        WHERE user_id = $1 AND idempotency_key = $2
+    `, userID, key, statusCode, body, NullString(messageID))
+	return err
+}
+
+// DeleteIdempotencyKey lepas key (request gagal, boleh dicoba ulang dengan key yang sama)
+func DeleteIdempotencyKey(userID int64, key string) error {
+	_, err := database.AppDB.Exec(`DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`, userID, key)
+	return err
+}
+
+// DeleteExpiredIdempotencyKeys hapus key yang sudah lewat window, return jumlah yang dihapus
+func DeleteExpiredIdempotencyKeys() (int64, error) {
+	res, err := database.AppDB.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
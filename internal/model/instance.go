@@ -11,25 +11,31 @@ import (
 
 // Struct Instance sesuai field table
 type Instance struct {
-	ID              int64
-	InstanceID      string
-	PhoneNumber     sql.NullString
-	JID             sql.NullString
-	Status          string
-	IsConnected     bool
-	Name            sql.NullString
-	ProfilePicture  sql.NullString
-	About           sql.NullString
-	Platform        sql.NullString
-	BatteryLevel    sql.NullInt64
-	BatteryCharging sql.NullBool
-	QRCode          sql.NullString
-	QRExpiresAt     sql.NullTime
-	CreatedAt       time.Time
-	ConnectedAt     sql.NullTime
-	DisconnectedAt  sql.NullTime
-	LastSeen        sql.NullTime
-	SessionData     []byte // tambahkan ini
+	ID                int64
+	InstanceID        string
+	PhoneNumber       sql.NullString
+	JID               sql.NullString
+	Status            string
+	IsConnected       bool
+	Name              sql.NullString
+	ProfilePicture    sql.NullString
+	About             sql.NullString
+	Platform          sql.NullString
+	BatteryLevel      sql.NullInt64
+	BatteryCharging   sql.NullBool
+	QRCode            sql.NullString
+	QRExpiresAt       sql.NullTime
+	CreatedAt         time.Time
+	ConnectedAt       sql.NullTime
+	DisconnectedAt    sql.NullTime
+	LastSeen          sql.NullTime
+	SessionData       []byte         // tambahkan ini
+	OwnerID           sql.NullInt64  // user pemilik instance (NULL = hanya admin)
+	PairCode          sql.NullString // kode pairing via nomor HP (alternatif QR)
+	PairCodeExpiresAt sql.NullTime
+	ReconnectAttempts int            // percobaan reconnect berturut-turut (reset saat connected)
+	LastError         sql.NullString // error terakhir saat reconnect
+	NextReconnectAt   sql.NullTime
 }
 
 type InstanceResp struct {
@@ -47,17 +53,24 @@ type InstanceResp struct {
 	BatteryCharging   bool      `json:"batteryCharging"`
 	QRCode            string    `json:"qrCode"`
 	QRExpiresAt       time.Time `json:"qrExpiresAt"`
+	PairCode          string    `json:"pairCode,omitempty"`
+	PairCodeExpiresAt time.Time `json:"pairCodeExpiresAt,omitempty"`
+	ReconnectAttempts int       `json:"reconnectAttempts"`
+	LastError         string    `json:"lastError,omitempty"`
+	NextReconnectAt   time.Time `json:"nextReconnectAt,omitempty"`
 	CreatedAt         time.Time `json:"createdAt"`
 	ConnectedAt       time.Time `json:"connectedAt"`
 	DisconnectedAt    time.Time `json:"disconnectedAt"`
 	LastSeen          time.Time `json:"lastSeen"`
 	ExistsInWhatsmeow bool      `json:"existsInWhatsmeow"`
+	OwnerID           int64     `json:"ownerId,omitempty"`
 }
 
 var ErrNoActiveInstance = errors.New("no active instance for this phone number")
 
 // GetActiveInstanceByPhoneNumber mengembalikan instance aktif (terbaru) untuk nomor tertentu.
-func GetActiveInstanceByPhoneNumber(phoneNumber string) (*Instance, error) {
+// ownerID 0 = instance milik siapa saja (admin), selain itu hanya instance milik user tsb.
+func GetActiveInstanceByPhoneNumber(phoneNumber string, ownerID int64) (*Instance, error) {
 	query := `
         SELECT
             id,
@@ -78,17 +91,24 @@ func GetActiveInstanceByPhoneNumber(phoneNumber string) (*Instance, error) {
             connected_at,
             disconnected_at,
             last_seen,
-            session_data
+            session_data,
+            owner_id,
+            pair_code,
+            pair_code_expires_at,
+            reconnect_attempts,
+            last_error,
+            next_reconnect_at
         FROM instances
         WHERE phone_number = $1
           AND status = 'online'
           AND is_connected = true
+          AND ($2 = 0 OR owner_id = $2)
         ORDER BY connected_at DESC, created_at DESC
         LIMIT 1
     `
 
 	inst := &Instance{}
-	err := database.AppDB.QueryRow(query, phoneNumber).Scan(
+	err := database.AppDB.QueryRow(query, phoneNumber, ownerID).Scan(
 		&inst.ID,
 		&inst.InstanceID,
 		&inst.PhoneNumber,
@@ -108,6 +128,12 @@ func GetActiveInstanceByPhoneNumber(phoneNumber string) (*Instance, error) {
 		&inst.DisconnectedAt,
 		&inst.LastSeen,
 		&inst.SessionData,
+		&inst.OwnerID,
+		&inst.PairCode,
+		&inst.PairCodeExpiresAt,
+		&inst.ReconnectAttempts,
+		&inst.LastError,
+		&inst.NextReconnectAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -123,8 +149,8 @@ func GetActiveInstanceByPhoneNumber(phoneNumber string) (*Instance, error) {
 func InsertInstance(in *Instance) error {
 	query := `
     INSERT INTO instances (
-        instance_id, status, is_connected, created_at, session_data
-    ) VALUES ($1, $2, $3, $4, $5)`
+        instance_id, status, is_connected, created_at, session_data, owner_id
+    ) VALUES ($1, $2, $3, $4, $5, $6)`
 	_, err := database.AppDB.Exec(
 		query,
 		in.InstanceID,
@@ -132,6 +158,7 @@ func InsertInstance(in *Instance) error {
 		in.IsConnected,
 		in.CreatedAt,
 		in.SessionData, // <- pastikan mengisi ini (bisa nil untuk awal)
+		in.OwnerID,
 	)
 	return err
 }
@@ -147,8 +174,19 @@ func UpdateInstanceQR(instanceID, qr string, expiresAt time.Time) error {
 	return err
 }
 
-// Ambil semua instance dari database custom
-func GetAllInstances() ([]Instance, error) {
+// simpan kode pairing (login via nomor HP) beserta expiry-nya
+func UpdateInstancePairCode(instanceID, code string, expiresAt time.Time) error {
+	query := `
+        UPDATE instances
+        SET pair_code = $1, pair_code_expires_at = $2, status = $3
+        WHERE instance_id = $4
+    `
+	_, err := database.AppDB.Exec(query, code, expiresAt, "pair_code_required", instanceID)
+	return err
+}
+
+// Ambil semua instance dari database custom; ownerID 0 = semua owner (admin)
+func GetAllInstances(ownerID int64) ([]Instance, error) {
 	query := `
         SELECT 
             id,
@@ -169,12 +207,19 @@ func GetAllInstances() ([]Instance, error) {
             connected_at,
             disconnected_at,
             last_seen,
-            session_data
+            session_data,
+            owner_id,
+            pair_code,
+            pair_code_expires_at,
+            reconnect_attempts,
+            last_error,
+            next_reconnect_at
         FROM instances
+        WHERE ($1 = 0 OR owner_id = $1)
         ORDER BY created_at DESC
     `
 
-	rows, err := database.AppDB.Query(query)
+	rows, err := database.AppDB.Query(query, ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +249,12 @@ func GetAllInstances() ([]Instance, error) {
 			&inst.DisconnectedAt,
 			&inst.LastSeen,
 			&inst.SessionData,
+			&inst.OwnerID,
+			&inst.PairCode,
+			&inst.PairCodeExpiresAt,
+			&inst.ReconnectAttempts,
+			&inst.LastError,
+			&inst.NextReconnectAt,
 		)
 
 		if err != nil {
@@ -224,17 +275,55 @@ func UpdateInstanceOnConnected(instanceID, jid, phoneNumber, platform string) er
         SET
             jid = $1,
             phone_number = $2,
-            platform = $3,
+            platform = COALESCE(NULLIF($3, ''), platform),
             status = 'online',
             is_connected = true,
             connected_at = NOW(),
-            last_seen = NOW()
+            first_connected_at = COALESCE(first_connected_at, NOW()),
+            last_seen = NOW(),
+            pair_code = NULL,
+            pair_code_expires_at = NULL,
+            reconnect_attempts = 0,
+            last_error = NULL,
+            next_reconnect_at = NULL
         WHERE instance_id = $4
     `
 	_, err := database.AppDB.Exec(query, jid, phoneNumber, platform, instanceID)
 	return err
 }
 
+// GetInstanceFirstConnectedAt kapan nomor instance pertama kali connect (acuan warm-up rate limit)
+func GetInstanceFirstConnectedAt(instanceID string) (sql.NullTime, error) {
+	var t sql.NullTime
+	err := database.AppDB.QueryRow(`SELECT first_connected_at FROM instances WHERE instance_id = $1`, instanceID).Scan(&t)
+	return t, err
+}
+
+// InstanceProfile data profil nomor instance. Field yang tidak Valid tidak diubah,
+// Valid dengan string kosong = kolom dikosongkan (mis. foto profil dihapus).
+type InstanceProfile struct {
+	Name           sql.NullString
+	About          sql.NullString
+	ProfilePicture sql.NullString
+	Platform       sql.NullString
+}
+
+// update profil instance (push name, about, foto, platform)
+func UpdateInstanceProfile(instanceID string, p InstanceProfile) error {
+	query := `
+        UPDATE instances
+        SET
+            name = CASE WHEN $1::text IS NULL THEN name ELSE NULLIF($1, '') END,
+            about = CASE WHEN $2::text IS NULL THEN about ELSE NULLIF($2, '') END,
+            profile_picture = CASE WHEN $3::text IS NULL THEN profile_picture ELSE NULLIF($3, '') END,
+            platform = CASE WHEN $4::text IS NULL THEN platform ELSE NULLIF($4, '') END,
+            last_seen = NOW()
+        WHERE instance_id = $5
+    `
+	_, err := database.AppDB.Exec(query, p.Name, p.About, p.ProfilePicture, p.Platform, instanceID)
+	return err
+}
+
 func UpdateInstanceOnDisconnected(instanceID string) error {
 	query := `
         UPDATE instances
@@ -248,6 +337,38 @@ func UpdateInstanceOnDisconnected(instanceID string) error {
 	return err
 }
 
+// update status saat supervisor menjadwalkan percobaan reconnect berikutnya
+func UpdateInstanceReconnecting(instanceID string, attempt int, lastError string, nextAt time.Time) error {
+	query := `
+        UPDATE instances
+        SET
+            status = 'reconnecting',
+            is_connected = false,
+            reconnect_attempts = $1,
+            last_error = NULLIF($2, ''),
+            next_reconnect_at = $3
+        WHERE instance_id = $4
+    `
+	_, err := database.AppDB.Exec(query, attempt, lastError, nextAt, instanceID)
+	return err
+}
+
+// update status saat supervisor menyerah, instance perlu dicek manual
+func UpdateInstanceNeedsAttention(instanceID string, attempts int, lastError string) error {
+	query := `
+        UPDATE instances
+        SET
+            status = 'needs_attention',
+            is_connected = false,
+            reconnect_attempts = $1,
+            last_error = NULLIF($2, ''),
+            next_reconnect_at = NULL
+        WHERE instance_id = $3
+    `
+	_, err := database.AppDB.Exec(query, attempts, lastError, instanceID)
+	return err
+}
+
 func UpdateInstanceOnLoggedOut(instanceID string) error {
 	query := `
         UPDATE instances
@@ -295,7 +416,13 @@ func GetInstanceByJID(jid string) (*Instance, error) {
             connected_at,
             disconnected_at,
             last_seen,
-            session_data
+            session_data,
+            owner_id,
+            pair_code,
+            pair_code_expires_at,
+            reconnect_attempts,
+            last_error,
+            next_reconnect_at
         FROM instances
         WHERE jid = $1
         ORDER BY created_at DESC
@@ -323,6 +450,12 @@ func GetInstanceByJID(jid string) (*Instance, error) {
 		&inst.DisconnectedAt,
 		&inst.LastSeen,
 		&inst.SessionData,
+		&inst.OwnerID,
+		&inst.PairCode,
+		&inst.PairCodeExpiresAt,
+		&inst.ReconnectAttempts,
+		&inst.LastError,
+		&inst.NextReconnectAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -357,7 +490,13 @@ func GetInstanceByInstanceID(instanceID string) (*Instance, error) {
             connected_at,
             disconnected_at,
             last_seen,
-            session_data
+            session_data,
+            owner_id,
+            pair_code,
+            pair_code_expires_at,
+            reconnect_attempts,
+            last_error,
+            next_reconnect_at
         FROM instances
         WHERE instance_id = $1
         LIMIT 1
@@ -399,18 +538,45 @@ func GetInstanceByInstanceID(instanceID string) (*Instance, error) {
 		&disconnectedAtNT,
 		&lastSeenNT,
 		&inst.SessionData,
+		&inst.OwnerID,
+		&inst.PairCode,
+		&inst.PairCodeExpiresAt,
+		&inst.ReconnectAttempts,
+		&inst.LastError,
+		&inst.NextReconnectAt,
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	// Assign dari variabel Null* ke field struct
+	inst.PhoneNumber = phoneNS
+	inst.JID = jidNS
+	inst.Name = nameNS
+	inst.ProfilePicture = profileNS
+	inst.About = aboutNS
+	inst.Platform = platformNS
 	inst.QRCode = qrCodeNS           // ← tambahkan baris ini
 	inst.QRExpiresAt = qrExpiresAtNT // ← dan ini
+	inst.ConnectedAt = connectedAtNT
+	inst.DisconnectedAt = disconnectedAtNT
+	inst.LastSeen = lastSeenNT
 
 	return inst, nil
 }
 
+// UpdateInstanceOwner pindahkan kepemilikan instance ke user lain (admin)
+func UpdateInstanceOwner(instanceID string, ownerID int64) error {
+	res, err := database.AppDB.Exec(`UPDATE instances SET owner_id = $1 WHERE instance_id = $2`, ownerID, instanceID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // Hapus instance table custom
 func DeleteInstanceByInstanceID(instanceID string) error {
 	_, err := database.AppDB.Exec(`DELETE FROM instances WHERE instance_id = $1`, instanceID)
@@ -455,6 +621,19 @@ func ToResponse(inst Instance) InstanceResp {
 	if inst.QRExpiresAt.Valid {
 		resp.QRExpiresAt = inst.QRExpiresAt.Time
 	}
+	if inst.PairCode.Valid {
+		resp.PairCode = inst.PairCode.String
+	}
+	if inst.PairCodeExpiresAt.Valid {
+		resp.PairCodeExpiresAt = inst.PairCodeExpiresAt.Time
+	}
+	resp.ReconnectAttempts = inst.ReconnectAttempts
+	if inst.LastError.Valid {
+		resp.LastError = inst.LastError.String
+	}
+	if inst.NextReconnectAt.Valid {
+		resp.NextReconnectAt = inst.NextReconnectAt.Time
+	}
 	resp.CreatedAt = inst.CreatedAt
 	if inst.ConnectedAt.Valid {
 		resp.ConnectedAt = inst.ConnectedAt.Time
@@ -465,6 +644,9 @@ func ToResponse(inst Instance) InstanceResp {
 	if inst.LastSeen.Valid {
 		resp.LastSeen = inst.LastSeen.Time
 	}
+	if inst.OwnerID.Valid {
+		resp.OwnerID = inst.OwnerID.Int64
+	}
 
 	return resp
 }
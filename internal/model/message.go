@@ -0,0 +1,304 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"gowa-yourself/database"
+	"strings"
+	"time"
+)
+
+// Struct Message sesuai field table messages
+type Message struct {
+	ID            int64
+	InstanceID    string
+	MessageID     string
+	ChatJID       string
+	ChatType      string
+	SenderJID     sql.NullString
+	SenderName    sql.NullString
+	Direction     string // "incoming" / "outgoing"
+	MessageType   string
+	Body          sql.NullString
+	MediaMimetype sql.NullString
+	MediaFileName sql.NullString
+	MediaSize     sql.NullInt64
+	RawMessage    []byte // waE2E.Message hasil proto.Marshal
+	Timestamp     time.Time
+	CreatedAt     time.Time
+
+	// Status pengiriman, hanya untuk pesan outgoing (server_ack → delivered → read → played)
+	Status          sql.NullString
+	StatusUpdatedAt sql.NullTime
+	DeliveredAt     sql.NullTime
+	ReadAt          sql.NullTime
+	PlayedAt        sql.NullTime
+
+	// Pesan yang diedit / ditarik (delete for everyone) lewat API
+	EditedAt  sql.NullTime
+	RevokedAt sql.NullTime
+}
+
+type MessageResp struct {
+	ID            int64      `json:"id"`
+	InstanceID    string     `json:"instanceId"`
+	MessageID     string     `json:"messageId"`
+	ChatJID       string     `json:"chatJid"`
+	ChatType      string     `json:"chatType"`
+	SenderJID     string     `json:"senderJid"`
+	SenderName    string     `json:"senderName"`
+	Direction     string     `json:"direction"`
+	MessageType   string     `json:"messageType"`
+	Body          string     `json:"body"`
+	MediaMimetype string     `json:"mediaMimetype,omitempty"`
+	MediaFileName string     `json:"mediaFileName,omitempty"`
+	MediaSize     int64      `json:"mediaSize,omitempty"`
+	Timestamp     time.Time  `json:"timestamp"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	Status        string     `json:"status,omitempty"`
+	EditedAt      *time.Time `json:"editedAt,omitempty"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+}
+
+// MessageFilter untuk query list pesan (GET /messages/:instanceId)
+type MessageFilter struct {
+	InstanceID string
+	ChatJID    string
+	Sender     string // JID lengkap atau nomor saja
+	Direction  string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+const messageColumns = `
+            id,
+            instance_id,
+            message_id,
+            chat_jid,
+            chat_type,
+            sender_jid,
+            sender_name,
+            direction,
+            message_type,
+            body,
+            media_mimetype,
+            media_file_name,
+            media_size,
+            raw_message,
+            timestamp,
+            created_at,
+            status,
+            status_updated_at,
+            delivered_at,
+            read_at,
+            played_at,
+            edited_at,
+            revoked_at`
+
+// InsertMessage simpan pesan ke table messages, abaikan kalau message_id sudah ada
+func InsertMessage(m *Message) error {
+	query := `
+    INSERT INTO messages (
+        instance_id, message_id, chat_jid, chat_type, sender_jid, sender_name,
+        direction, message_type, body, media_mimetype, media_file_name, media_size,
+        raw_message, timestamp, status, status_updated_at
+    ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+    ON CONFLICT (instance_id, message_id) DO NOTHING`
+	_, err := database.AppDB.Exec(
+		query,
+		m.InstanceID,
+		m.MessageID,
+		m.ChatJID,
+		m.ChatType,
+		m.SenderJID,
+		m.SenderName,
+		m.Direction,
+		m.MessageType,
+		m.Body,
+		m.MediaMimetype,
+		m.MediaFileName,
+		m.MediaSize,
+		m.RawMessage,
+		m.Timestamp,
+		m.Status,
+		m.StatusUpdatedAt,
+	)
+	return err
+}
+
+// GetMessages ambil list pesan sesuai filter + total row (untuk pagination)
+func GetMessages(f MessageFilter) ([]Message, int, error) {
+	where := []string{"instance_id = $1"}
+	args := []interface{}{f.InstanceID}
+
+	addArg := func(cond string, val interface{}) {
+		args = append(args, val)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.ChatJID != "" {
+		addArg("chat_jid = $%d", f.ChatJID)
+	}
+	if f.Sender != "" {
+		if strings.Contains(f.Sender, "@") {
+			addArg("sender_jid = $%d", f.Sender)
+		} else {
+			addArg("split_part(sender_jid, '@', 1) = $%d", f.Sender)
+		}
+	}
+	if f.Direction != "" {
+		addArg("direction = $%d", f.Direction)
+	}
+	if f.From != nil {
+		addArg("timestamp >= $%d", *f.From)
+	}
+	if f.To != nil {
+		addArg("timestamp <= $%d", *f.To)
+	}
+
+	whereSQL := strings.Join(where, " AND ")
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM messages WHERE ` + whereSQL
+	if err := database.AppDB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, f.Limit, f.Offset)
+	query := `SELECT` + messageColumns + `
+        FROM messages
+        WHERE ` + whereSQL + fmt.Sprintf(`
+        ORDER BY timestamp DESC, id DESC
+        LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	messages := make([]Message, 0)
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, *m)
+	}
+
+	return messages, total, rows.Err()
+}
+
+// GetMessageByMessageID ambil satu pesan berdasarkan instance + message id WhatsApp
+func GetMessageByMessageID(instanceID, messageID string) (*Message, error) {
+	query := `SELECT` + messageColumns + `
+        FROM messages
+        WHERE instance_id = $1 AND message_id = $2
+        LIMIT 1
+    `
+	return scanMessage(database.AppDB.QueryRow(query, instanceID, messageID))
+}
+
+// MarkMessageEdited update isi teks pesan setelah diedit
+func MarkMessageEdited(instanceID, messageID, body string, raw []byte) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE messages SET body = $3, raw_message = $4, edited_at = NOW()
+        WHERE instance_id = $1 AND message_id = $2
+    `, instanceID, messageID, body, raw)
+	return err
+}
+
+// MarkMessageRevoked tandai pesan sudah ditarik (delete for everyone)
+func MarkMessageRevoked(instanceID, messageID string) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE messages SET revoked_at = NOW()
+        WHERE instance_id = $1 AND message_id = $2
+    `, instanceID, messageID)
+	return err
+}
+
+// GetOutgoingMessageTimes waktu pesan keluar instance sejak waktu tertentu (urut naik),
+// dipakai untuk mengisi ulang hitungan rate limit setelah restart
+func GetOutgoingMessageTimes(instanceID string, since time.Time) ([]time.Time, error) {
+	rows, err := database.AppDB.Query(`
+        SELECT timestamp FROM messages
+        WHERE instance_id = $1 AND direction = 'outgoing' AND timestamp > $2
+        ORDER BY timestamp
+    `, instanceID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	times := make([]time.Time, 0)
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row rowScanner) (*Message, error) {
+	m := &Message{}
+	err := row.Scan(
+		&m.ID,
+		&m.InstanceID,
+		&m.MessageID,
+		&m.ChatJID,
+		&m.ChatType,
+		&m.SenderJID,
+		&m.SenderName,
+		&m.Direction,
+		&m.MessageType,
+		&m.Body,
+		&m.MediaMimetype,
+		&m.MediaFileName,
+		&m.MediaSize,
+		&m.RawMessage,
+		&m.Timestamp,
+		&m.CreatedAt,
+		&m.Status,
+		&m.StatusUpdatedAt,
+		&m.DeliveredAt,
+		&m.ReadAt,
+		&m.PlayedAt,
+		&m.EditedAt,
+		&m.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func ToMessageResponse(m Message) MessageResp {
+	return MessageResp{
+		ID:            m.ID,
+		InstanceID:    m.InstanceID,
+		MessageID:     m.MessageID,
+		ChatJID:       m.ChatJID,
+		ChatType:      m.ChatType,
+		SenderJID:     m.SenderJID.String,
+		SenderName:    m.SenderName.String,
+		Direction:     m.Direction,
+		MessageType:   m.MessageType,
+		Body:          m.Body.String,
+		MediaMimetype: m.MediaMimetype.String,
+		MediaFileName: m.MediaFileName.String,
+		MediaSize:     m.MediaSize.Int64,
+		Timestamp:     m.Timestamp,
+		CreatedAt:     m.CreatedAt,
+		Status:        m.Status.String,
+		EditedAt:      timePtr(m.EditedAt),
+		RevokedAt:     timePtr(m.RevokedAt),
+	}
+}
@@ -0,0 +1,268 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+)
+
+// Status job outbox
+const (
+	OutboxStatusPending    = "pending"    // menunggu dikirim / menunggu retry
+	OutboxStatusProcessing = "processing" // sedang dikirim worker
+	OutboxStatusSent       = "sent"       // terkirim, message_id final
+	OutboxStatusFailed     = "failed"     // menyerah setelah max attempt / error permanen
+)
+
+var ErrOutboxJobNotFound = errors.New("outbox job not found")
+
+// Struct OutboxJob sesuai field table outbox_jobs
+type OutboxJob struct {
+	ID            int64
+	InstanceID    string
+	RecipientJID  string
+	MessageType   string
+	Payload       []byte // waE2E.Message hasil proto.Marshal
+	MessageID     sql.NullString
+	Status        string
+	Attempts      int
+	MaxAttempts   int
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SentAt        sql.NullTime
+}
+
+type OutboxJobResp struct {
+	JobID         int64      `json:"jobId"`
+	InstanceID    string     `json:"instanceId"`
+	To            string     `json:"to"`
+	MessageType   string     `json:"messageType"`
+	MessageID     string     `json:"messageId,omitempty"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	MaxAttempts   int        `json:"maxAttempts"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+}
+
+const outboxJobColumns = `
+            id,
+            instance_id,
+            recipient_jid,
+            message_type,
+            payload,
+            message_id,
+            status,
+            attempts,
+            max_attempts,
+            next_attempt_at,
+            last_error,
+            created_at,
+            updated_at,
+            sent_at`
+
+// InsertOutboxJob antrikan satu pesan (status pending), ID & timestamp diisi balik ke struct
+func InsertOutboxJob(j *OutboxJob) error {
+	query := `
+    INSERT INTO outbox_jobs (instance_id, recipient_jid, message_type, payload, message_id, max_attempts)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, status, next_attempt_at, created_at, updated_at`
+	return database.AppDB.QueryRow(
+		query,
+		j.InstanceID,
+		j.RecipientJID,
+		j.MessageType,
+		j.Payload,
+		j.MessageID,
+		j.MaxAttempts,
+	).Scan(&j.ID, &j.Status, &j.NextAttemptAt, &j.CreatedAt, &j.UpdatedAt)
+}
+
+// ClaimNextOutboxJob ambil satu job instance yang sudah waktunya dikirim (FIFO) dan tandai 'processing'.
+// Return sql.ErrNoRows kalau antrian instance kosong.
+func ClaimNextOutboxJob(instanceID string) (*OutboxJob, error) {
+	query := `
+        UPDATE outbox_jobs
+        SET status = 'processing', updated_at = NOW()
+        WHERE id = (
+            SELECT id FROM outbox_jobs
+            WHERE instance_id = $1 AND status = 'pending' AND next_attempt_at <= NOW()
+            ORDER BY id
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING` + outboxJobColumns
+	return scanOutboxJob(database.AppDB.QueryRow(query, instanceID))
+}
+
+// GetInstancesWithDueOutboxJobs list instance yang punya job pending dan sudah jatuh tempo
+func GetInstancesWithDueOutboxJobs() ([]string, error) {
+	rows, err := database.AppDB.Query(`
+        SELECT DISTINCT instance_id FROM outbox_jobs
+        WHERE status = 'pending' AND next_attempt_at <= NOW()
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	instanceIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		instanceIDs = append(instanceIDs, id)
+	}
+	return instanceIDs, rows.Err()
+}
+
+// ResetProcessingOutboxJobs kembalikan job yang tertinggal 'processing' (mis. server mati saat kirim) ke antrian
+func ResetProcessingOutboxJobs() error {
+	_, err := database.AppDB.Exec(`UPDATE outbox_jobs SET status = 'pending', updated_at = NOW() WHERE status = 'processing'`)
+	return err
+}
+
+// SetOutboxJobMessageID simpan message id sebelum attempt pertama supaya retry memakai ID yang sama
+func SetOutboxJobMessageID(id int64, messageID string) error {
+	_, err := database.AppDB.Exec(`UPDATE outbox_jobs SET message_id = $1, updated_at = NOW() WHERE id = $2`, messageID, id)
+	return err
+}
+
+// MarkOutboxJobSent update hasil attempt yang berhasil
+func MarkOutboxJobSent(id int64, messageID string, sentAt time.Time) error {
+	query := `
+        UPDATE outbox_jobs
+        SET status = 'sent', attempts = attempts + 1, message_id = $1,
+            last_error = NULL, sent_at = $2, updated_at = NOW()
+        WHERE id = $3
+    `
+	_, err := database.AppDB.Exec(query, messageID, sentAt, id)
+	return err
+}
+
+// MarkOutboxJobFailed update hasil attempt yang gagal.
+// nextAttemptAt nil artinya sudah menyerah (status failed).
+func MarkOutboxJobFailed(id int64, lastError string, nextAttemptAt *time.Time) error {
+	status := OutboxStatusFailed
+	next := time.Now()
+	if nextAttemptAt != nil {
+		status = OutboxStatusPending
+		next = *nextAttemptAt
+	}
+
+	query := `
+        UPDATE outbox_jobs
+        SET status = $1, attempts = attempts + 1, last_error = $2,
+            next_attempt_at = $3, updated_at = NOW()
+        WHERE id = $4
+    `
+	_, err := database.AppDB.Exec(query, status, lastError, next, id)
+	return err
+}
+
+// DeferOutboxJob kembalikan job ke antrian tanpa menambah attempts (mis. kena rate limit instance)
+func DeferOutboxJob(id int64, reason string, nextAttemptAt time.Time) error {
+	query := `
+        UPDATE outbox_jobs
+        SET status = 'pending', last_error = $1, next_attempt_at = $2, updated_at = NOW()
+        WHERE id = $3
+    `
+	_, err := database.AppDB.Exec(query, reason, nextAttemptAt, id)
+	return err
+}
+
+func GetOutboxJob(instanceID string, id int64) (*OutboxJob, error) {
+	query := `SELECT` + outboxJobColumns + `
+        FROM outbox_jobs
+        WHERE id = $1 AND instance_id = $2
+    `
+	j, err := scanOutboxJob(database.AppDB.QueryRow(query, id, instanceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOutboxJobNotFound
+	}
+	return j, err
+}
+
+// GetOutboxJobs list job sebuah instance (terbaru dulu), status opsional
+func GetOutboxJobs(instanceID, status string, limit, offset int) ([]OutboxJob, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM outbox_jobs WHERE instance_id = $1 AND ($2 = '' OR status = $2)`
+	if err := database.AppDB.QueryRow(countQuery, instanceID, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT` + outboxJobColumns + `
+        FROM outbox_jobs
+        WHERE instance_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY id DESC
+        LIMIT $3 OFFSET $4
+    `
+	rows, err := database.AppDB.Query(query, instanceID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := make([]OutboxJob, 0)
+	for rows.Next() {
+		j, err := scanOutboxJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, total, rows.Err()
+}
+
+func scanOutboxJob(row rowScanner) (*OutboxJob, error) {
+	j := &OutboxJob{}
+	err := row.Scan(
+		&j.ID,
+		&j.InstanceID,
+		&j.RecipientJID,
+		&j.MessageType,
+		&j.Payload,
+		&j.MessageID,
+		&j.Status,
+		&j.Attempts,
+		&j.MaxAttempts,
+		&j.NextAttemptAt,
+		&j.LastError,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+		&j.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func ToOutboxJobResponse(j OutboxJob) OutboxJobResp {
+	resp := OutboxJobResp{
+		JobID:       j.ID,
+		InstanceID:  j.InstanceID,
+		To:          j.RecipientJID,
+		MessageType: j.MessageType,
+		MessageID:   j.MessageID.String,
+		Status:      j.Status,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		LastError:   j.LastError.String,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+		SentAt:      timePtr(j.SentAt),
+	}
+	if j.Status == OutboxStatusPending {
+		next := j.NextAttemptAt
+		resp.NextAttemptAt = &next
+	}
+	return resp
+}
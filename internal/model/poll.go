@@ -0,0 +1,172 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrPollNotFound = errors.New("poll not found")
+
+// Struct Poll sesuai field table polls (polling yang dikirim / diterima instance)
+type Poll struct {
+	ID              int64
+	InstanceID      string
+	MessageID       string
+	ChatJID         string
+	CreatorJID      sql.NullString
+	Question        string
+	Options         []string
+	SelectableCount int // 0 = boleh pilih lebih dari satu (tanpa batas)
+	CreatedAt       time.Time
+}
+
+// Struct PollVote sesuai field table poll_votes; pilihan kosong = vote ditarik
+type PollVote struct {
+	PollID          int64
+	VoterJID        string
+	SelectedOptions []string
+	VotedAt         time.Time
+}
+
+type PollOptionResult struct {
+	Name   string   `json:"name"`
+	Votes  int      `json:"votes"`
+	Voters []string `json:"voters"`
+}
+
+type PollVoterResp struct {
+	VoterJID        string    `json:"voterJid"`
+	SelectedOptions []string  `json:"selectedOptions"`
+	VotedAt         time.Time `json:"votedAt"`
+}
+
+type PollResultsResp struct {
+	InstanceID      string             `json:"instanceId"`
+	MessageID       string             `json:"messageId"`
+	ChatJID         string             `json:"chatJid"`
+	CreatorJID      string             `json:"creatorJid,omitempty"`
+	Question        string             `json:"question"`
+	SelectableCount int                `json:"selectableCount"`
+	MultiSelect     bool               `json:"multiSelect"`
+	TotalVoters     int                `json:"totalVoters"`
+	Options         []PollOptionResult `json:"options"`
+	Voters          []PollVoterResp    `json:"voters"`
+	CreatedAt       time.Time          `json:"createdAt"`
+}
+
+// InsertPoll simpan polling, abaikan kalau message_id sudah ada (mis. event pesan dari device sendiri)
+func InsertPoll(p *Poll) error {
+	_, err := database.AppDB.Exec(`
+        INSERT INTO polls (instance_id, message_id, chat_jid, creator_jid, question, options, selectable_count)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (instance_id, message_id) DO NOTHING
+    `, p.InstanceID, p.MessageID, p.ChatJID, p.CreatorJID, p.Question, pq.Array(p.Options), p.SelectableCount)
+	return err
+}
+
+func GetPollByMessageID(instanceID, messageID string) (*Poll, error) {
+	p := &Poll{}
+	err := database.AppDB.QueryRow(`
+        SELECT id, instance_id, message_id, chat_jid, creator_jid, question, options, selectable_count, created_at
+        FROM polls
+        WHERE instance_id = $1 AND message_id = $2
+    `, instanceID, messageID).Scan(
+		&p.ID,
+		&p.InstanceID,
+		&p.MessageID,
+		&p.ChatJID,
+		&p.CreatorJID,
+		&p.Question,
+		pq.Array(&p.Options),
+		&p.SelectableCount,
+		&p.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPollNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpsertPollVote simpan pilihan terbaru voter; vote yang datang terlambat (lebih lama) diabaikan
+func UpsertPollVote(v PollVote) error {
+	_, err := database.AppDB.Exec(`
+        INSERT INTO poll_votes (poll_id, voter_jid, selected_options, voted_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (poll_id, voter_jid) DO UPDATE
+        SET selected_options = EXCLUDED.selected_options, voted_at = EXCLUDED.voted_at
+        WHERE poll_votes.voted_at <= EXCLUDED.voted_at
+    `, v.PollID, v.VoterJID, pq.Array(v.SelectedOptions), v.VotedAt)
+	return err
+}
+
+func GetPollVotes(pollID int64) ([]PollVote, error) {
+	rows, err := database.AppDB.Query(`
+        SELECT poll_id, voter_jid, selected_options, voted_at
+        FROM poll_votes
+        WHERE poll_id = $1
+        ORDER BY voted_at
+    `, pollID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := make([]PollVote, 0)
+	for rows.Next() {
+		var v PollVote
+		if err := rows.Scan(&v.PollID, &v.VoterJID, pq.Array(&v.SelectedOptions), &v.VotedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, v)
+	}
+	return votes, rows.Err()
+}
+
+// ToPollResults agregasi vote per opsi dan per voter (voter yang menarik vote tidak dihitung)
+func ToPollResults(p Poll, votes []PollVote) PollResultsResp {
+	options := make([]PollOptionResult, len(p.Options))
+	index := make(map[string]int, len(p.Options))
+	for i, name := range p.Options {
+		options[i] = PollOptionResult{Name: name, Voters: make([]string, 0)}
+		index[name] = i
+	}
+
+	voters := make([]PollVoterResp, 0, len(votes))
+	for _, v := range votes {
+		if len(v.SelectedOptions) == 0 {
+			continue
+		}
+		for _, name := range v.SelectedOptions {
+			if i, ok := index[name]; ok {
+				options[i].Votes++
+				options[i].Voters = append(options[i].Voters, v.VoterJID)
+			}
+		}
+		voters = append(voters, PollVoterResp{
+			VoterJID:        v.VoterJID,
+			SelectedOptions: v.SelectedOptions,
+			VotedAt:         v.VotedAt,
+		})
+	}
+
+	return PollResultsResp{
+		InstanceID:      p.InstanceID,
+		MessageID:       p.MessageID,
+		ChatJID:         p.ChatJID,
+		CreatorJID:      p.CreatorJID.String,
+		Question:        p.Question,
+		SelectableCount: p.SelectableCount,
+		MultiSelect:     p.SelectableCount != 1,
+		TotalVoters:     len(voters),
+		Options:         options,
+		Voters:          voters,
+		CreatedAt:       p.CreatedAt,
+	}
+}
@@ -0,0 +1,282 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Strategi pemilihan instance pengirim dalam pool
+const (
+	PoolStrategyRoundRobin = "round_robin"
+	PoolStrategyLRU        = "lru"    // instance yang paling lama tidak dipakai
+	PoolStrategySticky     = "sticky" // penerima yang sama selalu dari instance yang sama (selama sehat)
+)
+
+var (
+	ErrSenderPoolNotFound  = errors.New("sender pool not found")
+	ErrSenderPoolNameTaken = errors.New("sender pool name already exists")
+)
+
+// Struct SenderPool sesuai field table sender_pools
+type SenderPool struct {
+	ID        int64
+	OwnerID   sql.NullInt64
+	Name      string
+	Strategy  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Struct SenderPoolMember sesuai field table sender_pool_members
+type SenderPoolMember struct {
+	PoolID     int64
+	InstanceID string
+	Position   int
+	LastUsedAt sql.NullTime
+}
+
+type SenderPoolMemberResp struct {
+	InstanceID  string     `json:"instanceId"`
+	PhoneNumber string     `json:"phoneNumber,omitempty"`
+	IsConnected bool       `json:"isConnected"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type SenderPoolResp struct {
+	ID        int64                  `json:"id"`
+	OwnerID   int64                  `json:"ownerId,omitempty"`
+	Name      string                 `json:"name"`
+	Strategy  string                 `json:"strategy"`
+	Members   []SenderPoolMemberResp `json:"members"`
+	CreatedAt time.Time              `json:"createdAt"`
+	UpdatedAt time.Time              `json:"updatedAt"`
+}
+
+const senderPoolColumns = `
+            id,
+            owner_id,
+            name,
+            strategy,
+            created_at,
+            updated_at`
+
+// InsertSenderPool simpan pool + anggota (urutan instanceIDs = urutan round-robin)
+func InsertSenderPool(p *SenderPool, instanceIDs []string) error {
+	tx, err := database.AppDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+        INSERT INTO sender_pools (owner_id, name, strategy)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at, updated_at
+    `, p.OwnerID, p.Name, p.Strategy).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return senderPoolUniqueError(err)
+	}
+
+	if err := replaceSenderPoolMembers(tx, p.ID, instanceIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateSenderPool ubah strategi; kalau instanceIDs != nil anggota pool diganti
+func UpdateSenderPool(p *SenderPool, instanceIDs []string) error {
+	tx, err := database.AppDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+        UPDATE sender_pools SET strategy = $1, updated_at = NOW()
+        WHERE id = $2
+        RETURNING updated_at
+    `, p.Strategy, p.ID).Scan(&p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSenderPoolNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if instanceIDs != nil {
+		if err := replaceSenderPoolMembers(tx, p.ID, instanceIDs); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// replaceSenderPoolMembers ganti anggota pool; last_used_at anggota lama dipertahankan
+func replaceSenderPoolMembers(tx *sql.Tx, poolID int64, instanceIDs []string) error {
+	_, err := tx.Exec(`DELETE FROM sender_pool_members WHERE pool_id = $1 AND NOT (instance_id = ANY($2))`,
+		poolID, pq.Array(instanceIDs))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO sender_pool_members (pool_id, instance_id, position)
+        SELECT $1, t.instance_id, t.position
+        FROM unnest($2::text[]) WITH ORDINALITY AS t(instance_id, position)
+        ON CONFLICT (pool_id, instance_id) DO UPDATE SET position = EXCLUDED.position
+    `, poolID, pq.Array(instanceIDs))
+	return err
+}
+
+func DeleteSenderPool(id int64) error {
+	res, err := database.AppDB.Exec(`DELETE FROM sender_pools WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSenderPoolNotFound
+	}
+	return nil
+}
+
+// GetSenderPoolByName ambil pool milik owner berdasarkan nama (nama hanya unik per owner)
+func GetSenderPoolByName(name string, ownerID sql.NullInt64) (*SenderPool, error) {
+	query := `SELECT` + senderPoolColumns + `
+        FROM sender_pools
+        WHERE name = $1 AND owner_id IS NOT DISTINCT FROM $2
+        ORDER BY id
+        LIMIT 1
+    `
+	p, err := scanSenderPool(database.AppDB.QueryRow(query, name, ownerID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSenderPoolNotFound
+	}
+	return p, err
+}
+
+func GetSenderPools(ownerID int64) ([]SenderPool, error) {
+	query := `SELECT` + senderPoolColumns + `
+        FROM sender_pools
+        WHERE ($1 = 0 OR owner_id = $1)
+        ORDER BY name, id
+    `
+	rows, err := database.AppDB.Query(query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pools := make([]SenderPool, 0)
+	for rows.Next() {
+		p, err := scanSenderPool(rows)
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, *p)
+	}
+	return pools, rows.Err()
+}
+
+// GetSenderPoolMembers anggota pool urut posisi
+func GetSenderPoolMembers(poolID int64) ([]SenderPoolMember, error) {
+	rows, err := database.AppDB.Query(`
+        SELECT pool_id, instance_id, position, last_used_at
+        FROM sender_pool_members
+        WHERE pool_id = $1
+        ORDER BY position
+    `, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]SenderPoolMember, 0)
+	for rows.Next() {
+		var m SenderPoolMember
+		if err := rows.Scan(&m.PoolID, &m.InstanceID, &m.Position, &m.LastUsedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// TouchSenderPoolMember catat waktu terakhir instance dipakai (strategi lru)
+func TouchSenderPoolMember(poolID int64, instanceID string) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE sender_pool_members SET last_used_at = NOW()
+        WHERE pool_id = $1 AND instance_id = $2
+    `, poolID, instanceID)
+	return err
+}
+
+// GetSenderPoolSticky instance yang terakhir mengirim ke penerima ini lewat pool (strategi sticky)
+func GetSenderPoolSticky(poolID int64, recipient string) (string, error) {
+	var instanceID string
+	err := database.AppDB.QueryRow(`
+        SELECT instance_id FROM sender_pool_sticky
+        WHERE pool_id = $1 AND recipient = $2
+    `, poolID, recipient).Scan(&instanceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return instanceID, err
+}
+
+func SetSenderPoolSticky(poolID int64, recipient, instanceID string) error {
+	_, err := database.AppDB.Exec(`
+        INSERT INTO sender_pool_sticky (pool_id, recipient, instance_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (pool_id, recipient) DO UPDATE SET instance_id = EXCLUDED.instance_id, updated_at = NOW()
+    `, poolID, recipient, instanceID)
+	return err
+}
+
+func senderPoolUniqueError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation (owner_id, name)
+		return ErrSenderPoolNameTaken
+	}
+	return err
+}
+
+func scanSenderPool(row rowScanner) (*SenderPool, error) {
+	p := &SenderPool{}
+	err := row.Scan(
+		&p.ID,
+		&p.OwnerID,
+		&p.Name,
+		&p.Strategy,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func ToSenderPoolResponse(p SenderPool, members []SenderPoolMemberResp) SenderPoolResp {
+	return SenderPoolResp{
+		ID:        p.ID,
+		OwnerID:   p.OwnerID.Int64,
+		Name:      p.Name,
+		Strategy:  p.Strategy,
+		Members:   members,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+func ToSenderPoolMemberResponse(m SenderPoolMember, phoneNumber string, isConnected bool) SenderPoolMemberResp {
+	return SenderPoolMemberResp{
+		InstanceID:  m.InstanceID,
+		PhoneNumber: phoneNumber,
+		IsConnected: isConnected,
+		LastUsedAt:  timePtr(m.LastUsedAt),
+	}
+}
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gowa-yourself/database"
+	"time"
+)
+
+// Status pengiriman pesan outgoing, urut dari paling awal
+const (
+	MessageStatusPending   = "pending"    // row sudah disimpan, SendMessage belum selesai
+	MessageStatusServerAck = "server_ack" // diterima server WhatsApp (SendMessage sukses)
+	MessageStatusDelivered = "delivered"  // sampai di device penerima
+	MessageStatusRead      = "read"       // dibuka penerima
+	MessageStatusPlayed    = "played"     // voice note / view-once diputar
+)
+
+var ErrMessageNotFound = errors.New("message not found")
+
+// statusRankSQL urutan status supaya receipt yang datang terlambat tidak menurunkan status
+const statusRankSQL = `CASE %s
+        WHEN 'server_ack' THEN 1
+        WHEN 'delivered' THEN 2
+        WHEN 'read' THEN 3
+        WHEN 'played' THEN 4
+        ELSE 0 END`
+
+// Struct MessageReceipt sesuai field table message_receipts (per penerima, penting untuk group)
+type MessageReceipt struct {
+	RecipientJID string
+	Status       string
+	UpdatedAt    time.Time
+}
+
+type MessageReceiptResp struct {
+	RecipientJID string    `json:"recipientJid"`
+	Status       string    `json:"status"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type MessageStatusResp struct {
+	MessageID       string               `json:"messageId"`
+	ChatJID         string               `json:"chatJid"`
+	Direction       string               `json:"direction"`
+	Status          string               `json:"status"`
+	SentAt          time.Time            `json:"sentAt"`
+	StatusUpdatedAt *time.Time           `json:"statusUpdatedAt,omitempty"`
+	DeliveredAt     *time.Time           `json:"deliveredAt,omitempty"`
+	ReadAt          *time.Time           `json:"readAt,omitempty"`
+	PlayedAt        *time.Time           `json:"playedAt,omitempty"`
+	Receipts        []MessageReceiptResp `json:"receipts"`
+}
+
+// ApplyMessageReceipt simpan receipt dari satu penerima dan naikkan status pesan kalau lebih tinggi.
+// Return changed=true kalau status level pesan berubah (untuk event MESSAGE_STATUS_CHANGED).
+func ApplyMessageReceipt(instanceID, messageID, recipientJID, status string, ts time.Time) (bool, error) {
+	receiptQuery := `
+        INSERT INTO message_receipts (instance_id, message_id, recipient_jid, status, updated_at)
+        SELECT $1, $2, $3, $4, $5
+        WHERE EXISTS (
+            SELECT 1 FROM messages
+            WHERE instance_id = $1 AND message_id = $2 AND direction = 'outgoing'
+        )
+        ON CONFLICT (instance_id, message_id, recipient_jid) DO UPDATE
+        SET status = EXCLUDED.status, updated_at = EXCLUDED.updated_at
+        WHERE ` + sprintfRank("message_receipts.status") + ` < ` + sprintfRank("EXCLUDED.status")
+	if _, err := database.AppDB.Exec(receiptQuery, instanceID, messageID, recipientJID, status, ts); err != nil {
+		return false, err
+	}
+
+	messageQuery := `
+        UPDATE messages
+        SET
+            status = $3,
+            status_updated_at = $4,
+            delivered_at = CASE WHEN $5 >= 2 THEN COALESCE(delivered_at, $4) ELSE delivered_at END,
+            read_at = CASE WHEN $5 >= 3 THEN COALESCE(read_at, $4) ELSE read_at END,
+            played_at = CASE WHEN $5 >= 4 THEN COALESCE(played_at, $4) ELSE played_at END
+        WHERE instance_id = $1
+          AND message_id = $2
+          AND direction = 'outgoing'
+          AND ` + sprintfRank("status") + ` < $5`
+	res, err := database.AppDB.Exec(messageQuery, instanceID, messageID, status, ts, MessageStatusRank(status))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// MarkMessageServerAck update pesan pending setelah SendMessage sukses (timestamp dari server).
+// Status hanya diubah kalau masih pending, receipt yang datang lebih dulu tidak diturunkan.
+func MarkMessageServerAck(instanceID, messageID string, ts time.Time) error {
+	_, err := database.AppDB.Exec(`
+        UPDATE messages
+        SET
+            timestamp = $3,
+            status = CASE WHEN status = $4 THEN $5 ELSE status END,
+            status_updated_at = CASE WHEN status = $4 THEN $3 ELSE status_updated_at END
+        WHERE instance_id = $1 AND message_id = $2 AND direction = 'outgoing'
+    `, instanceID, messageID, ts, MessageStatusPending, MessageStatusServerAck)
+	return err
+}
+
+// DeletePendingMessage hapus pesan outgoing yang gagal dikirim (masih pending, belum ada receipt)
+func DeletePendingMessage(instanceID, messageID string) error {
+	_, err := database.AppDB.Exec(`
+        DELETE FROM messages
+        WHERE instance_id = $1 AND message_id = $2 AND direction = 'outgoing' AND status = $3
+    `, instanceID, messageID, MessageStatusPending)
+	return err
+}
+
+// MessageStatusRank versi Go dari statusRankSQL
+func MessageStatusRank(status string) int {
+	switch status {
+	case MessageStatusServerAck:
+		return 1
+	case MessageStatusDelivered:
+		return 2
+	case MessageStatusRead:
+		return 3
+	case MessageStatusPlayed:
+		return 4
+	}
+	return 0
+}
+
+func GetMessageReceipts(instanceID, messageID string) ([]MessageReceipt, error) {
+	rows, err := database.AppDB.Query(`
+        SELECT recipient_jid, status, updated_at
+        FROM message_receipts
+        WHERE instance_id = $1 AND message_id = $2
+        ORDER BY updated_at
+    `, instanceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := make([]MessageReceipt, 0)
+	for rows.Next() {
+		var r MessageReceipt
+		if err := rows.Scan(&r.RecipientJID, &r.Status, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, rows.Err()
+}
+
+// GetMessageStatus gabungkan status pesan + receipt per penerima
+func GetMessageStatus(instanceID, messageID string) (*MessageStatusResp, error) {
+	m, err := GetMessageByMessageID(instanceID, messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	receipts, err := GetMessageReceipts(instanceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &MessageStatusResp{
+		MessageID:       m.MessageID,
+		ChatJID:         m.ChatJID,
+		Direction:       m.Direction,
+		Status:          m.Status.String,
+		SentAt:          m.Timestamp,
+		StatusUpdatedAt: timePtr(m.StatusUpdatedAt),
+		DeliveredAt:     timePtr(m.DeliveredAt),
+		ReadAt:          timePtr(m.ReadAt),
+		PlayedAt:        timePtr(m.PlayedAt),
+		Receipts:        make([]MessageReceiptResp, 0, len(receipts)),
+	}
+	for _, r := range receipts {
+		resp.Receipts = append(resp.Receipts, MessageReceiptResp(r))
+	}
+	return resp, nil
+}
+
+func sprintfRank(column string) string {
+	return "(" + fmt.Sprintf(statusRankSQL, column) + ")"
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
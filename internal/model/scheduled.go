@@ -0,0 +1,244 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+)
+
+// Status pesan terjadwal
+const (
+	ScheduledStatusScheduled  = "scheduled"  // menunggu send_at
+	ScheduledStatusDispatched = "dispatched" // sudah dipindah ke outbox (lihat outbox_job_id)
+	ScheduledStatusCancelled  = "cancelled"
+)
+
+var (
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	ErrScheduledMessageLocked   = errors.New("scheduled message is no longer pending")
+)
+
+// Struct ScheduledMessage sesuai field table scheduled_messages
+type ScheduledMessage struct {
+	ID           int64
+	InstanceID   string
+	RecipientJID string
+	MessageType  string
+	Payload      []byte // waE2E.Message hasil proto.Marshal
+	MessageID    sql.NullString
+	SendAt       time.Time
+	Status       string
+	OutboxJobID  sql.NullInt64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DispatchedAt sql.NullTime
+}
+
+type ScheduledMessageResp struct {
+	ScheduleID   int64      `json:"scheduleId"`
+	InstanceID   string     `json:"instanceId"`
+	To           string     `json:"to"`
+	MessageType  string     `json:"messageType"`
+	MessageID    string     `json:"messageId,omitempty"`
+	SendAt       time.Time  `json:"sendAt"`
+	Status       string     `json:"status"`
+	OutboxJobID  int64      `json:"outboxJobId,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	DispatchedAt *time.Time `json:"dispatchedAt,omitempty"`
+}
+
+const scheduledMessageColumns = `
+            id,
+            instance_id,
+            recipient_jid,
+            message_type,
+            payload,
+            message_id,
+            send_at,
+            status,
+            outbox_job_id,
+            created_at,
+            updated_at,
+            dispatched_at`
+
+// InsertScheduledMessage simpan pesan terjadwal baru, ID & timestamp diisi balik ke struct
+func InsertScheduledMessage(s *ScheduledMessage) error {
+	query := `
+    INSERT INTO scheduled_messages (instance_id, recipient_jid, message_type, payload, message_id, send_at)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, status, created_at, updated_at`
+	return database.AppDB.QueryRow(
+		query,
+		s.InstanceID,
+		s.RecipientJID,
+		s.MessageType,
+		s.Payload,
+		s.MessageID,
+		s.SendAt,
+	).Scan(&s.ID, &s.Status, &s.CreatedAt, &s.UpdatedAt)
+}
+
+// DispatchDueScheduledMessages pindahkan pesan yang sudah jatuh tempo ke outbox_jobs dalam satu statement
+// (insert job + update status) supaya tidak ada pesan dobel / hilang saat server mati di tengah jalan.
+// Return instance yang mendapat job baru.
+func DispatchDueScheduledMessages(limit, maxAttempts int) ([]string, error) {
+	query := `
+        WITH due AS (
+            SELECT id FROM scheduled_messages
+            WHERE status = 'scheduled' AND send_at <= NOW()
+            ORDER BY send_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        ), jobs AS (
+            INSERT INTO outbox_jobs (instance_id, recipient_jid, message_type, payload, message_id, max_attempts, scheduled_message_id)
+            SELECT s.instance_id, s.recipient_jid, s.message_type, s.payload, s.message_id, $2, s.id
+            FROM scheduled_messages s
+            JOIN due ON due.id = s.id
+            ORDER BY s.send_at, s.id
+            RETURNING id, scheduled_message_id
+        )
+        UPDATE scheduled_messages s
+        SET status = 'dispatched', outbox_job_id = jobs.id, dispatched_at = NOW(), updated_at = NOW()
+        FROM jobs
+        WHERE s.id = jobs.scheduled_message_id
+        RETURNING s.instance_id`
+
+	rows, err := database.AppDB.Query(query, limit, maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	instanceIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			instanceIDs = append(instanceIDs, id)
+		}
+	}
+	return instanceIDs, rows.Err()
+}
+
+// RescheduleMessage ubah send_at, hanya untuk pesan yang masih 'scheduled'
+func RescheduleMessage(instanceID string, id int64, sendAt time.Time) (*ScheduledMessage, error) {
+	query := `
+        UPDATE scheduled_messages
+        SET send_at = $1, updated_at = NOW()
+        WHERE id = $2 AND instance_id = $3 AND status = 'scheduled'
+        RETURNING` + scheduledMessageColumns
+	s, err := scanScheduledMessage(database.AppDB.QueryRow(query, sendAt, id, instanceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, scheduledMessageMissing(instanceID, id)
+	}
+	return s, err
+}
+
+// CancelScheduledMessage batalkan pesan yang masih 'scheduled'
+func CancelScheduledMessage(instanceID string, id int64) (*ScheduledMessage, error) {
+	query := `
+        UPDATE scheduled_messages
+        SET status = 'cancelled', updated_at = NOW()
+        WHERE id = $1 AND instance_id = $2 AND status = 'scheduled'
+        RETURNING` + scheduledMessageColumns
+	s, err := scanScheduledMessage(database.AppDB.QueryRow(query, id, instanceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, scheduledMessageMissing(instanceID, id)
+	}
+	return s, err
+}
+
+// scheduledMessageMissing bedakan "tidak ada" dengan "sudah dispatched / cancelled"
+func scheduledMessageMissing(instanceID string, id int64) error {
+	if _, err := GetScheduledMessage(instanceID, id); err != nil {
+		return err
+	}
+	return ErrScheduledMessageLocked
+}
+
+func GetScheduledMessage(instanceID string, id int64) (*ScheduledMessage, error) {
+	query := `SELECT` + scheduledMessageColumns + `
+        FROM scheduled_messages
+        WHERE id = $1 AND instance_id = $2
+    `
+	s, err := scanScheduledMessage(database.AppDB.QueryRow(query, id, instanceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduledMessageNotFound
+	}
+	return s, err
+}
+
+// GetScheduledMessages list pesan terjadwal sebuah instance (send_at terdekat dulu), status opsional
+func GetScheduledMessages(instanceID, status string, limit, offset int) ([]ScheduledMessage, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM scheduled_messages WHERE instance_id = $1 AND ($2 = '' OR status = $2)`
+	if err := database.AppDB.QueryRow(countQuery, instanceID, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT` + scheduledMessageColumns + `
+        FROM scheduled_messages
+        WHERE instance_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY send_at, id
+        LIMIT $3 OFFSET $4
+    `
+	rows, err := database.AppDB.Query(query, instanceID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := make([]ScheduledMessage, 0)
+	for rows.Next() {
+		s, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, *s)
+	}
+	return list, total, rows.Err()
+}
+
+func scanScheduledMessage(row rowScanner) (*ScheduledMessage, error) {
+	s := &ScheduledMessage{}
+	err := row.Scan(
+		&s.ID,
+		&s.InstanceID,
+		&s.RecipientJID,
+		&s.MessageType,
+		&s.Payload,
+		&s.MessageID,
+		&s.SendAt,
+		&s.Status,
+		&s.OutboxJobID,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+		&s.DispatchedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func ToScheduledMessageResponse(s ScheduledMessage) ScheduledMessageResp {
+	return ScheduledMessageResp{
+		ScheduleID:   s.ID,
+		InstanceID:   s.InstanceID,
+		To:           s.RecipientJID,
+		MessageType:  s.MessageType,
+		MessageID:    s.MessageID.String,
+		SendAt:       s.SendAt,
+		Status:       s.Status,
+		OutboxJobID:  s.OutboxJobID.Int64,
+		CreatedAt:    s.CreatedAt,
+		UpdatedAt:    s.UpdatedAt,
+		DispatchedAt: timePtr(s.DispatchedAt),
+	}
+}
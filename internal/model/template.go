@@ -0,0 +1,181 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Struct Template sesuai field table templates
+type Template struct {
+	ID        int64
+	OwnerID   sql.NullInt64
+	Name      string
+	Body      string   // teks pesan / caption media, placeholder {{nama}}
+	Variables []string // hasil parse placeholder di body (semua wajib diisi saat kirim)
+	MediaURL  sql.NullString
+	MediaType sql.NullString
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type TemplateResp struct {
+	ID        int64     `json:"id"`
+	OwnerID   int64     `json:"ownerId,omitempty"`
+	Name      string    `json:"name"`
+	Body      string    `json:"body"`
+	Variables []string  `json:"variables"`
+	MediaURL  string    `json:"mediaUrl,omitempty"`
+	MediaType string    `json:"mediaType,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+var (
+	ErrTemplateNotFound  = errors.New("template not found")
+	ErrTemplateNameTaken = errors.New("template name already exists")
+)
+
+const templateColumns = `
+            id,
+            owner_id,
+            name,
+            body,
+            variables,
+            media_url,
+            media_type,
+            created_at,
+            updated_at`
+
+// InsertTemplate simpan template baru, ID & timestamp diisi balik ke struct
+func InsertTemplate(t *Template) error {
+	query := `
+    INSERT INTO templates (owner_id, name, body, variables, media_url, media_type)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    RETURNING id, created_at, updated_at`
+	err := database.AppDB.QueryRow(
+		query,
+		t.OwnerID,
+		t.Name,
+		t.Body,
+		pq.Array(t.Variables),
+		t.MediaURL,
+		t.MediaType,
+	).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	return templateUniqueError(err)
+}
+
+// UpdateTemplate update isi template milik owner (ownerID 0 = admin, semua template)
+func UpdateTemplate(t *Template, ownerID int64) error {
+	query := `
+        UPDATE templates
+        SET name = $1, body = $2, variables = $3, media_url = $4, media_type = $5, updated_at = NOW()
+        WHERE id = $6 AND ($7 = 0 OR owner_id = $7)
+        RETURNING updated_at
+    `
+	err := database.AppDB.QueryRow(
+		query,
+		t.Name,
+		t.Body,
+		pq.Array(t.Variables),
+		t.MediaURL,
+		t.MediaType,
+		t.ID,
+		ownerID,
+	).Scan(&t.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTemplateNotFound
+	}
+	return templateUniqueError(err)
+}
+
+func DeleteTemplate(id, ownerID int64) error {
+	res, err := database.AppDB.Exec(`DELETE FROM templates WHERE id = $1 AND ($2 = 0 OR owner_id = $2)`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// GetTemplate ambil template milik owner (ownerID 0 = admin, semua template)
+func GetTemplate(id, ownerID int64) (*Template, error) {
+	query := `SELECT` + templateColumns + `
+        FROM templates
+        WHERE id = $1 AND ($2 = 0 OR owner_id = $2)
+    `
+	t, err := scanTemplate(database.AppDB.QueryRow(query, id, ownerID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTemplateNotFound
+	}
+	return t, err
+}
+
+func GetTemplates(ownerID int64) ([]Template, error) {
+	query := `SELECT` + templateColumns + `
+        FROM templates
+        WHERE ($1 = 0 OR owner_id = $1)
+        ORDER BY name, id
+    `
+	rows, err := database.AppDB.Query(query, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := make([]Template, 0)
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *t)
+	}
+	return templates, rows.Err()
+}
+
+func templateUniqueError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation (owner_id, name)
+		return ErrTemplateNameTaken
+	}
+	return err
+}
+
+func scanTemplate(row rowScanner) (*Template, error) {
+	t := &Template{}
+	err := row.Scan(
+		&t.ID,
+		&t.OwnerID,
+		&t.Name,
+		&t.Body,
+		pq.Array(&t.Variables),
+		&t.MediaURL,
+		&t.MediaType,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func ToTemplateResponse(t Template) TemplateResp {
+	return TemplateResp{
+		ID:        t.ID,
+		OwnerID:   t.OwnerID.Int64,
+		Name:      t.Name,
+		Body:      t.Body,
+		Variables: t.Variables,
+		MediaURL:  t.MediaURL.String,
+		MediaType: t.MediaType.String,
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
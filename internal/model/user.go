@@ -0,0 +1,227 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Struct User sesuai field table users
+type User struct {
+	ID           int64
+	Username     string
+	PasswordHash string
+	Role         string
+	IsActive     bool
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	LastLoginAt  sql.NullTime
+}
+
+type UserResp struct {
+	ID          int64      `json:"id"`
+	Username    string     `json:"username"`
+	Role        string     `json:"role"`
+	IsActive    bool       `json:"isActive"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+// Struct RefreshToken sesuai field table refresh_tokens (token asli tidak disimpan, hanya hash)
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+	CreatedAt time.Time
+}
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrUsernameTaken       = errors.New("username already exists")
+	ErrRefreshTokenInvalid = errors.New("refresh token invalid or expired")
+)
+
+const userColumns = `
+            id,
+            username,
+            password_hash,
+            role,
+            is_active,
+            created_at,
+            updated_at,
+            last_login_at`
+
+// InsertUser simpan user baru, ID & timestamp diisi balik ke struct
+func InsertUser(u *User) error {
+	query := `
+    INSERT INTO users (username, password_hash, role, is_active)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id, created_at, updated_at`
+	err := database.AppDB.QueryRow(query, u.Username, u.PasswordHash, u.Role, u.IsActive).
+		Scan(&u.ID, &u.CreatedAt, &u.UpdatedAt)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+func GetUserByUsername(username string) (*User, error) {
+	query := `SELECT` + userColumns + `
+        FROM users
+        WHERE username = $1
+    `
+	return scanUser(database.AppDB.QueryRow(query, username))
+}
+
+func GetUserByID(id int64) (*User, error) {
+	query := `SELECT` + userColumns + `
+        FROM users
+        WHERE id = $1
+    `
+	return scanUser(database.AppDB.QueryRow(query, id))
+}
+
+func GetAllUsers() ([]User, error) {
+	query := `SELECT` + userColumns + `
+        FROM users
+        ORDER BY id
+    `
+	rows, err := database.AppDB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]User, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	return users, rows.Err()
+}
+
+func CountUsers() (int, error) {
+	var total int
+	err := database.AppDB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&total)
+	return total, err
+}
+
+func UpdateUserPassword(id int64, passwordHash string) error {
+	res, err := database.AppDB.Exec(
+		`UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2`,
+		passwordHash, id,
+	)
+	return userAffected(res, err)
+}
+
+func UpdateUserActive(id int64, isActive bool) error {
+	res, err := database.AppDB.Exec(
+		`UPDATE users SET is_active = $1, updated_at = NOW() WHERE id = $2`,
+		isActive, id,
+	)
+	return userAffected(res, err)
+}
+
+func UpdateUserLastLogin(id int64) error {
+	_, err := database.AppDB.Exec(`UPDATE users SET last_login_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func userAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	u := &User{}
+	err := row.Scan(
+		&u.ID,
+		&u.Username,
+		&u.PasswordHash,
+		&u.Role,
+		&u.IsActive,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		&u.LastLoginAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func ToUserResponse(u User) UserResp {
+	resp := UserResp{
+		ID:        u.ID,
+		Username:  u.Username,
+		Role:      u.Role,
+		IsActive:  u.IsActive,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+	if u.LastLoginAt.Valid {
+		resp.LastLoginAt = &u.LastLoginAt.Time
+	}
+	return resp
+}
+
+// InsertRefreshToken simpan hash refresh token baru
+func InsertRefreshToken(userID int64, tokenHash string, expiresAt time.Time) error {
+	_, err := database.AppDB.Exec(
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt,
+	)
+	return err
+}
+
+// ConsumeRefreshToken revoke refresh token yang masih valid dan return user_id pemiliknya.
+// Dipakai untuk rotasi: token lama langsung tidak bisa dipakai ulang.
+func ConsumeRefreshToken(tokenHash string) (int64, error) {
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW()
+        WHERE token_hash = $1
+          AND revoked_at IS NULL
+          AND expires_at > NOW()
+        RETURNING user_id
+    `
+	var userID int64
+	err := database.AppDB.QueryRow(query, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrRefreshTokenInvalid
+	}
+	return userID, err
+}
+
+// RevokeUserRefreshTokens revoke semua refresh token milik user (disable / ganti password)
+func RevokeUserRefreshTokens(userID int64) error {
+	_, err := database.AppDB.Exec(
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+		userID,
+	)
+	return err
+}
@@ -0,0 +1,413 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"gowa-yourself/database"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Struct Webhook sesuai field table webhooks
+type Webhook struct {
+	ID         int64
+	InstanceID string
+	URL        string
+	Secret     string
+	Events     []string // kosong = semua event
+	IsActive   bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type WebhookResp struct {
+	ID         int64     `json:"id"`
+	InstanceID string    `json:"instanceId"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret"` // utuh hanya di response create, selain itu di-mask
+	Events     []string  `json:"events"`
+	IsActive   bool      `json:"isActive"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// Struct WebhookDelivery sesuai field table webhook_deliveries
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int64
+	InstanceID     string
+	Event          string
+	Payload        string
+	Status         string // pending, processing, success, failed
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode sql.NullInt64
+	LastError      sql.NullString
+	ReplayOf       sql.NullInt64
+	CreatedAt      time.Time
+	DeliveredAt    sql.NullTime
+}
+
+type WebhookDeliveryResp struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhookId"`
+	InstanceID     string     `json:"instanceId"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	LastStatusCode int64      `json:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	ReplayOf       int64      `json:"replayOf,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+const webhookColumns = `
+            id,
+            instance_id,
+            url,
+            secret,
+            events,
+            is_active,
+            created_at,
+            updated_at`
+
+const webhookDeliveryColumns = `
+            id,
+            webhook_id,
+            instance_id,
+            event,
+            payload,
+            status,
+            attempts,
+            next_attempt_at,
+            last_status_code,
+            last_error,
+            replay_of,
+            created_at,
+            delivered_at`
+
+// InsertWebhook simpan subscription webhook baru, ID & timestamp diisi balik ke struct
+func InsertWebhook(w *Webhook) error {
+	query := `
+    INSERT INTO webhooks (instance_id, url, secret, events, is_active)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, created_at, updated_at`
+	return database.AppDB.QueryRow(
+		query,
+		w.InstanceID,
+		w.URL,
+		w.Secret,
+		pq.Array(w.Events),
+		w.IsActive,
+	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func UpdateWebhook(w *Webhook) error {
+	query := `
+        UPDATE webhooks
+        SET url = $1, secret = $2, events = $3, is_active = $4, updated_at = NOW()
+        WHERE id = $5 AND instance_id = $6
+        RETURNING updated_at
+    `
+	err := database.AppDB.QueryRow(
+		query,
+		w.URL,
+		w.Secret,
+		pq.Array(w.Events),
+		w.IsActive,
+		w.ID,
+		w.InstanceID,
+	).Scan(&w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+func DeleteWebhook(instanceID string, id int64) error {
+	res, err := database.AppDB.Exec(`DELETE FROM webhooks WHERE id = $1 AND instance_id = $2`, id, instanceID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func GetWebhook(instanceID string, id int64) (*Webhook, error) {
+	query := `SELECT` + webhookColumns + `
+        FROM webhooks
+        WHERE id = $1 AND instance_id = $2
+    `
+	w, err := scanWebhook(database.AppDB.QueryRow(query, id, instanceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	return w, err
+}
+
+// GetWebhooksByInstance ambil semua webhook milik instance (aktif & non-aktif)
+func GetWebhooksByInstance(instanceID string) ([]Webhook, error) {
+	query := `SELECT` + webhookColumns + `
+        FROM webhooks
+        WHERE instance_id = $1
+        ORDER BY id
+    `
+	return queryWebhooks(query, instanceID)
+}
+
+// GetActiveWebhooksForEvent ambil webhook aktif instance yang subscribe ke event tsb
+func GetActiveWebhooksForEvent(instanceID, event string) ([]Webhook, error) {
+	query := `SELECT` + webhookColumns + `
+        FROM webhooks
+        WHERE instance_id = $1
+          AND is_active = true
+          AND (cardinality(events) = 0 OR $2 = ANY(events))
+    `
+	return queryWebhooks(query, instanceID, event)
+}
+
+func queryWebhooks(query string, args ...interface{}) ([]Webhook, error) {
+	rows, err := database.AppDB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, rows.Err()
+}
+
+func scanWebhook(row rowScanner) (*Webhook, error) {
+	w := &Webhook{}
+	err := row.Scan(
+		&w.ID,
+		&w.InstanceID,
+		&w.URL,
+		&w.Secret,
+		pq.Array(&w.Events),
+		&w.IsActive,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// InsertWebhookDelivery antrikan satu pengiriman webhook (status pending)
+func InsertWebhookDelivery(d *WebhookDelivery) error {
+	query := `
+    INSERT INTO webhook_deliveries (webhook_id, instance_id, event, payload, replay_of)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, status, next_attempt_at, created_at`
+	return database.AppDB.QueryRow(
+		query,
+		d.WebhookID,
+		d.InstanceID,
+		d.Event,
+		d.Payload,
+		d.ReplayOf,
+	).Scan(&d.ID, &d.Status, &d.NextAttemptAt, &d.CreatedAt)
+}
+
+// ClaimDueWebhookDeliveries ambil delivery yang sudah waktunya dikirim dan tandai 'processing'
+// supaya tidak diambil worker lain.
+func ClaimDueWebhookDeliveries(limit int) ([]WebhookDelivery, error) {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = 'processing'
+        WHERE id IN (
+            SELECT id FROM webhook_deliveries
+            WHERE status = 'pending' AND next_attempt_at <= NOW()
+            ORDER BY next_attempt_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING` + webhookDeliveryColumns
+
+	rows, err := database.AppDB.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ResetProcessingWebhookDeliveries kembalikan delivery yang tertinggal 'processing'
+// (mis. server mati saat kirim) ke antrian.
+func ResetProcessingWebhookDeliveries() error {
+	_, err := database.AppDB.Exec(`UPDATE webhook_deliveries SET status = 'pending' WHERE status = 'processing'`)
+	return err
+}
+
+// MarkWebhookDeliverySuccess update hasil attempt yang berhasil
+func MarkWebhookDeliverySuccess(id int64, statusCode int) error {
+	query := `
+        UPDATE webhook_deliveries
+        SET status = 'success', attempts = attempts + 1, last_status_code = $1,
+            last_error = NULL, delivered_at = NOW()
+        WHERE id = $2
+    `
+	_, err := database.AppDB.Exec(query, statusCode, id)
+	return err
+}
+
+// MarkWebhookDeliveryFailed update hasil attempt yang gagal.
+// nextAttemptAt nil artinya sudah menyerah (status failed).
+func MarkWebhookDeliveryFailed(id int64, statusCode int, lastError string, nextAttemptAt *time.Time) error {
+	status := "failed"
+	next := time.Now()
+	if nextAttemptAt != nil {
+		status = "pending"
+		next = *nextAttemptAt
+	}
+
+	query := `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = attempts + 1, last_status_code = $2,
+            last_error = $3, next_attempt_at = $4
+        WHERE id = $5
+    `
+	_, err := database.AppDB.Exec(
+		query,
+		status,
+		sql.NullInt64{Int64: int64(statusCode), Valid: statusCode > 0},
+		lastError,
+		next,
+		id,
+	)
+	return err
+}
+
+func GetWebhookDelivery(webhookID, id int64) (*WebhookDelivery, error) {
+	query := `SELECT` + webhookDeliveryColumns + `
+        FROM webhook_deliveries
+        WHERE id = $1 AND webhook_id = $2
+    `
+	return scanWebhookDelivery(database.AppDB.QueryRow(query, id, webhookID))
+}
+
+// GetWebhookDeliveries list delivery sebuah webhook (terbaru dulu), status opsional
+func GetWebhookDeliveries(webhookID int64, status string, limit, offset int) ([]WebhookDelivery, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1 AND ($2 = '' OR status = $2)`
+	if err := database.AppDB.QueryRow(countQuery, webhookID, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT` + webhookDeliveryColumns + `
+        FROM webhook_deliveries
+        WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, id DESC
+        LIMIT $3 OFFSET $4
+    `
+	rows, err := database.AppDB.Query(query, webhookID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, total, rows.Err()
+}
+
+func scanWebhookDelivery(row rowScanner) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.InstanceID,
+		&d.Event,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.ReplayOf,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ToWebhookResponse response webhook dengan secret di-mask (hanya 4 karakter terakhir)
+func ToWebhookResponse(w Webhook) WebhookResp {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	return WebhookResp{
+		ID:         w.ID,
+		InstanceID: w.InstanceID,
+		URL:        w.URL,
+		Secret:     maskSecret(w.Secret),
+		Events:     events,
+		IsActive:   w.IsActive,
+		CreatedAt:  w.CreatedAt,
+		UpdatedAt:  w.UpdatedAt,
+	}
+}
+
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+func ToWebhookDeliveryResponse(d WebhookDelivery) WebhookDeliveryResp {
+	resp := WebhookDeliveryResp{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		InstanceID:     d.InstanceID,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode.Int64,
+		LastError:      d.LastError.String,
+		ReplayOf:       d.ReplayOf.Int64,
+		CreatedAt:      d.CreatedAt,
+	}
+	if d.DeliveredAt.Valid {
+		resp.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrMessageRevoked      = errors.New("message has already been revoked")
+	ErrNotOwnMessage       = errors.New("only messages sent by this instance can be edited")
+	ErrNotTextMessage      = errors.New("only text messages can be edited")
+	ErrRevokeNotPermitted  = errors.New("messages from other people can only be revoked in groups (as group admin)")
+	ErrEditWindowExpired   = fmt.Errorf("messages can only be edited within %d minutes after they are sent", int(whatsmeow.EditWindow.Minutes()))
+	ErrUnsupportedChatType = errors.New("reactions, edits and revokes are only supported for personal and group chats")
+)
+
+// ReactToMessage kirim reaksi emoji ke pesan di riwayat instance (emoji kosong = hapus reaksi)
+func ReactToMessage(ctx context.Context, instanceID, messageID, emoji string) (*model.Message, whatsmeow.SendResponse, error) {
+	session, target, chat, err := actionTarget(instanceID, messageID)
+	if err != nil {
+		return nil, whatsmeow.SendResponse{}, err
+	}
+
+	sender, err := targetSender(session, target, chat)
+	if err != nil {
+		return nil, whatsmeow.SendResponse{}, err
+	}
+
+	msg := session.Client.BuildReaction(chat, sender, target.MessageID, emoji)
+	resp, err := SendMessage(ctx, instanceID, chat, msg, nil)
+	return target, resp, err
+}
+
+// EditMessage ganti teks pesan yang dikirim instance ini, maksimal whatsmeow.EditWindow setelah terkirim.
+// Reply / mention / preview pesan asli tetap dipertahankan.
+func EditMessage(ctx context.Context, instanceID, messageID, text string) (*model.Message, whatsmeow.SendResponse, error) {
+	session, target, chat, err := actionTarget(instanceID, messageID)
+	if err != nil {
+		return nil, whatsmeow.SendResponse{}, err
+	}
+
+	switch {
+	case target.Direction != "outgoing":
+		return nil, whatsmeow.SendResponse{}, ErrNotOwnMessage
+	case target.MessageType != "text":
+		return nil, whatsmeow.SendResponse{}, ErrNotTextMessage
+	case time.Since(target.Timestamp) > whatsmeow.EditWindow:
+		return nil, whatsmeow.SendResponse{}, ErrEditWindowExpired
+	}
+
+	content := &waE2E.Message{}
+	if len(target.RawMessage) > 0 {
+		if err := proto.Unmarshal(target.RawMessage, content); err != nil {
+			return nil, whatsmeow.SendResponse{}, fmt.Errorf("failed to decode original message: %w", err)
+		}
+	}
+	if inner := content.GetDeviceSentMessage().GetMessage(); inner != nil {
+		content = inner
+	}
+	content.MessageContextInfo = nil
+	helper.SetMessageText(content, text)
+
+	resp, err := SendMessage(ctx, instanceID, chat, session.Client.BuildEdit(chat, target.MessageID, content), nil)
+	if err != nil {
+		return target, resp, err
+	}
+
+	raw, _ := proto.Marshal(content)
+	if err := model.MarkMessageEdited(instanceID, target.MessageID, text, raw); err != nil {
+		fmt.Printf("Warning: failed to update edited message %s for instance %s: %v\n", target.MessageID, instanceID, err)
+	}
+	return target, resp, nil
+}
+
+// RevokeMessage tarik pesan (delete for everyone). Pesan orang lain hanya bisa ditarik di group
+// dan instance harus admin group (kalau bukan admin, WhatsApp mengabaikan permintaan).
+func RevokeMessage(ctx context.Context, instanceID, messageID string) (*model.Message, whatsmeow.SendResponse, error) {
+	session, target, chat, err := actionTarget(instanceID, messageID)
+	if err != nil {
+		return nil, whatsmeow.SendResponse{}, err
+	}
+
+	sender := types.EmptyJID // pesan sendiri
+	if target.Direction != "outgoing" {
+		if chat.Server != types.GroupServer {
+			return nil, whatsmeow.SendResponse{}, ErrRevokeNotPermitted
+		}
+		if sender, err = targetSender(session, target, chat); err != nil {
+			return nil, whatsmeow.SendResponse{}, err
+		}
+	}
+
+	resp, err := SendMessage(ctx, instanceID, chat, session.Client.BuildRevoke(chat, sender, target.MessageID), nil)
+	if err != nil {
+		return target, resp, err
+	}
+
+	if err := model.MarkMessageRevoked(instanceID, target.MessageID); err != nil {
+		fmt.Printf("Warning: failed to mark message %s revoked for instance %s: %v\n", target.MessageID, instanceID, err)
+	}
+	return target, resp, nil
+}
+
+// actionTarget ambil session + pesan target dari riwayat instance
+func actionTarget(instanceID, messageID string) (*model.Session, *model.Message, types.JID, error) {
+	session, err := GetSession(instanceID)
+	if err != nil {
+		return nil, nil, types.JID{}, ErrInstanceNotFound
+	}
+	if session.Client.Store.ID == nil || !session.Client.IsConnected() {
+		return nil, nil, types.JID{}, ErrSessionNotConnected
+	}
+
+	target, err := model.GetMessageByMessageID(instanceID, messageID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, types.JID{}, model.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, nil, types.JID{}, err
+	}
+	if target.RevokedAt.Valid {
+		return nil, nil, types.JID{}, ErrMessageRevoked
+	}
+
+	chat, err := types.ParseJID(target.ChatJID)
+	if err != nil {
+		return nil, nil, types.JID{}, fmt.Errorf("invalid chat JID %q: %w", target.ChatJID, err)
+	}
+	switch helper.ChatType(chat) {
+	case "personal", "group":
+	default:
+		return nil, nil, types.JID{}, ErrUnsupportedChatType
+	}
+	return session, target, chat, nil
+}
+
+// targetSender pengirim pesan target untuk message key (pesan sendiri = JID instance)
+func targetSender(session *model.Session, target *model.Message, chat types.JID) (types.JID, error) {
+	if target.Direction == "outgoing" {
+		return session.Client.Store.ID.ToNonAD(), nil
+	}
+	if !target.SenderJID.Valid {
+		return chat, nil // chat personal: pengirim = lawan chat
+	}
+	sender, err := types.ParseJID(target.SenderJID.String)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid sender JID %q: %w", target.SenderJID.String, err)
+	}
+	return sender, nil
+}
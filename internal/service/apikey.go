@@ -0,0 +1,143 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+)
+
+const (
+	apiKeyPrefix        = "swa_"
+	apiKeyTouchInterval = time.Minute // jeda minimal update last_used_at
+)
+
+var (
+	ErrInvalidAPIKey     = errors.New("invalid or revoked api key")
+	ErrInvalidPermission = fmt.Errorf("permissions must be any of %v", model.AllPermissions)
+)
+
+// CreateAPIKey generate key random untuk user. Key plaintext hanya dikembalikan sekali di sini,
+// yang disimpan di DB hanya hash SHA-256 + prefix untuk identifikasi.
+func CreateAPIKey(userID int64, name string, instanceIDs, phoneNumbers, permissions []string, expiresAt *time.Time) (string, *model.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("name is required")
+	}
+
+	perms, err := normalizePermissions(permissions)
+	if err != nil {
+		return "", nil, err
+	}
+
+	phones := make([]string, 0, len(phoneNumbers))
+	for _, p := range phoneNumbers {
+		jid, err := helper.FormatPhoneNumber(p)
+		if err != nil {
+			return "", nil, err
+		}
+		phones = append(phones, jid.User)
+	}
+
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	secret := hex.EncodeToString(b)
+	plaintext := apiKeyPrefix + secret
+
+	key := &model.APIKey{
+		UserID:       userID,
+		Name:         name,
+		KeyPrefix:    apiKeyPrefix + secret[:8],
+		KeyHash:      hashToken(plaintext),
+		InstanceIDs:  compactStrings(instanceIDs),
+		PhoneNumbers: phones,
+		Permissions:  perms,
+	}
+	if expiresAt != nil {
+		key.ExpiresAt = model.NullTime(*expiresAt)
+	}
+
+	if err := model.InsertAPIKey(key); err != nil {
+		return "", nil, err
+	}
+	return plaintext, key, nil
+}
+
+// AuthenticateAPIKey validasi header X-API-Key, return key + user pemiliknya (harus aktif).
+func AuthenticateAPIKey(plaintext string) (*model.APIKey, *model.User, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	key, err := model.GetActiveAPIKeyByHash(hashToken(plaintext))
+	if err != nil {
+		if errors.Is(err, model.ErrAPIKeyNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+
+	user, err := model.GetUserByID(key.UserID)
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, ErrUserDisabled
+	}
+
+	// last_used_at cukup presisi per menit: skip write kalau baru di-update (hot path tiap request)
+	if !key.LastUsedAt.Valid || time.Since(key.LastUsedAt.Time) > apiKeyTouchInterval {
+		if err := model.TouchAPIKey(key.ID); err != nil {
+			log.Printf("Warning: failed to update last_used_at for api key %d: %v", key.ID, err)
+		}
+	}
+	return key, user, nil
+}
+
+func normalizePermissions(permissions []string) ([]string, error) {
+	if len(permissions) == 0 {
+		return nil, ErrInvalidPermission
+	}
+
+	result := make([]string, 0, len(permissions))
+	seen := make(map[string]bool)
+	for _, p := range permissions {
+		p = strings.ToLower(strings.TrimSpace(p))
+		valid := false
+		for _, allowed := range model.AllPermissions {
+			if p == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, ErrInvalidPermission
+		}
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func compactStrings(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,507 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	campaignValidateBatch = 50 // jumlah nomor per request IsOnWhatsApp
+	campaignProgressEvery = 25 // publish CAMPAIGN_PROGRESS tiap N penerima diproses
+	campaignCallTimeout   = 60 * time.Second
+	campaignRateLimitPoll = 30 * time.Second // kena rate limit: cek ulang status campaign tiap N detik
+)
+
+var (
+	ErrCampaignNotRunning   = errors.New("campaign is not running")
+	ErrCampaignNotPaused    = errors.New("campaign is not paused")
+	ErrCampaignFinished     = errors.New("campaign already completed or cancelled")
+	ErrCampaignNoRecipients = errors.New("campaign has no recipients")
+	errCampaignSessionLost  = errors.New("whatsapp session is not connected, campaign paused")
+
+	// Media campaign di-upload sekali saat dibuat; lewat ScheduleMediaMaxAhead file CDN-nya bisa expired
+	ErrCampaignMediaExpired = errors.New("campaign media was uploaded too long ago and may have expired, create a new campaign")
+	ErrCampaignTooLong      = errors.New("estimated campaign duration exceeds the media upload window")
+)
+
+// CampaignTarget satu penerima campaign beserta variabel template khusus penerima tsb
+type CampaignTarget struct {
+	Phone     string
+	Variables map[string]interface{}
+}
+
+// RecipientVariablesError variabel template penerima tidak lengkap (campaign tidak dibuat)
+type RecipientVariablesError struct {
+	Phone   string
+	Missing []string
+}
+
+func (e *RecipientVariablesError) Error() string {
+	return fmt.Sprintf("recipient %s: missing template variables: %s", e.Phone, strings.Join(e.Missing, ", "))
+}
+
+var (
+	campaignRunnersLock sync.Mutex
+	campaignRunners     = make(map[int64]bool) // campaign yang runner-nya sedang jalan
+)
+
+// CreateCampaign simpan campaign + penerima (nomor dinormalisasi & dedup) lalu langsung jalankan runner.
+// Kalau templateBody diisi, teks / caption di-render per penerima dari variables campaign
+// digabung variables penerima; semua penerima wajib punya variabel lengkap.
+func CreateCampaign(instanceID, name string, msg *waE2E.Message, targets []CampaignTarget,
+	templateBody string, variables map[string]interface{}, delayMs, jitterMs int) (*model.Campaign, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	messageType := "unknown"
+	if content, ok := helper.ExtractMessageContent(msg); ok {
+		messageType = content.Type
+	}
+
+	if templateBody != "" {
+		for _, t := range targets {
+			missing := helper.MissingTemplateVariables(templateBody, helper.MergeVariables(variables, t.Variables))
+			if len(missing) > 0 {
+				return nil, &RecipientVariablesError{Phone: t.Phone, Missing: missing}
+			}
+		}
+	}
+
+	recipients, err := buildCampaignRecipients(targets)
+	if err != nil {
+		return nil, err
+	}
+	if len(recipients) == 0 {
+		return nil, ErrCampaignNoRecipients
+	}
+
+	// Estimasi minimal (tanpa rate limit / pause): jeda rata-rata × jumlah penerima
+	if helper.HasUploadedMedia(msg) {
+		perMessage := time.Duration(delayMs)*time.Millisecond + time.Duration(jitterMs/2)*time.Millisecond
+		if estimate := perMessage * time.Duration(len(recipients)); estimate > ScheduleMediaMaxAhead {
+			return nil, fmt.Errorf("%w: ~%s for %d recipients (max %s), split the recipients or lower delayMs",
+				ErrCampaignTooLong, estimate.Round(time.Minute), len(recipients), ScheduleMediaMaxAhead)
+		}
+	}
+
+	campaign := &model.Campaign{
+		InstanceID:  instanceID,
+		Name:        name,
+		MessageType: messageType,
+		Payload:     payload,
+		Status:      model.CampaignStatusRunning,
+		DelayMs:     delayMs,
+		JitterMs:    jitterMs,
+	}
+	if templateBody != "" {
+		campaign.TemplateBody = model.NullString(templateBody)
+		if len(variables) > 0 {
+			if campaign.Variables, err = json.Marshal(variables); err != nil {
+				return nil, fmt.Errorf("failed to marshal variables: %w", err)
+			}
+		}
+	}
+	if err := model.InsertCampaign(campaign, recipients); err != nil {
+		return nil, err
+	}
+
+	startCampaign(*campaign)
+	return campaign, nil
+}
+
+// buildCampaignRecipients normalisasi nomor, buang duplikat, dan tandai format salah sebagai invalid
+func buildCampaignRecipients(targets []CampaignTarget) ([]model.CampaignRecipient, error) {
+	seen := make(map[string]bool)
+	recipients := make([]model.CampaignRecipient, 0, len(targets))
+
+	for _, t := range targets {
+		var vars []byte
+		if len(t.Variables) > 0 {
+			b, err := json.Marshal(t.Variables)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal variables for %s: %w", t.Phone, err)
+			}
+			vars = b
+		}
+
+		jid, err := helper.FormatPhoneNumber(t.Phone)
+		if err != nil {
+			recipients = append(recipients, model.CampaignRecipient{
+				Phone:     t.Phone,
+				Status:    model.RecipientStatusInvalid,
+				Error:     model.NullString(err.Error()),
+				Variables: vars,
+			})
+			continue
+		}
+		if seen[jid.User] {
+			continue
+		}
+		seen[jid.User] = true
+		recipients = append(recipients, model.CampaignRecipient{
+			Phone:     jid.User,
+			Status:    model.RecipientStatusPending,
+			Variables: vars,
+		})
+	}
+	return recipients, nil
+}
+
+// PauseCampaign hentikan pengiriman setelah pesan yang sedang dikirim selesai
+func PauseCampaign(instanceID string, id int64) (*model.Campaign, error) {
+	return transitionCampaign(instanceID, id, []string{model.CampaignStatusRunning}, model.CampaignStatusPaused, ErrCampaignNotRunning)
+}
+
+// ResumeCampaign lanjutkan campaign yang di-pause dari penerima berikutnya
+func ResumeCampaign(instanceID string, id int64) (*model.Campaign, error) {
+	current, err := model.GetCampaign(instanceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaignMediaExpired(*current) {
+		return nil, ErrCampaignMediaExpired
+	}
+
+	campaign, err := transitionCampaign(instanceID, id, []string{model.CampaignStatusPaused}, model.CampaignStatusRunning, ErrCampaignNotPaused)
+	if err != nil {
+		return nil, err
+	}
+	startCampaign(*campaign)
+	return campaign, nil
+}
+
+// CancelCampaign batalkan campaign; penerima yang belum dikirim ditandai cancelled
+func CancelCampaign(instanceID string, id int64) (*model.Campaign, error) {
+	campaign, err := transitionCampaign(instanceID, id,
+		[]string{model.CampaignStatusRunning, model.CampaignStatusPaused}, model.CampaignStatusCancelled, ErrCampaignFinished)
+	if err != nil {
+		return nil, err
+	}
+	if err := model.CancelCampaignRecipients(id); err != nil {
+		return nil, err
+	}
+	publishCampaignProgress(*campaign)
+	return campaign, nil
+}
+
+func transitionCampaign(instanceID string, id int64, from []string, to string, invalid error) (*model.Campaign, error) {
+	if _, err := model.GetCampaign(instanceID, id); err != nil {
+		return nil, err
+	}
+
+	ok, err := model.TransitionCampaignStatus(id, from, to, "")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, invalid
+	}
+
+	campaign, err := model.GetCampaign(instanceID, id)
+	if err != nil {
+		return nil, err
+	}
+	if to != model.CampaignStatusRunning {
+		publishCampaignProgress(*campaign)
+	}
+	return campaign, nil
+}
+
+// ResumeRunningCampaigns dipanggil saat startup: campaign yang masih 'running' dilanjutkan
+func ResumeRunningCampaigns() {
+	campaigns, err := model.GetRunningCampaigns()
+	if err != nil {
+		log.Printf("campaign: failed to load running campaigns: %v", err)
+		return
+	}
+	for _, c := range campaigns {
+		startCampaign(c)
+	}
+}
+
+// startCampaign jalankan runner kalau belum ada runner untuk campaign ini
+func startCampaign(c model.Campaign) {
+	campaignRunnersLock.Lock()
+	defer campaignRunnersLock.Unlock()
+
+	if campaignRunners[c.ID] {
+		return
+	}
+	campaignRunners[c.ID] = true
+	go runCampaign(c)
+}
+
+// runCampaign validasi nomor per batch lalu kirim satu per satu dengan jeda delay + jitter.
+// Status dicek dari DB sebelum setiap langkah supaya pause / cancel berlaku di instance server mana pun.
+func runCampaign(c model.Campaign) {
+	// failed = berhenti karena error DB saat status masih 'running' (tidak di-restart otomatis)
+	failed := false
+	defer func() {
+		campaignRunnersLock.Lock()
+		delete(campaignRunners, c.ID)
+		campaignRunnersLock.Unlock()
+
+		// Resume yang masuk saat runner ini sedang berhenti tidak memulai runner baru
+		// (startCampaign masih melihat runner lama), jadi cek ulang status setelah runner dilepas.
+		if failed {
+			return
+		}
+		if status, err := model.GetCampaignStatus(c.ID); err == nil && status == model.CampaignStatusRunning {
+			if latest, err := model.GetCampaign(c.InstanceID, c.ID); err == nil {
+				startCampaign(*latest)
+			}
+		}
+	}()
+
+	msg := &waE2E.Message{}
+	if err := proto.Unmarshal(c.Payload, msg); err != nil {
+		stopCampaign(c, model.CampaignStatusCancelled, fmt.Sprintf("invalid campaign payload: %v", err))
+		return
+	}
+
+	var variables map[string]interface{}
+	if len(c.Variables) > 0 {
+		if err := json.Unmarshal(c.Variables, &variables); err != nil {
+			stopCampaign(c, model.CampaignStatusCancelled, fmt.Sprintf("invalid campaign variables: %v", err))
+			return
+		}
+	}
+
+	publishCampaignProgress(c)
+
+	processed := 0
+	for {
+		status, err := model.GetCampaignStatus(c.ID)
+		if err != nil {
+			log.Printf("campaign %d: failed to get status: %v", c.ID, err)
+			failed = true
+			return
+		}
+		if status != model.CampaignStatusRunning {
+			return
+		}
+
+		// 1. Validasi nomor yang belum dicek (batch IsOnWhatsApp)
+		pending, err := model.GetCampaignRecipientsByStatus(c.ID, model.RecipientStatusPending, campaignValidateBatch)
+		if err != nil {
+			log.Printf("campaign %d: failed to get pending recipients: %v", c.ID, err)
+			failed = true
+			return
+		}
+		if len(pending) > 0 {
+			if err := validateCampaignRecipients(c.InstanceID, pending); err != nil {
+				stopCampaign(c, model.CampaignStatusPaused, err.Error())
+				return
+			}
+			continue
+		}
+
+		// 2. Kirim ke penerima valid berikutnya (media yang sudah terlalu lama tidak dipakai lagi)
+		if campaignMediaExpired(c) {
+			if err := model.CancelCampaignRecipients(c.ID); err != nil {
+				log.Printf("campaign %d: failed to cancel recipients: %v", c.ID, err)
+			}
+			stopCampaign(c, model.CampaignStatusCancelled, ErrCampaignMediaExpired.Error())
+			return
+		}
+		next, err := model.GetCampaignRecipientsByStatus(c.ID, model.RecipientStatusValid, 1)
+		if err != nil {
+			log.Printf("campaign %d: failed to get next recipient: %v", c.ID, err)
+			failed = true
+			return
+		}
+		if len(next) == 0 {
+			stopCampaign(c, model.CampaignStatusCompleted, "")
+			return
+		}
+
+		if err := sendCampaignMessage(c, next[0], msg, variables); err != nil {
+			// Kena rate limit instance: tunggu lalu coba lagi penerima yang sama
+			var limited *RateLimitError
+			if errors.As(err, &limited) {
+				time.Sleep(min(limited.RetryAfter, campaignRateLimitPoll))
+				continue
+			}
+			stopCampaign(c, model.CampaignStatusPaused, err.Error())
+			return
+		}
+
+		processed++
+		if processed%campaignProgressEvery == 0 {
+			publishCampaignProgress(c)
+		}
+
+		time.Sleep(campaignDelay(c))
+	}
+}
+
+// campaignMediaExpired media campaign di-upload saat campaign dibuat (CreatedAt)
+func campaignMediaExpired(c model.Campaign) bool {
+	if time.Since(c.CreatedAt) <= ScheduleMediaMaxAhead {
+		return false
+	}
+	msg := &waE2E.Message{}
+	if err := proto.Unmarshal(c.Payload, msg); err != nil {
+		return false
+	}
+	return helper.HasUploadedMedia(msg)
+}
+
+func validateCampaignRecipients(instanceID string, recipients []model.CampaignRecipient) error {
+	session, err := GetSession(instanceID)
+	if err != nil || session.Client.Store.ID == nil || !session.Client.IsConnected() {
+		return errCampaignSessionLost
+	}
+
+	phones := make([]string, len(recipients))
+	for i, r := range recipients {
+		phones[i] = r.Phone
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), campaignCallTimeout)
+	defer cancel()
+
+	results, err := session.Client.IsOnWhatsApp(ctx, phones)
+	if err != nil {
+		return fmt.Errorf("failed to verify phone numbers: %w", err)
+	}
+
+	registered := make(map[string]types.JID, len(results))
+	for _, r := range results {
+		if r.IsIn {
+			registered[r.Query] = r.JID
+		}
+	}
+
+	for _, r := range recipients {
+		status, jid := model.RecipientStatusNotRegistered, ""
+		if j, ok := registered[r.Phone]; ok {
+			status, jid = model.RecipientStatusValid, j.String()
+		}
+		if err := model.UpdateCampaignRecipientValidation(r.ID, status, jid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendCampaignMessage kirim ke satu penerima. Error hanya dikembalikan kalau campaign harus di-pause
+// (session putus) atau instance kena rate limit; kegagalan per penerima dicatat sebagai failed.
+func sendCampaignMessage(c model.Campaign, r model.CampaignRecipient, msg *waE2E.Message, variables map[string]interface{}) error {
+	to, err := types.ParseJID(r.JID.String)
+	if err != nil {
+		return model.MarkCampaignRecipientResult(r.ID, model.RecipientStatusFailed, "", err.Error())
+	}
+
+	if c.TemplateBody.Valid {
+		msg, err = renderCampaignMessage(c.TemplateBody.String, msg, variables, r.Variables)
+		if err != nil {
+			return model.MarkCampaignRecipientResult(r.ID, model.RecipientStatusFailed, "", err.Error())
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), campaignCallTimeout)
+	defer cancel()
+
+	resp, err := SendMessage(ctx, c.InstanceID, to, msg, nil)
+	if errors.Is(err, ErrInstanceNotFound) || errors.Is(err, ErrSessionNotConnected) {
+		return errCampaignSessionLost
+	}
+	var limited *RateLimitError
+	if errors.As(err, &limited) {
+		return err
+	}
+	if err != nil {
+		return model.MarkCampaignRecipientResult(r.ID, model.RecipientStatusFailed, "", err.Error())
+	}
+	return model.MarkCampaignRecipientResult(r.ID, model.RecipientStatusSent, resp.ID, "")
+}
+
+// renderCampaignMessage salin pesan campaign lalu isi teks / caption hasil render untuk satu penerima
+func renderCampaignMessage(body string, msg *waE2E.Message, variables map[string]interface{}, recipientVars []byte) (*waE2E.Message, error) {
+	var own map[string]interface{}
+	if len(recipientVars) > 0 {
+		if err := json.Unmarshal(recipientVars, &own); err != nil {
+			return nil, fmt.Errorf("invalid recipient variables: %w", err)
+		}
+	}
+
+	text, err := helper.RenderTemplate(body, helper.MergeVariables(variables, own))
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := proto.Clone(msg).(*waE2E.Message)
+	helper.SetMessageText(rendered, text)
+	return rendered, nil
+}
+
+// stopCampaign ubah status campaign yang masih running (completed / paused karena error)
+func stopCampaign(c model.Campaign, status, lastError string) {
+	ok, err := model.TransitionCampaignStatus(c.ID, []string{model.CampaignStatusRunning}, status, lastError)
+	if err != nil {
+		log.Printf("campaign %d: failed to set status %s: %v", c.ID, status, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if updated, err := model.GetCampaign(c.InstanceID, c.ID); err == nil {
+		publishCampaignProgress(*updated)
+	}
+}
+
+func campaignDelay(c model.Campaign) time.Duration {
+	delay := time.Duration(c.DelayMs) * time.Millisecond
+	if c.JitterMs > 0 {
+		delay += time.Duration(rand.Intn(c.JitterMs+1)) * time.Millisecond
+	}
+	return delay
+}
+
+func publishCampaignProgress(c model.Campaign) {
+	if Realtime == nil {
+		return
+	}
+
+	stats, err := model.GetCampaignStats(c.ID)
+	if err != nil {
+		log.Printf("campaign %d: failed to get stats: %v", c.ID, err)
+		return
+	}
+	status, err := model.GetCampaignStatus(c.ID)
+	if err != nil {
+		status = c.Status
+	}
+
+	Realtime.Publish(ws.WsEvent{
+		Event:     ws.EventCampaignProgress,
+		Timestamp: time.Now().UTC(),
+		Data: ws.CampaignProgressData{
+			InstanceID:  c.InstanceID,
+			PhoneNumber: ownPhoneNumber(c.InstanceID),
+			CampaignID:  c.ID,
+			Status:      status,
+			LastError:   c.LastError.String,
+			Progress:    stats,
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gowa-yourself/internal/model"
+)
+
+const (
+	// idempotencyStaleAfter request yang masih 'processing' lebih lama dari ini dianggap
+	// tertinggal (server mati di tengah kirim) dan key-nya boleh dipakai ulang
+	idempotencyStaleAfter = 5 * time.Minute
+	idempotencyCleanEvery = 1 * time.Hour
+)
+
+// IdempotencyWindow di-set dari main.go (IDEMPOTENCY_WINDOW): berapa lama hasil request disimpan
+var IdempotencyWindow = 24 * time.Hour
+
+var (
+	ErrIdempotencyKeyInUse    = errors.New("a request with this idempotency key is still being processed")
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was already used for a different request")
+)
+
+// BeginIdempotentRequest klaim key untuk request baru. Kalau key sudah punya hasil, return
+// record-nya untuk di-replay (nil = request baru, lanjut diproses lalu Complete / Release).
+func BeginIdempotentRequest(userID int64, key, requestHash string) (*model.IdempotencyKey, error) {
+	now := time.Now()
+	reserved, err := model.ReserveIdempotencyKey(userID, key, requestHash, now.Add(IdempotencyWindow), now.Add(-idempotencyStaleAfter))
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := model.GetIdempotencyKey(userID, key)
+	if errors.Is(err, model.ErrIdempotencyKeyNotFound) {
+		return nil, ErrIdempotencyKeyInUse // baru saja expired, klien coba lagi
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	if existing.Status != model.IdempotencyStatusCompleted {
+		return nil, ErrIdempotencyKeyInUse
+	}
+	return existing, nil
+}
+
+// CompleteIdempotentRequest simpan response sukses supaya request ulang mendapat hasil yang sama
+func CompleteIdempotentRequest(userID int64, key string, statusCode int, body []byte, messageID string) {
+	if err := model.CompleteIdempotencyKey(userID, key, statusCode, body, messageID); err != nil {
+		log.Printf("idempotency: failed to save result for key %q: %v", key, err)
+	}
+}
+
+// ReleaseIdempotentRequest lepas key request yang gagal (pesan tidak terkirim, boleh di-retry)
+func ReleaseIdempotentRequest(userID int64, key string) {
+	if err := model.DeleteIdempotencyKey(userID, key); err != nil {
+		log.Printf("idempotency: failed to release key %q: %v", key, err)
+	}
+}
+
+// RunIdempotencyCleaner hapus idempotency key yang sudah lewat window secara berkala
+func RunIdempotencyCleaner() {
+	ticker := time.NewTicker(idempotencyCleanEvery)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := model.DeleteExpiredIdempotencyKeys(); err != nil {
+			log.Printf("idempotency: failed to delete expired keys: %v", err)
+		}
+	}
+}
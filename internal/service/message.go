@@ -0,0 +1,159 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// handleIncomingMessage simpan pesan dari events.Message ke table messages
+// lalu broadcast MESSAGE_RECEIVED ke client WS.
+func handleIncomingMessage(instanceID string, evt *events.Message) {
+	if evt.IsEdit {
+		return
+	}
+
+	// Vote polling tidak disimpan sebagai chat, hanya hasil vote-nya
+	if evt.Message.GetPollUpdateMessage() != nil {
+		handlePollVote(instanceID, evt)
+		return
+	}
+
+	content, ok := helper.ExtractMessageContent(evt.Message)
+	if !ok {
+		return
+	}
+
+	direction := "incoming"
+	var status sql.NullString
+	var statusUpdatedAt sql.NullTime
+	if evt.Info.IsFromMe {
+		// Dikirim dari device lain milik nomor yang sama, status tetap dilacak dari receipt
+		direction = "outgoing"
+		status = model.NullString(model.MessageStatusServerAck)
+		statusUpdatedAt = model.NullTime(evt.Info.Timestamp)
+	}
+
+	raw, err := proto.Marshal(evt.Message)
+	if err != nil {
+		fmt.Printf("Warning: failed to marshal message %s: %v\n", evt.Info.ID, err)
+	}
+
+	msg := &model.Message{
+		InstanceID:      instanceID,
+		MessageID:       evt.Info.ID,
+		ChatJID:         evt.Info.Chat.ToNonAD().String(),
+		ChatType:        helper.ChatType(evt.Info.Chat),
+		SenderJID:       model.NullString(evt.Info.Sender.ToNonAD().String()),
+		SenderName:      model.NullString(evt.Info.PushName),
+		Direction:       direction,
+		MessageType:     content.Type,
+		Body:            model.NullString(content.Body),
+		MediaMimetype:   model.NullString(content.Mimetype),
+		MediaFileName:   model.NullString(content.FileName),
+		MediaSize:       sql.NullInt64{Int64: int64(content.FileSize), Valid: content.FileSize > 0},
+		RawMessage:      raw,
+		Timestamp:       evt.Info.Timestamp,
+		Status:          status,
+		StatusUpdatedAt: statusUpdatedAt,
+	}
+
+	if err := model.InsertMessage(msg); err != nil {
+		fmt.Printf("Warning: failed to store message %s for instance %s: %v\n", evt.Info.ID, instanceID, err)
+		return
+	}
+	recordPoll(instanceID, evt.Info.ID, evt.Info.Chat, evt.Info.Sender, evt.Message)
+
+	if Realtime != nil {
+		data := ws.MessageReceivedData{
+			InstanceID:  instanceID,
+			PhoneNumber: ownPhoneNumber(instanceID),
+			MessageID:   msg.MessageID,
+			ChatJID:     msg.ChatJID,
+			ChatType:    msg.ChatType,
+			SenderJID:   msg.SenderJID.String,
+			SenderName:  msg.SenderName.String,
+			Direction:   msg.Direction,
+			MessageType: msg.MessageType,
+			Body:        content.Body,
+			Mimetype:    content.Mimetype,
+			FileName:    content.FileName,
+			SentAt:      msg.Timestamp,
+		}
+
+		Realtime.Publish(ws.WsEvent{
+			Event:     ws.EventMessageReceived,
+			Timestamp: time.Now().UTC(),
+			Data:      data,
+		})
+	}
+}
+
+// handleReceipt update status pesan outgoing dari receipt penerima
+// lalu broadcast MESSAGE_STATUS_CHANGED kalau status pesan naik.
+func handleReceipt(instanceID string, evt *events.Receipt) {
+	// Receipt dari device kita sendiri (sender, read-self, played-self) bukan status penerima
+	if evt.IsFromMe {
+		return
+	}
+
+	var status string
+	switch evt.Type {
+	case types.ReceiptTypeDelivered:
+		status = model.MessageStatusDelivered
+	case types.ReceiptTypeRead:
+		status = model.MessageStatusRead
+	case types.ReceiptTypePlayed:
+		status = model.MessageStatusPlayed
+	default:
+		return
+	}
+
+	recipient := evt.Sender.ToNonAD().String()
+	chatJID := evt.Chat.ToNonAD().String()
+
+	for _, messageID := range evt.MessageIDs {
+		changed, err := model.ApplyMessageReceipt(instanceID, messageID, recipient, status, evt.Timestamp)
+		if err != nil {
+			fmt.Printf("Warning: failed to apply %s receipt for message %s: %v\n", status, messageID, err)
+			continue
+		}
+		if !changed || Realtime == nil {
+			continue
+		}
+
+		Realtime.Publish(ws.WsEvent{
+			Event:     ws.EventMessageStatusChanged,
+			Timestamp: time.Now().UTC(),
+			Data: ws.MessageStatusChangedData{
+				InstanceID:   instanceID,
+				PhoneNumber:  ownPhoneNumber(instanceID),
+				MessageID:    messageID,
+				ChatJID:      chatJID,
+				RecipientJID: recipient,
+				Status:       status,
+				UpdatedAt:    evt.Timestamp,
+			},
+		})
+	}
+}
+
+// ownPhoneNumber ambil nomor milik instance dari session memory
+func ownPhoneNumber(instanceID string) string {
+	sessionsLock.RLock()
+	defer sessionsLock.RUnlock()
+
+	session, exists := sessions[instanceID]
+	if !exists || session.Client == nil || session.Client.Store.ID == nil {
+		return ""
+	}
+	return session.Client.Store.ID.User
+}
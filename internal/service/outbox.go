@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	outboxBaseBackoff = 10 * time.Second // 10s, 20s, 40s, ... (eksponensial)
+	outboxMaxBackoff  = 10 * time.Minute
+	outboxPollEvery   = 5 * time.Second
+	outboxSendTimeout = 60 * time.Second
+)
+
+// OutboxMaxAttempts di-set dari main.go (OUTBOX_MAX_ATTEMPTS), berlaku untuk job baru
+var OutboxMaxAttempts = 8
+
+// Outbox di-set dari main.go, dipakai handler untuk mode kirim async
+var Outbox *OutboxDispatcher
+
+// errPermanent menandai error yang tidak perlu di-retry (job langsung failed)
+type errPermanent struct{ err error }
+
+func (e errPermanent) Error() string { return e.err.Error() }
+func (e errPermanent) Unwrap() error { return e.err }
+
+// OutboxDispatcher memproses table outbox_jobs dengan satu worker per instance,
+// jadi urutan pesan per instance tetap FIFO dan instance lain tidak saling menunggu.
+type OutboxDispatcher struct {
+	mu      sync.Mutex
+	workers map[string]chan struct{} // instanceID -> sinyal wake worker
+}
+
+// NewOutboxDispatcher membuat dispatcher baru. Jalankan Run() di goroutine terpisah.
+func NewOutboxDispatcher() *OutboxDispatcher {
+	return &OutboxDispatcher{
+		workers: make(map[string]chan struct{}),
+	}
+}
+
+// Run menyalakan worker untuk instance yang punya job jatuh tempo (termasuk job retry
+// dan job yang tertinggal sebelum restart).
+func (d *OutboxDispatcher) Run() {
+	// Job yang tertinggal 'processing' saat server mati dikirim ulang (message_id tetap sama)
+	if err := model.ResetProcessingOutboxJobs(); err != nil {
+		log.Printf("outbox: failed to reset processing jobs: %v", err)
+	}
+
+	ticker := time.NewTicker(outboxPollEvery)
+	defer ticker.Stop()
+
+	for {
+		instanceIDs, err := model.GetInstancesWithDueOutboxJobs()
+		if err != nil {
+			log.Printf("outbox: failed to get due jobs: %v", err)
+		}
+		for _, instanceID := range instanceIDs {
+			d.wakeInstance(instanceID)
+		}
+		<-ticker.C
+	}
+}
+
+// Enqueue simpan pesan ke outbox dan bangunkan worker instance-nya.
+// Message ID dibuat di awal (kalau session ada) supaya bisa langsung dipakai cek status.
+func (d *OutboxDispatcher) Enqueue(instanceID string, to types.JID, msg *waE2E.Message) (*model.OutboxJob, error) {
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	messageType := "unknown"
+	if content, ok := helper.ExtractMessageContent(msg); ok {
+		messageType = content.Type
+	}
+
+	job := &model.OutboxJob{
+		InstanceID:   instanceID,
+		RecipientJID: to.String(),
+		MessageType:  messageType,
+		Payload:      payload,
+		MaxAttempts:  OutboxMaxAttempts,
+	}
+	if session, err := GetSession(instanceID); err == nil {
+		job.MessageID = model.NullString(session.Client.GenerateMessageID())
+	}
+
+	if err := model.InsertOutboxJob(job); err != nil {
+		return nil, err
+	}
+
+	d.wakeInstance(instanceID)
+	return job, nil
+}
+
+// wakeInstance nyalakan worker instance kalau belum ada, atau kirim sinyal ke worker yang sedang jalan
+func (d *OutboxDispatcher) wakeInstance(instanceID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if wake, ok := d.workers[instanceID]; ok {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+
+	wake := make(chan struct{}, 1)
+	d.workers[instanceID] = wake
+	go d.work(instanceID, wake)
+}
+
+// work kirim job instance satu per satu sampai antrian kosong lalu berhenti
+func (d *OutboxDispatcher) work(instanceID string, wake chan struct{}) {
+	for {
+		job, err := model.ClaimNextOutboxJob(instanceID)
+		if err == nil {
+			// Kena rate limit: job sudah ditunda lewat next_attempt_at, worker berhenti dan
+			// poll berikutnya yang membangunkannya lagi (RetryAfter bisa berjam-jam untuk batas harian)
+			if limited := d.attempt(*job); !limited {
+				continue
+			}
+			d.mu.Lock()
+			delete(d.workers, instanceID)
+			d.mu.Unlock()
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("outbox: failed to claim job for instance %s: %v", instanceID, err)
+		}
+
+		// Cek sinyal terakhir di bawah lock supaya job yang baru di-enqueue tidak terlewat
+		d.mu.Lock()
+		select {
+		case <-wake:
+			d.mu.Unlock()
+			continue
+		default:
+		}
+		delete(d.workers, instanceID)
+		d.mu.Unlock()
+		return
+	}
+}
+
+// attempt kirim satu job dan catat hasilnya. Return true kalau instance kena rate limit
+// (job dikembalikan ke antrian tanpa menambah attempt).
+func (d *OutboxDispatcher) attempt(job model.OutboxJob) bool {
+	resp, err := d.send(&job)
+
+	var limited *RateLimitError
+	if errors.As(err, &limited) {
+		next := time.Now().Add(limited.RetryAfter)
+		job.Status = model.OutboxStatusPending
+		if err := model.DeferOutboxJob(job.ID, err.Error(), next); err != nil {
+			log.Printf("outbox: failed to defer job %d: %v", job.ID, err)
+		}
+		publishOutboxJob(job, err.Error(), &next)
+		return true
+	}
+
+	job.Attempts++
+
+	if err == nil {
+		job.Status = model.OutboxStatusSent
+		job.MessageID = model.NullString(resp.ID)
+		if err := model.MarkOutboxJobSent(job.ID, resp.ID, resp.Timestamp); err != nil {
+			log.Printf("outbox: failed to mark job %d sent: %v", job.ID, err)
+		}
+		publishOutboxJob(job, "", nil)
+		return false
+	}
+
+	var next *time.Time
+	var permanent errPermanent
+	if !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts {
+		t := time.Now().Add(outboxBackoff(job.Attempts))
+		next = &t
+	}
+
+	job.Status = model.OutboxStatusFailed
+	if next != nil {
+		job.Status = model.OutboxStatusPending
+	}
+
+	log.Printf("outbox: job %d for instance %s failed (attempt %d): %v", job.ID, job.InstanceID, job.Attempts, err)
+	if err := model.MarkOutboxJobFailed(job.ID, err.Error(), next); err != nil {
+		log.Printf("outbox: failed to mark job %d failed: %v", job.ID, err)
+	}
+	publishOutboxJob(job, err.Error(), next)
+	return false
+}
+
+func (d *OutboxDispatcher) send(job *model.OutboxJob) (whatsmeow.SendResponse, error) {
+	msg := &waE2E.Message{}
+	if err := proto.Unmarshal(job.Payload, msg); err != nil {
+		return whatsmeow.SendResponse{}, errPermanent{fmt.Errorf("invalid payload: %w", err)}
+	}
+
+	to, err := types.ParseJID(job.RecipientJID)
+	if err != nil {
+		return whatsmeow.SendResponse{}, errPermanent{fmt.Errorf("invalid recipient: %w", err)}
+	}
+
+	// Message ID dikunci sebelum kirim supaya retry tidak menghasilkan pesan dobel di penerima
+	if !job.MessageID.Valid {
+		session, err := GetSession(job.InstanceID)
+		if err != nil {
+			return whatsmeow.SendResponse{}, errPermanent{ErrInstanceNotFound}
+		}
+		job.MessageID = model.NullString(session.Client.GenerateMessageID())
+		if err := model.SetOutboxJobMessageID(job.ID, job.MessageID.String); err != nil {
+			return whatsmeow.SendResponse{}, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+	defer cancel()
+
+	resp, err := SendMessage(ctx, job.InstanceID, to, msg, &whatsmeow.SendRequestExtra{ID: job.MessageID.String})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInstanceNotFound),
+			errors.Is(err, whatsmeow.ErrBroadcastListUnsupported),
+			errors.Is(err, whatsmeow.ErrUnknownServer),
+			errors.Is(err, whatsmeow.ErrRecipientADJID):
+			return resp, errPermanent{err}
+		}
+		return resp, err
+	}
+	return resp, nil
+}
+
+func publishOutboxJob(job model.OutboxJob, lastError string, next *time.Time) {
+	if Realtime == nil {
+		return
+	}
+
+	Realtime.Publish(ws.WsEvent{
+		Event:     ws.EventOutboxJobUpdated,
+		Timestamp: time.Now().UTC(),
+		Data: ws.OutboxJobUpdatedData{
+			InstanceID:    job.InstanceID,
+			PhoneNumber:   ownPhoneNumber(job.InstanceID),
+			JobID:         job.ID,
+			To:            job.RecipientJID,
+			MessageID:     job.MessageID.String,
+			Status:        job.Status,
+			Attempts:      job.Attempts,
+			LastError:     lastError,
+			NextAttemptAt: next,
+		},
+	})
+}
+
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff << (attempts - 1)
+	if delay <= 0 || delay > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return delay
+}
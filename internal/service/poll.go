@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// recordPoll simpan pertanyaan & opsi polling supaya vote (yang hanya berisi hash opsi) bisa dipetakan
+func recordPoll(instanceID, messageID string, chat, creator types.JID, msg *waE2E.Message) {
+	poll := helper.PollCreation(msg)
+	if poll == nil {
+		return
+	}
+
+	options := make([]string, 0, len(poll.GetOptions()))
+	for _, opt := range poll.GetOptions() {
+		options = append(options, opt.GetOptionName())
+	}
+
+	p := &model.Poll{
+		InstanceID:      instanceID,
+		MessageID:       messageID,
+		ChatJID:         chat.ToNonAD().String(),
+		CreatorJID:      model.NullString(creator.ToNonAD().String()),
+		Question:        poll.GetName(),
+		Options:         options,
+		SelectableCount: int(poll.GetSelectableOptionsCount()),
+	}
+	if err := model.InsertPoll(p); err != nil {
+		fmt.Printf("Warning: failed to store poll %s for instance %s: %v\n", messageID, instanceID, err)
+	}
+}
+
+// handlePollVote dekripsi vote polling (PollUpdateMessage), simpan pilihan voter,
+// lalu broadcast POLL_VOTE_UPDATED berisi hasil terbaru.
+func handlePollVote(instanceID string, evt *events.Message) {
+	session, err := GetSession(instanceID)
+	if err != nil {
+		return
+	}
+
+	update := evt.Message.GetPollUpdateMessage()
+	pollID := update.GetPollCreationMessageKey().GetID()
+	poll, err := model.GetPollByMessageID(instanceID, pollID)
+	if err != nil {
+		// Polling dibuat sebelum fitur ini aktif / di luar riwayat instance
+		fmt.Printf("Warning: poll vote %s for unknown poll %s (instance %s): %v\n", evt.Info.ID, pollID, instanceID, err)
+		return
+	}
+
+	vote, err := session.Client.DecryptPollVote(context.Background(), evt)
+	if err != nil {
+		fmt.Printf("Warning: failed to decrypt poll vote %s for instance %s: %v\n", evt.Info.ID, instanceID, err)
+		return
+	}
+
+	// Vote berisi sha256 nama opsi, petakan balik ke nama opsi polling
+	hashes := whatsmeow.HashPollOptions(poll.Options)
+	selected := make([]string, 0, len(vote.GetSelectedOptions()))
+	for _, h := range vote.GetSelectedOptions() {
+		for i, optionHash := range hashes {
+			if bytes.Equal(h, optionHash) {
+				selected = append(selected, poll.Options[i])
+				break
+			}
+		}
+	}
+
+	v := model.PollVote{
+		PollID:          poll.ID,
+		VoterJID:        evt.Info.Sender.ToNonAD().String(),
+		SelectedOptions: selected,
+		VotedAt:         evt.Info.Timestamp,
+	}
+	if ts := update.GetSenderTimestampMS(); ts > 0 {
+		v.VotedAt = time.UnixMilli(ts)
+	}
+	if err := model.UpsertPollVote(v); err != nil {
+		fmt.Printf("Warning: failed to store poll vote %s for instance %s: %v\n", evt.Info.ID, instanceID, err)
+		return
+	}
+
+	if Realtime == nil {
+		return
+	}
+	results, err := GetPollResults(instanceID, poll.MessageID)
+	if err != nil {
+		fmt.Printf("Warning: failed to get poll results %s for instance %s: %v\n", poll.MessageID, instanceID, err)
+		return
+	}
+	Realtime.Publish(ws.WsEvent{
+		Event:     ws.EventPollVoteUpdated,
+		Timestamp: time.Now().UTC(),
+		Data: ws.PollVoteUpdatedData{
+			InstanceID:      instanceID,
+			PhoneNumber:     ownPhoneNumber(instanceID),
+			MessageID:       poll.MessageID,
+			ChatJID:         poll.ChatJID,
+			VoterJID:        v.VoterJID,
+			SelectedOptions: v.SelectedOptions,
+			VotedAt:         v.VotedAt,
+			Results:         results,
+		},
+	})
+}
+
+// GetPollResults hasil polling per opsi dan per voter
+func GetPollResults(instanceID, messageID string) (*model.PollResultsResp, error) {
+	poll, err := model.GetPollByMessageID(instanceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	votes, err := model.GetPollVotes(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	results := model.ToPollResults(*poll, votes)
+	return &results, nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+var ErrPoolNoHealthyMember = errors.New("no connected instance available in sender pool")
+
+var (
+	poolCursorsLock sync.Mutex
+	poolCursors     = make(map[int64]int) // posisi round-robin per pool (in-memory)
+)
+
+// PickPoolSession instance pertama dari candidates yang connected dan belum kena rate limit.
+// Dipakai untuk cek nomor penerima dan mode async / terjadwal (instance dikunci saat enqueue).
+func PickPoolSession(candidates []string) (*model.Session, error) {
+	var limited *RateLimitError
+	for _, instanceID := range candidates {
+		session, ok := connectedSession(instanceID)
+		if !ok {
+			continue
+		}
+		if err := checkRateLimit(instanceID); err != nil {
+			limited = shorterRetry(limited, err)
+			continue
+		}
+		return session, nil
+	}
+
+	if limited != nil {
+		return nil, limited
+	}
+	return nil, ErrPoolNoHealthyMember
+}
+
+// SendViaPool kirim lewat candidates secara berurutan; instance yang disconnected atau kena
+// rate limit dilewati (failover ke anggota berikutnya). Return instance yang dipakai.
+func SendViaPool(ctx context.Context, pool model.SenderPool, candidates []string, to types.JID, msg *waE2E.Message) (string, whatsmeow.SendResponse, error) {
+	var limited *RateLimitError
+	for _, instanceID := range candidates {
+		if _, ok := connectedSession(instanceID); !ok {
+			continue
+		}
+
+		resp, err := SendMessage(ctx, instanceID, to, msg, nil)
+		if err == nil {
+			RecordPoolUse(pool, instanceID, to)
+			return instanceID, resp, nil
+		}
+
+		var rl *RateLimitError
+		switch {
+		case errors.As(err, &rl):
+			limited = shorterRetry(limited, err)
+			continue
+		case errors.Is(err, ErrInstanceNotFound), errors.Is(err, ErrSessionNotConnected):
+			continue
+		}
+		// Error lain tidak di-failover supaya pesan tidak terkirim dobel dari dua nomor
+		return instanceID, resp, err
+	}
+
+	if limited != nil {
+		return "", whatsmeow.SendResponse{}, limited
+	}
+	return "", whatsmeow.SendResponse{}, ErrPoolNoHealthyMember
+}
+
+// RecordPoolUse catat pemakaian instance pool untuk strategi lru & sticky
+// (dipanggil juga setelah enqueue mode async / terjadwal)
+func RecordPoolUse(pool model.SenderPool, instanceID string, to types.JID) {
+	if err := model.TouchSenderPoolMember(pool.ID, instanceID); err != nil {
+		log.Printf("pool %s: failed to update last used for %s: %v", pool.Name, instanceID, err)
+	}
+	if pool.Strategy == model.PoolStrategySticky {
+		if err := model.SetSenderPoolSticky(pool.ID, to.ToNonAD().String(), instanceID); err != nil {
+			log.Printf("pool %s: failed to save sticky sender for %s: %v", pool.Name, to, err)
+		}
+	}
+}
+
+// PoolCandidates urutan instance yang dicoba sesuai strategi pool. Hitung sekali per pesan
+// (round-robin maju satu langkah setiap dipanggil).
+func PoolCandidates(pool model.SenderPool, members []model.SenderPoolMember, to types.JID) []string {
+	ids := make([]string, 0, len(members))
+	if len(members) == 0 {
+		return ids
+	}
+
+	switch pool.Strategy {
+	case model.PoolStrategyLRU, model.PoolStrategySticky:
+		sorted := make([]model.SenderPoolMember, len(members))
+		copy(sorted, members)
+		// belum pernah dipakai dulu, lalu yang paling lama tidak dipakai
+		sort.SliceStable(sorted, func(i, j int) bool {
+			a, b := sorted[i].LastUsedAt, sorted[j].LastUsedAt
+			if !a.Valid || !b.Valid {
+				return !a.Valid && b.Valid
+			}
+			return a.Time.Before(b.Time)
+		})
+		for _, m := range sorted {
+			ids = append(ids, m.InstanceID)
+		}
+
+		if pool.Strategy == model.PoolStrategySticky {
+			sticky, err := model.GetSenderPoolSticky(pool.ID, to.ToNonAD().String())
+			if err != nil {
+				log.Printf("pool %s: failed to get sticky sender for %s: %v", pool.Name, to, err)
+			}
+			for i, id := range ids {
+				if id == sticky && i > 0 {
+					reordered := append([]string{id}, ids[:i]...)
+					ids = append(reordered, ids[i+1:]...)
+					break
+				}
+			}
+		}
+
+	default: // round_robin
+		poolCursorsLock.Lock()
+		start := poolCursors[pool.ID] % len(members)
+		poolCursors[pool.ID] = start + 1
+		poolCursorsLock.Unlock()
+
+		for i := range members {
+			ids = append(ids, members[(start+i)%len(members)].InstanceID)
+		}
+	}
+	return ids
+}
+
+func connectedSession(instanceID string) (*model.Session, bool) {
+	session, err := GetSession(instanceID)
+	if err != nil || !session.IsConnected || session.Client.Store.ID == nil || !session.Client.IsConnected() {
+		return nil, false
+	}
+	return session, true
+}
+
+// shorterRetry simpan RateLimitError dengan retry-after paling pendek di antara anggota pool
+func shorterRetry(current *RateLimitError, err error) *RateLimitError {
+	var rl *RateLimitError
+	if !errors.As(err, &rl) {
+		return current
+	}
+	if current == nil || rl.RetryAfter < current.RetryAfter {
+		return rl
+	}
+	return current
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ProfileRefreshInterval interval refresh profil berkala (URL foto profil dari CDN WA bisa expire).
+// Di-set dari config (lihat main.go).
+var ProfileRefreshInterval = 6 * time.Hour
+
+// RefreshProfile ambil push name, about, URL foto profil & platform milik nomor instance
+// lalu simpan ke table instances. Field yang gagal diambil tidak ditimpa.
+func RefreshProfile(instanceID string) error {
+	session, err := GetSession(instanceID)
+	if err != nil {
+		return ErrInstanceNotFound
+	}
+
+	client := session.Client
+	if client.Store.ID == nil || !client.IsLoggedIn() {
+		return errors.New("instance is not connected")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	own := client.Store.ID.ToNonAD()
+	profile := model.InstanceProfile{
+		Platform: nullIfEmpty(client.Store.Platform),
+	}
+
+	// Push name (akun bisnis pakai business name kalau push name kosong)
+	name := client.Store.PushName
+	if name == "" {
+		name = client.Store.BusinessName
+	}
+	profile.Name = nullIfEmpty(name)
+
+	// About
+	if info, err := client.GetUserInfo(ctx, []types.JID{own}); err != nil {
+		fmt.Printf("Warning: failed to get about for instance %s: %v\n", instanceID, err)
+	} else if u, ok := info[own]; ok {
+		profile.About = sql.NullString{String: u.Status, Valid: true}
+	}
+
+	// Foto profil; kalau tidak di-set, kosongkan kolomnya
+	pic, err := client.GetProfilePictureInfo(ctx, own, &whatsmeow.GetProfilePictureParams{})
+	switch {
+	case errors.Is(err, whatsmeow.ErrProfilePictureNotSet):
+		profile.ProfilePicture = sql.NullString{String: "", Valid: true}
+	case err != nil:
+		fmt.Printf("Warning: failed to get profile picture for instance %s: %v\n", instanceID, err)
+	case pic != nil:
+		profile.ProfilePicture = sql.NullString{String: pic.URL, Valid: true}
+	}
+
+	return model.UpdateInstanceProfile(instanceID, profile)
+}
+
+// refreshProfileAsync dipanggil dari event handler supaya tidak blocking event whatsmeow
+func refreshProfileAsync(instanceID string) {
+	go func() {
+		if err := RefreshProfile(instanceID); err != nil {
+			fmt.Printf("Warning: failed to refresh profile for instance %s: %v\n", instanceID, err)
+		}
+	}()
+}
+
+// isOwnJID cek apakah event (foto, about, business name) milik nomor instance sendiri
+func isOwnJID(instanceID string, jid types.JID) bool {
+	session, err := GetSession(instanceID)
+	if err != nil || session.Client.Store.ID == nil {
+		return false
+	}
+	return session.Client.Store.ID.User == jid.User
+}
+
+// RunProfileRefresher refresh profil semua instance yang terkoneksi secara berkala
+func RunProfileRefresher() {
+	ticker := time.NewTicker(ProfileRefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for instanceID, session := range GetAllSessions() {
+			if !session.IsConnected {
+				continue
+			}
+			if err := RefreshProfile(instanceID); err != nil {
+				fmt.Printf("Warning: failed to refresh profile for instance %s: %v\n", instanceID, err)
+			}
+		}
+	}
+}
+
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
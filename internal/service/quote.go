@@ -0,0 +1,77 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrQuotedMessageNotFound = errors.New("quoted message not found in message history")
+
+// BuildContextInfo contextInfo untuk reply (quotedMessageID dari riwayat pesan instance) dan
+// mention (JID hasil helper.ParseMentionJIDs). Return nil kalau keduanya kosong.
+func BuildContextInfo(instanceID string, chat types.JID, quotedMessageID string, mentionJIDs []string) (*waE2E.ContextInfo, error) {
+	if quotedMessageID == "" && len(mentionJIDs) == 0 {
+		return nil, nil
+	}
+
+	contextInfo := &waE2E.ContextInfo{}
+	if len(mentionJIDs) > 0 {
+		contextInfo.MentionedJID = mentionJIDs
+	}
+
+	if quotedMessageID != "" {
+		quoted, err := model.GetMessageByMessageID(instanceID, quotedMessageID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuotedMessageNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		quotedMsg, err := quotedMessageContent(quoted)
+		if err != nil {
+			return nil, err
+		}
+
+		contextInfo.StanzaID = proto.String(quoted.MessageID)
+		contextInfo.QuotedMessage = quotedMsg
+		if quoted.SenderJID.Valid {
+			contextInfo.Participant = proto.String(quoted.SenderJID.String)
+		}
+		// Pesan yang di-quote dari chat lain (mis. balas pesan grup lewat chat pribadi)
+		if quoted.ChatJID != chat.ToNonAD().String() {
+			contextInfo.RemoteJID = proto.String(quoted.ChatJID)
+		}
+	}
+	return contextInfo, nil
+}
+
+// quotedMessageContent isi pesan asli dari raw_message (fallback ke body teks)
+func quotedMessageContent(m *model.Message) (*waE2E.Message, error) {
+	if len(m.RawMessage) == 0 {
+		return &waE2E.Message{Conversation: proto.String(m.Body.String)}, nil
+	}
+
+	msg := &waE2E.Message{}
+	if err := proto.Unmarshal(m.RawMessage, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode quoted message: %w", err)
+	}
+	// Pesan dari device kita sendiri dibungkus DeviceSentMessage
+	if inner := msg.GetDeviceSentMessage().GetMessage(); inner != nil {
+		msg = inner
+	}
+	// Quote di dalam quote dan secret pesan asli tidak ikut dikirim
+	msg = proto.Clone(msg).(*waE2E.Message)
+	msg.MessageContextInfo = nil
+	if ext := msg.GetExtendedTextMessage(); ext != nil {
+		ext.ContextInfo = nil
+	}
+	return msg, nil
+}
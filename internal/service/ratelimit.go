@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/model"
+)
+
+// RateLimitConfig batas kirim per instance (per nomor WhatsApp). Nilai 0 = tanpa batas.
+type RateLimitConfig struct {
+	PerMinute int
+	PerHour   int
+	PerDay    int
+
+	MinGap  time.Duration // jeda minimal antar pesan
+	Jitter  time.Duration // tambahan jeda acak 0..Jitter setelah setiap pesan
+	MaxWait time.Duration // jeda (gap + jitter) yang masih ditunggu; lebih dari ini → RATE_LIMITED
+
+	// Warm-up nomor baru: batas harian naik linear dari WarmupStartPerDay ke PerDay
+	// selama WarmupDays hari sejak instance pertama kali connect
+	WarmupDays        int
+	WarmupStartPerDay int
+}
+
+// SendRateLimit di-set dari main.go (SEND_LIMIT_*, SEND_MIN_GAP, ...)
+var SendRateLimit = RateLimitConfig{
+	PerMinute:         20,
+	PerHour:           300,
+	PerDay:            1500,
+	MinGap:            1 * time.Second,
+	Jitter:            2 * time.Second,
+	MaxWait:           30 * time.Second,
+	WarmupDays:        7,
+	WarmupStartPerDay: 50,
+}
+
+// RateLimitError batas kirim instance tercapai; RetryAfter = kapan boleh coba lagi
+type RateLimitError struct {
+	Limit      string // minute, hour, day, gap
+	Max        int
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Limit == "gap" {
+		return fmt.Sprintf("send rate limit reached (min gap), retry after %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("send rate limit reached (%d per %s), retry after %s", e.Max, e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+// RateLimitStatus pemakaian & batas efektif instance (dipakai endpoint rate-limit)
+type RateLimitStatus struct {
+	InstanceID     string     `json:"instanceId"`
+	SentLastMinute int        `json:"sentLastMinute"`
+	SentLastHour   int        `json:"sentLastHour"`
+	SentLastDay    int        `json:"sentLastDay"`
+	PerMinute      int        `json:"perMinute"`
+	PerHour        int        `json:"perHour"`
+	PerDay         int        `json:"perDay"` // sudah termasuk warm-up
+	WarmupDay      int        `json:"warmupDay,omitempty"`
+	WarmupDays     int        `json:"warmupDays,omitempty"`
+	MinGapMs       int64      `json:"minGapMs"`
+	JitterMs       int64      `json:"jitterMs"`
+	NextSendAt     *time.Time `json:"nextSendAt,omitempty"`
+}
+
+// instanceLimiter jejak waktu kirim 24 jam terakhir satu instance
+type instanceLimiter struct {
+	mu          sync.Mutex
+	sent        []time.Time // urut naik
+	nextAllowed time.Time   // waktu paling awal pesan berikutnya (gap + jitter)
+
+	// Slot terakhir yang dipesan + nextAllowed sebelum slot itu, untuk dikembalikan oleh releaseSendSlot
+	lastSlot    time.Time
+	prevAllowed time.Time
+}
+
+var (
+	rateLimitersLock sync.Mutex
+	rateLimiters     = make(map[string]*instanceLimiter)
+)
+
+// getRateLimiter ambil limiter instance; saat pertama dibuat diisi dari riwayat pesan keluar
+// 24 jam terakhir supaya restart server tidak me-reset hitungan. Query riwayat jalan di luar
+// rateLimitersLock (hanya l.mu yang dipegang) supaya instance lain tidak ikut menunggu DB.
+func getRateLimiter(instanceID string) *instanceLimiter {
+	rateLimitersLock.Lock()
+	if l, ok := rateLimiters[instanceID]; ok {
+		rateLimitersLock.Unlock()
+		return l
+	}
+
+	// l.mu dikunci sebelum masuk map: pemanggil lain untuk instance ini menunggu riwayat selesai dimuat
+	l := &instanceLimiter{}
+	l.mu.Lock()
+	rateLimiters[instanceID] = l
+	rateLimitersLock.Unlock()
+
+	defer l.mu.Unlock()
+	sent, err := model.GetOutgoingMessageTimes(instanceID, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		log.Printf("ratelimit: failed to load send history for instance %s: %v", instanceID, err)
+	}
+	l.sent = sent
+	return l
+}
+
+// reserveSendSlot cek batas kirim lalu pesan slot. Return waktu slot (untuk releaseSendSlot)
+// dan jeda yang harus ditunggu sebelum kirim (gap + jitter).
+func reserveSendSlot(instanceID string) (time.Time, time.Duration, error) {
+	cfg := SendRateLimit
+	l := getRateLimiter(instanceID)
+	perDay, _, _ := effectiveDailyLimit(instanceID, cfg)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	slot, wait, err := l.check(cfg, perDay, now)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	l.sent = append(l.sent, slot)
+	l.lastSlot, l.prevAllowed = slot, l.nextAllowed
+	l.nextAllowed = slot.Add(cfg.MinGap)
+	if cfg.Jitter > 0 {
+		l.nextAllowed = l.nextAllowed.Add(time.Duration(rand.Int63n(int64(cfg.Jitter) + 1)))
+	}
+	return slot, wait, nil
+}
+
+// checkRateLimit cek apakah instance boleh kirim sekarang tanpa memesan slot
+// (dipakai sender pool untuk memilih instance)
+func checkRateLimit(instanceID string) error {
+	cfg := SendRateLimit
+	l := getRateLimiter(instanceID)
+	perDay, _, _ := effectiveDailyLimit(instanceID, cfg)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	_, _, err := l.check(cfg, perDay, now)
+	return err
+}
+
+// releaseSendSlot batalkan slot yang gagal dikirim supaya tidak ikut dihitung. Kalau slot itu
+// yang terakhir dipesan, jeda gap + jitter-nya juga dibatalkan supaya kiriman berikutnya tidak ikut tertunda.
+func releaseSendSlot(instanceID string, slot time.Time) {
+	l := getRateLimiter(instanceID)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.sent) - 1; i >= 0; i-- {
+		if l.sent[i].Equal(slot) {
+			l.sent = append(l.sent[:i], l.sent[i+1:]...)
+			break
+		}
+	}
+	if !l.lastSlot.IsZero() && l.lastSlot.Equal(slot) {
+		l.nextAllowed = l.prevAllowed
+		l.lastSlot = time.Time{}
+	}
+}
+
+// waitSendSlot tunggu sampai slot tiba (atau context selesai)
+func waitSendSlot(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetRateLimitStatus pemakaian kuota kirim instance saat ini
+func GetRateLimitStatus(instanceID string) RateLimitStatus {
+	cfg := SendRateLimit
+	l := getRateLimiter(instanceID)
+	perDay, warmupDay, warmupDays := effectiveDailyLimit(instanceID, cfg)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	status := RateLimitStatus{
+		InstanceID:     instanceID,
+		SentLastMinute: len(l.countSince(now.Add(-time.Minute))),
+		SentLastHour:   len(l.countSince(now.Add(-time.Hour))),
+		SentLastDay:    len(l.sent),
+		PerMinute:      cfg.PerMinute,
+		PerHour:        cfg.PerHour,
+		PerDay:         perDay,
+		WarmupDay:      warmupDay,
+		WarmupDays:     warmupDays,
+		MinGapMs:       cfg.MinGap.Milliseconds(),
+		JitterMs:       cfg.Jitter.Milliseconds(),
+	}
+	if l.nextAllowed.After(now) {
+		next := l.nextAllowed
+		status.NextSendAt = &next
+	}
+	return status
+}
+
+// effectiveDailyLimit batas harian setelah warm-up. warmupDay (mulai 1) hanya diisi
+// kalau instance masih dalam masa warm-up.
+func effectiveDailyLimit(instanceID string, cfg RateLimitConfig) (perDay, warmupDay, warmupDays int) {
+	if cfg.PerDay <= 0 || cfg.WarmupDays <= 0 || cfg.WarmupStartPerDay <= 0 || cfg.WarmupStartPerDay >= cfg.PerDay {
+		return cfg.PerDay, 0, 0
+	}
+
+	firstConnected, err := model.GetInstanceFirstConnectedAt(instanceID)
+	if err != nil || !firstConnected.Valid {
+		// belum pernah tercatat connect: anggap nomor baru (hari pertama)
+		return cfg.WarmupStartPerDay, 1, cfg.WarmupDays
+	}
+
+	day := int(time.Since(firstConnected.Time) / (24 * time.Hour))
+	if day >= cfg.WarmupDays {
+		return cfg.PerDay, 0, 0
+	}
+	limit := cfg.WarmupStartPerDay + (cfg.PerDay-cfg.WarmupStartPerDay)*day/cfg.WarmupDays
+	return limit, day + 1, cfg.WarmupDays
+}
+
+// check hitung slot kirim berikutnya dan jeda tunggunya; error kalau batas tercapai.
+// Dipanggil dengan l.mu terkunci.
+func (l *instanceLimiter) check(cfg RateLimitConfig, perDay int, now time.Time) (time.Time, time.Duration, error) {
+	slot := now
+	if l.nextAllowed.After(now) {
+		slot = l.nextAllowed
+	}
+	wait := slot.Sub(now)
+	if cfg.MaxWait > 0 && wait > cfg.MaxWait {
+		return time.Time{}, 0, &RateLimitError{Limit: "gap", RetryAfter: wait}
+	}
+
+	windows := []struct {
+		name   string
+		max    int
+		period time.Duration
+	}{
+		{"minute", cfg.PerMinute, time.Minute},
+		{"hour", cfg.PerHour, time.Hour},
+		{"day", perDay, 24 * time.Hour},
+	}
+	for _, w := range windows {
+		if w.max <= 0 {
+			continue
+		}
+		inWindow := l.countSince(slot.Add(-w.period))
+		if len(inWindow) >= w.max {
+			// slot baru kosong setelah pesan tertua yang masih dihitung keluar dari window
+			oldest := inWindow[len(inWindow)-w.max]
+			return time.Time{}, 0, &RateLimitError{Limit: w.name, Max: w.max, RetryAfter: oldest.Add(w.period).Sub(now)}
+		}
+	}
+	return slot, wait, nil
+}
+
+// prune buang jejak kirim yang lebih lama dari 24 jam
+func (l *instanceLimiter) prune(now time.Time) {
+	cutoff := now.Add(-24 * time.Hour)
+	i := 0
+	for i < len(l.sent) && !l.sent[i].After(cutoff) {
+		i++
+	}
+	l.sent = l.sent[i:]
+}
+
+// countSince jejak kirim setelah waktu tertentu (slice urut naik)
+func (l *instanceLimiter) countSince(since time.Time) []time.Time {
+	for i, t := range l.sent {
+		if t.After(since) {
+			return l.sent[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// ReconnectConfig aturan backoff supervisor reconnect
+type ReconnectConfig struct {
+	MaxAttempts int           // setelah ini instance ditandai needs_attention
+	BaseDelay   time.Duration // delay attempt pertama, dikali 2 tiap attempt
+	MaxDelay    time.Duration // batas atas delay
+}
+
+// ReconnectPolicy di-set dari config (lihat main.go)
+var ReconnectPolicy = ReconnectConfig{
+	MaxAttempts: 10,
+	BaseDelay:   2 * time.Second,
+	MaxDelay:    5 * time.Minute,
+}
+
+const (
+	StatusReconnecting   = "reconnecting"
+	StatusNeedsAttention = "needs_attention"
+
+	// setelah Connect() berhasil, tunggu events.Connected (login) maksimal selama ini
+	reconnectLoginTimeout = 30 * time.Second
+
+	// sama dengan whatsmeow.KeepAliveMaxFailTime: lewat dari ini koneksi dianggap mati
+	keepAliveMaxFail = 3 * time.Minute
+)
+
+var (
+	ErrNotPaired        = errors.New("instance is not paired yet")
+	ErrAlreadyConnected = errors.New("instance is already connected")
+
+	// supervisor yang sedang berjalan per instance
+	reconnecting     = make(map[string]*reconnectJob)
+	reconnectingLock sync.Mutex
+)
+
+type reconnectJob struct {
+	cancel context.CancelFunc
+}
+
+// scheduleReconnect jalankan supervisor untuk instance (no-op kalau sudah berjalan)
+func scheduleReconnect(instanceID string, cause error) {
+	reconnectingLock.Lock()
+	defer reconnectingLock.Unlock()
+
+	if _, running := reconnecting[instanceID]; running {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &reconnectJob{cancel: cancel}
+	reconnecting[instanceID] = job
+
+	go superviseReconnect(ctx, job, instanceID, cause)
+}
+
+// stopReconnect hentikan supervisor (connected, logout, delete instance)
+func stopReconnect(instanceID string) {
+	reconnectingLock.Lock()
+	defer reconnectingLock.Unlock()
+
+	if job, ok := reconnecting[instanceID]; ok {
+		job.cancel()
+		delete(reconnecting, instanceID)
+	}
+}
+
+// ReconnectInstance mulai ulang supervisor secara manual, mis. untuk instance needs_attention
+func ReconnectInstance(instanceID string) error {
+	session, err := GetSession(instanceID)
+	if err != nil {
+		return ErrInstanceNotFound
+	}
+	if session.Client.Store.ID == nil {
+		return ErrNotPaired
+	}
+	if session.IsConnected && session.Client.IsLoggedIn() {
+		return ErrAlreadyConnected
+	}
+
+	stopReconnect(instanceID)
+	scheduleReconnect(instanceID, errors.New("manual reconnect requested"))
+	return nil
+}
+
+func superviseReconnect(ctx context.Context, job *reconnectJob, instanceID string, cause error) {
+	defer func() {
+		reconnectingLock.Lock()
+		if reconnecting[instanceID] == job {
+			delete(reconnecting, instanceID)
+		}
+		reconnectingLock.Unlock()
+		job.cancel()
+	}()
+
+	lastErr := cause
+	for attempt := 1; ; attempt++ {
+		if attempt > ReconnectPolicy.MaxAttempts {
+			fmt.Printf("✗ Reconnect gave up after %d attempts. Instance: %s, last error: %v\n", attempt-1, instanceID, lastErr)
+			if err := model.UpdateInstanceNeedsAttention(instanceID, attempt-1, errString(lastErr)); err != nil {
+				fmt.Println("Warning: failed to update instance needs_attention:", err)
+			}
+			publishReconnectStatus(instanceID, StatusNeedsAttention, attempt-1, lastErr, nil)
+			return
+		}
+
+		delay := reconnectDelay(attempt)
+		nextAt := time.Now().Add(delay)
+		if err := model.UpdateInstanceReconnecting(instanceID, attempt, errString(lastErr), nextAt); err != nil {
+			fmt.Println("Warning: failed to update instance reconnecting:", err)
+		}
+		publishReconnectStatus(instanceID, StatusReconnecting, attempt, lastErr, &nextAt)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		session, err := GetSession(instanceID)
+		if err != nil || session.Client.Store.ID == nil {
+			return // sudah logout / dihapus / belum pernah pairing
+		}
+
+		fmt.Printf("↻ Reconnecting instance %s (attempt %d/%d)\n", instanceID, attempt, ReconnectPolicy.MaxAttempts)
+		lastErr = connectAndWaitLogin(ctx, session.Client)
+		if lastErr == nil || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// connectAndWaitLogin connect lalu tunggu events.Connected (yang memanggil stopReconnect → ctx selesai)
+func connectAndWaitLogin(ctx context.Context, client *whatsmeow.Client) error {
+	if err := client.Connect(); err != nil && !errors.Is(err, whatsmeow.ErrAlreadyConnected) {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(reconnectLoginTimeout):
+		client.Disconnect()
+		return errors.New("timed out waiting for login after connect")
+	}
+}
+
+// reconnectDelay exponential backoff dengan jitter: setengah delay tetap + setengah random
+func reconnectDelay(attempt int) time.Duration {
+	delay := ReconnectPolicy.BaseDelay
+	for i := 1; i < attempt && delay < ReconnectPolicy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > ReconnectPolicy.MaxDelay {
+		delay = ReconnectPolicy.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func publishReconnectStatus(instanceID, status string, attempt int, lastErr error, nextAt *time.Time) {
+	if Realtime == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	data := ws.InstanceStatusChangedData{
+		InstanceID:       instanceID,
+		Status:           status,
+		IsConnected:      false,
+		ReconnectAttempt: attempt,
+		LastError:        errString(lastErr),
+		NextRetryAt:      nextAt,
+	}
+	if inst, err := model.GetInstanceByInstanceID(instanceID); err == nil {
+		data.PhoneNumber = inst.PhoneNumber.String
+	}
+
+	Realtime.Publish(ws.WsEvent{
+		Event:     ws.EventInstanceStatusChanged,
+		Timestamp: now,
+		Data:      data,
+	})
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	schedulerPollEvery = 2 * time.Second
+	schedulerBatchSize = 100
+)
+
+// ScheduleMediaMaxAhead di-set dari main.go (SCHEDULE_MEDIA_MAX_AHEAD): media di-upload saat
+// pesan dijadwalkan, jadi jadwal terlalu jauh bisa menunjuk file CDN WhatsApp yang sudah expired
+var ScheduleMediaMaxAhead = 7 * 24 * time.Hour
+
+var ErrScheduleTooFar = errors.New("sendAt is too far in the future for a media message")
+
+// checkScheduleWindow tolak pesan media yang dijadwalkan melewati ScheduleMediaMaxAhead
+func checkScheduleWindow(msg *waE2E.Message, sendAt time.Time) error {
+	if !helper.HasUploadedMedia(msg) || !sendAt.After(time.Now().Add(ScheduleMediaMaxAhead)) {
+		return nil
+	}
+	return fmt.Errorf("%w (max %s ahead)", ErrScheduleTooFar, ScheduleMediaMaxAhead)
+}
+
+// ScheduleMessage simpan pesan ke scheduled_messages untuk dikirim pada sendAt.
+// Message ID dibuat di awal supaya bisa dipakai cek status setelah terkirim.
+func ScheduleMessage(instanceID string, to types.JID, msg *waE2E.Message, sendAt time.Time) (*model.ScheduledMessage, error) {
+	if err := checkScheduleWindow(msg, sendAt); err != nil {
+		return nil, err
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	messageType := "unknown"
+	if content, ok := helper.ExtractMessageContent(msg); ok {
+		messageType = content.Type
+	}
+
+	scheduled := &model.ScheduledMessage{
+		InstanceID:   instanceID,
+		RecipientJID: to.String(),
+		MessageType:  messageType,
+		Payload:      payload,
+		SendAt:       sendAt,
+	}
+	if session, err := GetSession(instanceID); err == nil {
+		scheduled.MessageID = model.NullString(session.Client.GenerateMessageID())
+	}
+
+	if err := model.InsertScheduledMessage(scheduled); err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// RescheduleMessage ubah send_at pesan terjadwal dengan batas yang sama seperti ScheduleMessage
+func RescheduleMessage(instanceID string, id int64, sendAt time.Time) (*model.ScheduledMessage, error) {
+	scheduled, err := model.GetScheduledMessage(instanceID, id)
+	if err != nil {
+		return nil, err
+	}
+	msg := &waE2E.Message{}
+	if err := proto.Unmarshal(scheduled.Payload, msg); err != nil {
+		return nil, fmt.Errorf("invalid scheduled payload: %w", err)
+	}
+	if err := checkScheduleWindow(msg, sendAt); err != nil {
+		return nil, err
+	}
+	return model.RescheduleMessage(instanceID, id, sendAt)
+}
+
+// RunScheduler memindahkan pesan terjadwal yang jatuh tempo ke outbox; pengiriman, retry,
+// dan event OUTBOX_JOB_UPDATED ditangani worker outbox. Pesan yang terlewat saat server mati
+// langsung dikirim begitu server jalan lagi.
+func RunScheduler() {
+	ticker := time.NewTicker(schedulerPollEvery)
+	defer ticker.Stop()
+
+	for {
+		for {
+			instanceIDs, err := model.DispatchDueScheduledMessages(schedulerBatchSize, OutboxMaxAttempts)
+			if err != nil {
+				log.Printf("scheduler: failed to dispatch scheduled messages: %v", err)
+				break
+			}
+			if Outbox != nil {
+				for _, instanceID := range instanceIDs {
+					Outbox.wakeInstance(instanceID)
+				}
+			}
+			if len(instanceIDs) == 0 {
+				break
+			}
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+var ErrSessionNotConnected = errors.New("whatsapp session is not connected")
+
+// SendMessage jalur tunggal pengiriman pesan dari semua handler (personal & group).
+// Rate limit per instance dicek di sini; batas tercapai → *RateLimitError.
+// Pesan disimpan ke table messages (direction outgoing, status pending) SEBELUM dikirim memakai
+// message ID yang sudah di-generate, supaya receipt delivered / read / played yang datang sangat cepat
+// tidak hilang. Sukses → status server_ack; gagal pasti (belum terkirim) → row dihapus; hasil tidak
+// pasti (timeout) → row tetap pending supaya receipt yang menyusul masih tercatat.
+// extra opsional (nil = default), mis. message ID yang sudah dikunci outbox.
+func SendMessage(ctx context.Context, instanceID string, to types.JID, msg *waE2E.Message, extra *whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error) {
+	session, err := GetSession(instanceID)
+	if err != nil {
+		return whatsmeow.SendResponse{}, ErrInstanceNotFound
+	}
+	if session.Client.Store.ID == nil || !session.Client.IsConnected() {
+		return whatsmeow.SendResponse{}, ErrSessionNotConnected
+	}
+
+	// Anti-ban: batas kirim per instance (menit / jam / hari + warm-up) dan jeda antar pesan
+	slot, wait, err := reserveSendSlot(instanceID)
+	if err != nil {
+		return whatsmeow.SendResponse{}, err
+	}
+	if err := waitSendSlot(ctx, wait); err != nil {
+		releaseSendSlot(instanceID, slot)
+		return whatsmeow.SendResponse{}, err
+	}
+
+	var req whatsmeow.SendRequestExtra
+	if extra != nil {
+		req = *extra
+	}
+	if req.ID == "" {
+		req.ID = session.Client.GenerateMessageID()
+	}
+
+	sender := session.Client.Store.ID.ToNonAD()
+	stored := storePendingMessage(instanceID, req.ID, sender, to, msg)
+
+	resp, err := session.Client.SendMessage(ctx, to, msg, req)
+	if err != nil {
+		if IsSendOutcomeUnknown(err) {
+			// Bisa jadi sudah diterima server: slot rate limit tetap dihitung, row tetap pending
+			return resp, err
+		}
+		releaseSendSlot(instanceID, slot)
+		if stored {
+			if err := model.DeletePendingMessage(instanceID, req.ID); err != nil {
+				fmt.Printf("Warning: failed to delete unsent message %s for instance %s: %v\n", req.ID, instanceID, err)
+			}
+		}
+		return resp, err
+	}
+
+	if stored {
+		sentAt := resp.Timestamp
+		if sentAt.IsZero() {
+			sentAt = time.Now()
+		}
+		if err := model.MarkMessageServerAck(instanceID, resp.ID, sentAt); err != nil {
+			fmt.Printf("Warning: failed to update outgoing message %s for instance %s: %v\n", resp.ID, instanceID, err)
+		}
+		recordPoll(instanceID, resp.ID, to, sender, msg)
+	}
+	return resp, nil
+}
+
+// IsSendOutcomeUnknown error dari Client.SendMessage setelah pesan sudah dikirim ke server tapi
+// jawabannya tidak ditunggu sampai selesai (timeout / context habis): pesan mungkin sudah terkirim.
+func IsSendOutcomeUnknown(err error) bool {
+	return errors.Is(err, whatsmeow.ErrMessageTimedOut) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled)
+}
+
+// storePendingMessage simpan pesan keluar sebelum dikirim; gagal simpan hanya di-log supaya
+// pengiriman tetap jalan. Return true kalau pesan tersimpan (perlu di-update / dihapus setelah kirim).
+func storePendingMessage(instanceID, messageID string, sender, to types.JID, msg *waE2E.Message) bool {
+	content, ok := helper.ExtractMessageContent(msg)
+	if !ok {
+		return false
+	}
+
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		fmt.Printf("Warning: failed to marshal outgoing message %s: %v\n", messageID, err)
+	}
+
+	now := time.Now()
+	m := &model.Message{
+		InstanceID:      instanceID,
+		MessageID:       messageID,
+		ChatJID:         to.ToNonAD().String(),
+		ChatType:        helper.ChatType(to),
+		SenderJID:       model.NullString(sender.String()),
+		Direction:       "outgoing",
+		MessageType:     content.Type,
+		Body:            model.NullString(content.Body),
+		MediaMimetype:   model.NullString(content.Mimetype),
+		MediaFileName:   model.NullString(content.FileName),
+		MediaSize:       sql.NullInt64{Int64: int64(content.FileSize), Valid: content.FileSize > 0},
+		RawMessage:      raw,
+		Timestamp:       now,
+		Status:          model.NullString(model.MessageStatusPending),
+		StatusUpdatedAt: model.NullTime(now),
+	}
+
+	if err := model.InsertMessage(m); err != nil {
+		fmt.Printf("Warning: failed to store outgoing message %s for instance %s: %v\n", messageID, instanceID, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gowa-yourself/internal/model"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const minPasswordLength = 8
+
+// Hash dummy untuk menyamakan waktu respon login saat username tidak ada
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUserDisabled       = errors.New("user is disabled")
+	ErrWeakPassword       = fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	ErrInvalidRole        = errors.New("role must be 'admin' or 'user'")
+)
+
+// CreateUser validasi input, hash password (bcrypt) lalu simpan ke table users
+func CreateUser(username, password, role string) (*model.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+	if role == "" {
+		role = model.RoleUser
+	}
+	if role != model.RoleAdmin && role != model.RoleUser {
+		return nil, ErrInvalidRole
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &model.User{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		IsActive:     true,
+	}
+	if err := model.InsertUser(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Authenticate cek username + password, return user kalau valid & aktif
+func Authenticate(username, password string) (*model.User, error) {
+	user, err := model.GetUserByUsername(username)
+	if err != nil {
+		if errors.Is(err, model.ErrUserNotFound) {
+			// Tetap jalankan bcrypt supaya waktu respon tidak membocorkan username
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsActive {
+		return nil, ErrUserDisabled
+	}
+
+	if err := model.UpdateUserLastLogin(user.ID); err != nil {
+		log.Printf("Warning: failed to update last login for user %d: %v", user.ID, err)
+	}
+	return user, nil
+}
+
+// ChangePassword ganti password user. verifyOld=false dipakai admin untuk reset (tanpa cek password lama).
+// Semua refresh token user di-revoke supaya session lain harus login ulang.
+func ChangePassword(userID int64, oldPassword, newPassword string, verifyOld bool) error {
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if verifyOld && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)) != nil {
+		return ErrInvalidCredentials
+	}
+
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := model.UpdateUserPassword(userID, hash); err != nil {
+		return err
+	}
+	return model.RevokeUserRefreshTokens(userID)
+}
+
+// Access token JWT tetap valid sampai expired, jadi status aktif user dicek per request (JWT & WebSocket).
+// Hasil di-cache sebentar supaya tidak query DB tiap request; SetUserActive langsung invalidasi cache.
+const userActiveCacheTTL = 30 * time.Second
+
+type userActiveEntry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+var (
+	userActiveCache     = make(map[int64]userActiveEntry)
+	userActiveCacheLock sync.Mutex
+)
+
+// IsUserActive status aktif user (user yang sudah dihapus dianggap tidak aktif)
+func IsUserActive(userID int64) (bool, error) {
+	userActiveCacheLock.Lock()
+	entry, ok := userActiveCache[userID]
+	userActiveCacheLock.Unlock()
+	if ok && time.Since(entry.checkedAt) < userActiveCacheTTL {
+		return entry.active, nil
+	}
+
+	user, err := model.GetUserByID(userID)
+	if err != nil && !errors.Is(err, model.ErrUserNotFound) {
+		return false, err
+	}
+	active := err == nil && user.IsActive
+
+	userActiveCacheLock.Lock()
+	userActiveCache[userID] = userActiveEntry{active: active, checkedAt: time.Now()}
+	userActiveCacheLock.Unlock()
+	return active, nil
+}
+
+// SetUserActive enable / disable user. Disable juga revoke semua refresh token.
+func SetUserActive(userID int64, isActive bool) error {
+	if err := model.UpdateUserActive(userID, isActive); err != nil {
+		return err
+	}
+
+	userActiveCacheLock.Lock()
+	delete(userActiveCache, userID)
+	userActiveCacheLock.Unlock()
+
+	if !isActive {
+		return model.RevokeUserRefreshTokens(userID)
+	}
+	return nil
+}
+
+// IssueRefreshToken buat refresh token random; yang disimpan di DB hanya hash SHA-256.
+func IssueRefreshToken(userID int64, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	if err := model.InsertRefreshToken(userID, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken tukar refresh token lama (sekali pakai) dengan user pemiliknya.
+func RotateRefreshToken(token string) (*model.User, error) {
+	userID, err := model.ConsumeRefreshToken(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := model.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrUserDisabled
+	}
+	return user, nil
+}
+
+// EnsureAdminUser membuat admin pertama dari config kalau table users masih kosong.
+func EnsureAdminUser(username, password string) error {
+	total, err := model.CountUsers()
+	if err != nil {
+		return fmt.Errorf("count users: %w", err)
+	}
+	if total > 0 {
+		return nil
+	}
+
+	if username == "" || password == "" {
+		log.Println("Warning: no users found. Set ADMIN_USERNAME and ADMIN_PASSWORD to bootstrap the first admin.")
+		return nil
+	}
+
+	if _, err := CreateUser(username, password, model.RoleAdmin); err != nil {
+		return fmt.Errorf("create admin user: %w", err)
+	}
+	log.Printf("✓ Admin user %q created", username)
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"gowa-yourself/internal/model"
+	"gowa-yourself/internal/ws"
+)
+
+const (
+	webhookMaxAttempts = 8                // setelah ini delivery dianggap failed
+	webhookBaseBackoff = 10 * time.Second // 10s, 20s, 40s, ... (eksponensial)
+	webhookMaxBackoff  = 1 * time.Hour
+	webhookBatchSize   = 20
+	webhookWorkers     = 4
+	webhookPollEvery   = 5 * time.Second
+)
+
+// Webhooks di-set dari main.go, dipakai handler untuk replay delivery
+var Webhooks *WebhookDispatcher
+
+// WebhookAllowPrivateNetworks di-set dari main.go; false = URL webhook ke loopback / private /
+// link-local ditolak (cegah SSRF ke DB, metadata cloud, atau host intranet)
+var WebhookAllowPrivateNetworks bool
+
+var ErrWebhookHostNotAllowed = errors.New("webhook host resolves to a private, loopback or link-local address")
+
+// WebhookDispatcher menerima WsEvent (sama seperti Hub), menyimpan delivery ke DB
+// untuk setiap webhook yang subscribe, lalu worker mengirim POST + retry.
+type WebhookDispatcher struct {
+	events chan ws.WsEvent
+	wake   chan struct{}
+	client *http.Client
+}
+
+// NewWebhookDispatcher membuat dispatcher baru. Jalankan Run() di goroutine terpisah.
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{
+		events: make(chan ws.WsEvent, 1024),
+		wake:   make(chan struct{}, 1),
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+			// IP tujuan dicek lagi saat dial (termasuk redirect & DNS yang berubah setelah registrasi)
+			Transport: &http.Transport{
+				DialContext: (&net.Dialer{
+					Timeout: 10 * time.Second,
+					Control: webhookDialControl,
+				}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 15 * time.Second,
+				MaxIdleConnsPerHost:   4,
+			},
+		},
+	}
+}
+
+// Publish mengimplementasikan ws.RealtimePublisher. Tidak pernah blocking: dipanggil dari
+// event handler whatsmeow, jadi kalau buffer penuh (DB lambat) event di-drop & di-log
+// supaya pemrosesan pesan semua instance tidak ikut macet.
+func (d *WebhookDispatcher) Publish(event ws.WsEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	select {
+	case d.events <- event:
+	default:
+		log.Printf("webhook: event buffer full, dropping %s event", event.Event)
+	}
+}
+
+// Run menjalankan loop enqueue event dan loop pengiriman delivery.
+func (d *WebhookDispatcher) Run() {
+	// Delivery yang tertinggal 'processing' saat server mati dikirim ulang
+	if err := model.ResetProcessingWebhookDeliveries(); err != nil {
+		log.Printf("webhook: failed to reset processing deliveries: %v", err)
+	}
+
+	go d.deliverLoop()
+
+	for event := range d.events {
+		d.enqueue(event)
+	}
+}
+
+// Replay membuat delivery baru dengan payload yang sama dari delivery lama.
+func (d *WebhookDispatcher) Replay(webhookID, deliveryID int64) (*model.WebhookDelivery, error) {
+	old, err := model.GetWebhookDelivery(webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := &model.WebhookDelivery{
+		WebhookID:  old.WebhookID,
+		InstanceID: old.InstanceID,
+		Event:      old.Event,
+		Payload:    old.Payload,
+		ReplayOf:   sql.NullInt64{Int64: old.ID, Valid: true},
+	}
+	if err := model.InsertWebhookDelivery(replay); err != nil {
+		return nil, err
+	}
+
+	d.notify()
+	return replay, nil
+}
+
+func (d *WebhookDispatcher) enqueue(event ws.WsEvent) {
+	instanceID, _ := ws.EventScope(event)
+	if instanceID == "" {
+		return
+	}
+
+	webhooks, err := model.GetActiveWebhooksForEvent(instanceID, event.Event)
+	if err != nil {
+		log.Printf("webhook: failed to get webhooks for instance %s: %v", instanceID, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("webhook: failed to marshal event %s: %v", event.Event, err)
+		return
+	}
+
+	for _, w := range webhooks {
+		delivery := &model.WebhookDelivery{
+			WebhookID:  w.ID,
+			InstanceID: instanceID,
+			Event:      event.Event,
+			Payload:    string(payload),
+		}
+		if err := model.InsertWebhookDelivery(delivery); err != nil {
+			log.Printf("webhook: failed to queue delivery for webhook %d: %v", w.ID, err)
+		}
+	}
+
+	d.notify()
+}
+
+func (d *WebhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *WebhookDispatcher) deliverLoop() {
+	ticker := time.NewTicker(webhookPollEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		for {
+			deliveries, err := model.ClaimDueWebhookDeliveries(webhookBatchSize)
+			if err != nil {
+				log.Printf("webhook: failed to claim deliveries: %v", err)
+				break
+			}
+			if len(deliveries) == 0 {
+				break
+			}
+
+			var wg sync.WaitGroup
+			sem := make(chan struct{}, webhookWorkers)
+			for _, delivery := range deliveries {
+				wg.Add(1)
+				sem <- struct{}{}
+				go func(delivery model.WebhookDelivery) {
+					defer func() {
+						<-sem
+						wg.Done()
+					}()
+					d.attempt(delivery)
+				}(delivery)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// attempt mengirim satu delivery dan mencatat hasilnya
+func (d *WebhookDispatcher) attempt(delivery model.WebhookDelivery) {
+	webhook, err := model.GetWebhook(delivery.InstanceID, delivery.WebhookID)
+	if err != nil {
+		_ = model.MarkWebhookDeliveryFailed(delivery.ID, 0, "webhook no longer exists", nil)
+		return
+	}
+
+	statusCode, err := d.post(webhook, delivery)
+	if err == nil {
+		if err := model.MarkWebhookDeliverySuccess(delivery.ID, statusCode); err != nil {
+			log.Printf("webhook: failed to mark delivery %d success: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	var next *time.Time
+	if attempts < webhookMaxAttempts {
+		t := time.Now().Add(webhookBackoff(attempts))
+		next = &t
+	}
+
+	log.Printf("webhook: delivery %d to %s failed (attempt %d): %v", delivery.ID, webhook.URL, attempts, err)
+	if err := model.MarkWebhookDeliveryFailed(delivery.ID, statusCode, err.Error(), next); err != nil {
+		log.Printf("webhook: failed to mark delivery %d failed: %v", delivery.ID, err)
+	}
+}
+
+func (d *WebhookDispatcher) post(webhook *model.Webhook, delivery model.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SudevWA-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(snippet))
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload menghasilkan HMAC-SHA256 (hex) dari timestamp + "." + body dengan secret webhook.
+// Penerima menghitung ulang dari header X-Webhook-Timestamp + body mentah, membandingkan dengan
+// X-Webhook-Signature (constant-time), lalu menolak timestamp yang terlalu lama (mis. > 5 menit)
+// supaya request lama yang direkam tidak bisa di-replay.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateWebhookURL cek URL webhook absolut http(s) dan host-nya tidak resolve ke alamat internal
+func ValidateWebhookURL(raw string) error {
+	if raw == "" {
+		return errors.New("field 'url' is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if WebhookAllowPrivateNetworks {
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(context.Background(), u.Hostname())
+	if err != nil {
+		return fmt.Errorf("cannot resolve host %s: %w", u.Hostname(), err)
+	}
+	for _, ip := range ips {
+		if !isPublicIP(ip.IP) {
+			return fmt.Errorf("%w: %s", ErrWebhookHostNotAllowed, ip.IP)
+		}
+	}
+	return nil
+}
+
+// webhookDialControl tolak koneksi ke IP internal tepat sebelum connect
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	if WebhookAllowPrivateNetworks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrWebhookHostNotAllowed, host)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff << (attempts - 1)
+	if delay <= 0 || delay > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return delay
+}
@@ -15,6 +15,7 @@ import (
 	"gowa-yourself/internal/ws"
 
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/types/events"
 )
 
@@ -34,7 +35,13 @@ var (
 // Event handler untuk handle connection events
 func eventHandler(instanceID string) func(evt interface{}) {
 	return func(evt interface{}) {
-		switch evt.(type) {
+		switch v := evt.(type) {
+
+		case *events.Message:
+			handleIncomingMessage(instanceID, v)
+
+		case *events.Receipt:
+			handleReceipt(instanceID, v)
 
 		case *events.Connected:
 			loggingOutLock.RLock()
@@ -45,6 +52,9 @@ func eventHandler(instanceID string) func(evt interface{}) {
 				return
 			}
 
+			// Login berhasil, supervisor reconnect (kalau ada) tidak perlu lanjut
+			stopReconnect(instanceID)
+
 			sessionsLock.Lock()
 			session, exists := sessions[instanceID]
 			if exists {
@@ -61,7 +71,7 @@ func eventHandler(instanceID string) func(evt interface{}) {
 				jid := session.Client.Store.ID
 				phoneNumber := jid.User // biasanya sudah format 6285xxxx
 
-				platform := "" // kalau ada field ini; kalau tidak bisa kosong
+				platform := session.Client.Store.Platform // platform HP utama (android, iphone, smba, ...)
 				if err := model.UpdateInstanceOnConnected(
 					instanceID,
 					jid.String(),
@@ -92,12 +102,34 @@ func eventHandler(instanceID string) func(evt interface{}) {
 					Realtime.Publish(evt)
 				}
 
+				// Push name, about, foto profil diambil setelah login
+				refreshProfileAsync(instanceID)
+			}
+
+		case *events.PushNameSetting:
+			refreshProfileAsync(instanceID)
+
+		case *events.Picture:
+			if isOwnJID(instanceID, v.JID) {
+				refreshProfileAsync(instanceID)
+			}
+
+		case *events.UserAbout:
+			if isOwnJID(instanceID, v.JID) {
+				refreshProfileAsync(instanceID)
+			}
+
+		case *events.BusinessName:
+			if isOwnJID(instanceID, v.JID) {
+				refreshProfileAsync(instanceID)
 			}
 
 		case *events.PairSuccess:
 			fmt.Println("✓ Pair Success! Instance:", instanceID)
 
 		case *events.LoggedOut:
+			stopReconnect(instanceID)
+
 			sessionsLock.Lock()
 			if session, exists := sessions[instanceID]; exists {
 				session.IsConnected = false
@@ -155,6 +187,28 @@ func eventHandler(instanceID string) func(evt interface{}) {
 				if err := model.UpdateInstanceOnDisconnected(instanceID); err != nil {
 					fmt.Println("Warning: failed to update instance on disconnected:", err)
 				}
+
+				// Auto-reconnect whatsmeow dimatikan, supervisor yang mengambil alih
+				scheduleReconnect(instanceID, errors.New("connection lost"))
+			}
+
+		case *events.KeepAliveTimeout:
+			// Tanpa auto-reconnect whatsmeow, koneksi yang mati total harus diputus manual
+			if time.Since(v.LastSuccess) > keepAliveMaxFail {
+				fmt.Println("⚠ Keepalive timeout! Instance:", instanceID)
+				if session, err := GetSession(instanceID); err == nil {
+					session.Client.Disconnect()
+				}
+				sessionsLock.Lock()
+				if session, exists := sessions[instanceID]; exists {
+					session.IsConnected = false
+				}
+				sessionsLock.Unlock()
+
+				if err := model.UpdateInstanceOnDisconnected(instanceID); err != nil {
+					fmt.Println("Warning: failed to update instance on disconnected:", err)
+				}
+				scheduleReconnect(instanceID, fmt.Errorf("keepalive failed %d times", v.ErrorCount))
 			}
 		}
 	}
@@ -190,22 +244,30 @@ func LoadAllDevices() error {
 		}
 
 		// 2) Buat client WhatsMeow dan attach event handler dengan instanceID yang benar
-		client := whatsmeow.NewClient(device, nil)
-		client.AddEventHandler(eventHandler(instanceID))
+		client := newClient(device, instanceID)
+
+		// 3) Simpan ke sessions map dengan key instanceID yang konsisten.
+		//    Disimpan sebelum Connect supaya device yang gagal connect tetap bisa di-reconnect supervisor.
+		session := &model.Session{
+			ID:     instanceID,
+			JID:    jid,
+			Client: client,
+		}
+		sessionsLock.Lock()
+		sessions[instanceID] = session
+		sessionsLock.Unlock()
 
 		if err := client.Connect(); err != nil {
 			fmt.Printf("Failed to connect device %s: %v\n", jid, err)
+			if err := model.UpdateInstanceOnDisconnected(instanceID); err != nil {
+				fmt.Printf("Warning: failed to update instance on disconnected %s: %v\n", instanceID, err)
+			}
+			scheduleReconnect(instanceID, err)
 			continue
 		}
 
-		// 3) Simpan ke sessions map dengan key instanceID yang konsisten
 		sessionsLock.Lock()
-		sessions[instanceID] = &model.Session{
-			ID:          instanceID,
-			JID:         jid,
-			Client:      client,
-			IsConnected: client.IsConnected(),
-		}
+		session.IsConnected = client.IsConnected()
 		sessionsLock.Unlock()
 
 		// 4) Update status di DB bahwa instance ini berhasil re-connect
@@ -217,7 +279,7 @@ func LoadAllDevices() error {
 				instanceID,
 				jid,
 				phoneNumber,
-				"", // platform sementara kosong
+				device.Platform,
 			); err != nil {
 				fmt.Printf("Warning: failed to update instance on reconnect %s: %v\n", instanceID, err)
 			}
@@ -241,11 +303,8 @@ func CreateSession(instanceID string) (*model.Session, error) {
 	// Buat device baru
 	deviceStore := database.Container.NewDevice()
 
-	// Create whatsmeow client
-	client := whatsmeow.NewClient(deviceStore, nil)
-
-	// Add event handler
-	client.AddEventHandler(eventHandler(instanceID))
+	// Create whatsmeow client + event handler
+	client := newClient(deviceStore, instanceID)
 
 	// Simpan session
 	session := &model.Session{
@@ -258,6 +317,15 @@ func CreateSession(instanceID string) (*model.Session, error) {
 	return session, nil
 }
 
+// newClient buat client whatsmeow untuk instance. Auto-reconnect bawaan dimatikan karena
+// reconnect ditangani supervisor (lihat reconnect.go) supaya attempt & error tercatat di DB.
+func newClient(device *store.Device, instanceID string) *whatsmeow.Client {
+	client := whatsmeow.NewClient(device, nil)
+	client.EnableAutoReconnect = false
+	client.AddEventHandler(eventHandler(instanceID))
+	return client
+}
+
 func GetSession(instanceID string) (*model.Session, error) {
 	sessionsLock.RLock()
 	defer sessionsLock.RUnlock()
@@ -288,6 +356,7 @@ func DeleteSession(instanceID string) error {
 	loggingOutLock.Lock()
 	loggingOut[instanceID] = true
 	loggingOutLock.Unlock()
+	stopReconnect(instanceID)
 
 	// Ambil session
 	sessionsLock.Lock()
@@ -370,6 +439,8 @@ func DeleteInstance(instanceID string) error {
 		return ErrInstanceStillConnected
 	}
 
+	stopReconnect(instanceID)
+
 	// Opsional: bersihkan session in-memory + store whatsmeow
 	sess, err := GetSession(instanceID)
 	if err == nil && sess.Client != nil {
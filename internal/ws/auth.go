@@ -0,0 +1,145 @@
+package ws
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// ActionAuth autentikasi lewat pesan pertama: {"action":"auth","token":"<jwt / api key>"}
+	ActionAuth = "auth"
+
+	// authTimeout batas waktu client mengirim pesan auth kalau token tidak ada di handshake
+	authTimeout = 10 * time.Second
+
+	// CloseTokenExpired close code (range 4000-4999 untuk aplikasi) saat token kadaluarsa
+	CloseTokenExpired = 4001
+	// CloseUnauthorized close code kalau auth lewat pesan gagal / tidak dikirim
+	CloseUnauthorized = 4003
+	// CloseRevoked close code kalau identitas client tidak aktif lagi (mis. user di-disable)
+	CloseRevoked = 4004
+)
+
+var ErrForbiddenInstance = errors.New("not allowed to access this instance")
+
+// Authenticator validasi token (JWT atau API key) yang dikirim client.
+// Diisi oleh handler /ws supaya package ws tidak tergantung ke model / service.
+type Authenticator func(token string) (*ClientAuth, error)
+
+// ClientAuth identitas client WebSocket yang sudah terautentikasi.
+type ClientAuth struct {
+	Subject   string    // username / prefix API key, untuk log & balasan auth
+	ExpiresAt time.Time // zero = tidak pernah expire
+
+	// CanAccess cek apakah client boleh menerima event untuk instance tertentu
+	CanAccess func(instanceID string) bool
+
+	// Active cek ulang identitas saat event dikirim (mis. user di-disable);
+	// false → koneksi ditutup. nil = tidak dicek.
+	Active func() bool
+}
+
+// AuthenticatedData dikirim ke client setelah auth berhasil.
+type AuthenticatedData struct {
+	Subject   string     `json:"subject"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Authorize pasang identitas ke client dan jadwalkan penutupan koneksi
+// saat token expire. Bisa dipanggil ulang (re-auth) untuk memperpanjang sesi.
+func (c *Client) Authorize(auth *ClientAuth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+	c.auth = auth
+
+	if !auth.ExpiresAt.IsZero() {
+		c.expiry = time.AfterFunc(time.Until(auth.ExpiresAt), func() {
+			log.Printf("ws: token expired for %s, closing connection", auth.Subject)
+			c.closeWith(CloseTokenExpired, "token expired")
+		})
+	}
+}
+
+func (c *Client) currentAuth() *ClientAuth {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.auth
+}
+
+// allowed cek hak akses client ke event instance tertentu (belum auth = tidak boleh)
+func (c *Client) allowed(instanceID string) bool {
+	auth := c.currentAuth()
+	if auth == nil {
+		return false
+	}
+	if auth.Active != nil && !auth.Active() {
+		c.revoke(auth)
+		return false
+	}
+	if auth.CanAccess == nil {
+		return true
+	}
+	return auth.CanAccess(instanceID)
+}
+
+// closeWith kirim close frame dengan alasan lalu tutup koneksi;
+// ReadPump akan error dan meng-unregister client.
+func (c *Client) closeWith(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(5*time.Second))
+	_ = c.conn.Close()
+}
+
+// revoke tutup koneksi sekali saja; dijalankan di goroutine supaya loop hub tidak tertahan write close frame
+func (c *Client) revoke(auth *ClientAuth) {
+	c.revokeOnce.Do(func() {
+		log.Printf("ws: %s is no longer active, closing connection", auth.Subject)
+		go c.closeWith(CloseRevoked, "user disabled")
+	})
+}
+
+func (c *Client) stopExpiry() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.expiry != nil {
+		c.expiry.Stop()
+		c.expiry = nil
+	}
+}
+
+func (c *Client) handleAuth(cmd ClientCommand) {
+	if c.authenticate == nil || cmd.Token == "" {
+		c.replyError(cmd.Action, "field 'token' is required")
+		return
+	}
+
+	auth, err := c.authenticate(cmd.Token)
+	if err != nil {
+		log.Printf("ws: auth failed: %v", err)
+		c.closeWith(CloseUnauthorized, "invalid token")
+		return
+	}
+
+	// Re-auth hanya boleh untuk identitas yang sama (mis. perpanjang token)
+	if prev := c.currentAuth(); prev != nil && prev.Subject != auth.Subject {
+		c.replyError(cmd.Action, "cannot switch identity on an authenticated connection")
+		return
+	}
+
+	c.Authorize(auth)
+	_ = c.conn.SetReadDeadline(time.Now().Add(15 * time.Minute))
+
+	data := AuthenticatedData{Subject: auth.Subject}
+	if !auth.ExpiresAt.IsZero() {
+		data.ExpiresAt = &auth.ExpiresAt
+	}
+	c.hub.sendTo(c, WsEvent{Event: EventAuthenticated, Data: data})
+}
@@ -1,10 +1,14 @@
 package ws
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Nama event (konstanta) supaya konsisten antara BE dan FE.
 const (
 	EventQRGenerated           = "QR_GENERATED"
+	EventPairCodeGenerated     = "PAIR_CODE_GENERATED" // Kode pairing via nomor HP (alternatif QR)
 	EventQRExpired             = "QR_EXPIRED"
 	EventInstanceStatusChanged = "INSTANCE_STATUS_CHANGED"
 	EventInstanceError         = "INSTANCE_ERROR"
@@ -12,10 +16,43 @@ const (
 	EventQRSuccess   = "QR_SUCCESS" // Pairing berhasil
 	EventQRTimeout   = "QR_TIMEOUT"
 	EventQRCancelled = "QR_CANCELLED" // Tambahkan ini
+
+	EventMessageReceived      = "MESSAGE_RECEIVED"       // Pesan masuk (personal, group, status)
+	EventMessageStatusChanged = "MESSAGE_STATUS_CHANGED" // Status pesan keluar berubah (delivered, read, played)
+	EventOutboxJobUpdated     = "OUTBOX_JOB_UPDATED"     // Status job kirim async berubah (sent, retry, failed)
+	EventCampaignProgress     = "CAMPAIGN_PROGRESS"      // Progress / status campaign broadcast berubah
+	EventPollVoteUpdated      = "POLL_VOTE_UPDATED"      // Vote polling masuk / berubah / ditarik
+
+	// Balasan perintah client WebSocket (tidak dikirim ke webhook)
+	EventAuthenticated       = "AUTHENTICATED"
+	EventSubscriptionUpdated = "SUBSCRIPTION_UPDATED"
+	EventCommandError        = "COMMAND_ERROR"
 	// Kalau nanti mau dipakai:
 	// EventQRScanned = "QR_SCANNED"
 )
 
+// webhookEvents event yang bisa di-subscribe webhook (balasan perintah client WebSocket tidak termasuk)
+var webhookEvents = map[string]bool{
+	EventQRGenerated:           true,
+	EventPairCodeGenerated:     true,
+	EventQRExpired:             true,
+	EventInstanceStatusChanged: true,
+	EventInstanceError:         true,
+	EventQRSuccess:             true,
+	EventQRTimeout:             true,
+	EventQRCancelled:           true,
+	EventMessageReceived:       true,
+	EventMessageStatusChanged:  true,
+	EventOutboxJobUpdated:      true,
+	EventCampaignProgress:      true,
+	EventPollVoteUpdated:       true,
+}
+
+// IsWebhookEvent cek nama event (huruf besar) dikenal dan bisa dikirim ke webhook
+func IsWebhookEvent(name string) bool {
+	return webhookEvents[name]
+}
+
 // WsEvent adalah envelope umum setiap pesan yang dikirim via WebSocket.
 // FE cukup switch berdasarkan field Event, lalu cast Data ke bentuk yang sesuai.
 type WsEvent struct {
@@ -24,6 +61,24 @@ type WsEvent struct {
 	Data      interface{} `json:"data"`      // Payload spesifik event
 }
 
+// EventScope mengambil instance_id & phone_number dari payload event apa pun.
+// Semua payload (struct maupun map) memakai key JSON yang sama, jadi cukup decode ulang.
+func EventScope(event WsEvent) (instanceID, phoneNumber string) {
+	raw, err := json.Marshal(event.Data)
+	if err != nil {
+		return "", ""
+	}
+
+	var scope struct {
+		InstanceID  string `json:"instance_id"`
+		PhoneNumber string `json:"phone_number"`
+	}
+	if err := json.Unmarshal(raw, &scope); err != nil {
+		return "", ""
+	}
+	return scope.InstanceID, scope.PhoneNumber
+}
+
 // =====================
 // Payload per jenis event
 // =====================
@@ -37,6 +92,15 @@ type QRGeneratedData struct {
 	ExpiresAt   time.Time `json:"expires_at"`             // waktu kadaluarsa QR
 }
 
+// PairCodeGeneratedData dikirim ketika kode pairing berhasil dibuat;
+// user memasukkan kode ini di HP (Perangkat tertaut > Tautkan dengan nomor telepon).
+type PairCodeGeneratedData struct {
+	InstanceID  string    `json:"instance_id"`
+	PhoneNumber string    `json:"phone_number"`
+	PairCode    string    `json:"pair_code"`  // format XXXX-XXXX
+	ExpiresAt   time.Time `json:"expires_at"` // perkiraan kadaluarsa kode
+}
+
 // QRExpiredData dikirim ketika QR untuk instance tertentu dianggap kadaluarsa.
 type QRExpiredData struct {
 	InstanceID  string `json:"instance_id"`
@@ -52,6 +116,11 @@ type InstanceStatusChangedData struct {
 	IsConnected    bool       `json:"is_connected"`              // true kalau koneksi aktif
 	ConnectedAt    *time.Time `json:"connected_at,omitempty"`    // bisa nil jika belum pernah connect
 	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"` // bisa nil jika belum pernah disconnect
+
+	// Diisi supervisor reconnect (status "reconnecting" / "needs_attention")
+	ReconnectAttempt int        `json:"reconnect_attempt,omitempty"`
+	LastError        string     `json:"last_error,omitempty"`
+	NextRetryAt      *time.Time `json:"next_retry_at,omitempty"`
 }
 
 // InstanceErrorData opsional, untuk mengirim error penting terkait instance,
@@ -62,3 +131,71 @@ type InstanceErrorData struct {
 	Code        string `json:"code"`    // contoh: "LOGIN_FAILED", "UNOFFICIAL_APP", "QR_CHANNEL_FAILED"
 	Message     string `json:"message"` // human readable message
 }
+
+// MessageReceivedData dikirim ketika instance menerima pesan baru
+// (atau pesan yang dikirim dari device lain milik nomor yang sama).
+type MessageReceivedData struct {
+	InstanceID  string    `json:"instance_id"`
+	PhoneNumber string    `json:"phone_number,omitempty"`
+	MessageID   string    `json:"message_id"`
+	ChatJID     string    `json:"chat_jid"`
+	ChatType    string    `json:"chat_type"` // "personal", "group", "status", dll
+	SenderJID   string    `json:"sender_jid"`
+	SenderName  string    `json:"sender_name,omitempty"`
+	Direction   string    `json:"direction"` // "incoming" / "outgoing"
+	MessageType string    `json:"message_type"`
+	Body        string    `json:"body,omitempty"`
+	Mimetype    string    `json:"mimetype,omitempty"`
+	FileName    string    `json:"file_name,omitempty"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
+// MessageStatusChangedData dikirim ketika status pesan outgoing naik
+// (server_ack → delivered → read → played) berdasarkan receipt dari penerima.
+type MessageStatusChangedData struct {
+	InstanceID   string    `json:"instance_id"`
+	PhoneNumber  string    `json:"phone_number,omitempty"`
+	MessageID    string    `json:"message_id"`
+	ChatJID      string    `json:"chat_jid"`
+	RecipientJID string    `json:"recipient_jid"` // penerima yang mengirim receipt (berbeda dengan chat_jid untuk group)
+	Status       string    `json:"status"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OutboxJobUpdatedData dikirim setiap attempt job outbox (mode kirim async) selesai.
+// Status "pending" + next_attempt_at artinya attempt gagal dan akan di-retry.
+type OutboxJobUpdatedData struct {
+	InstanceID    string     `json:"instance_id"`
+	PhoneNumber   string     `json:"phone_number,omitempty"`
+	JobID         int64      `json:"job_id"`
+	To            string     `json:"to"`
+	MessageID     string     `json:"message_id,omitempty"`
+	Status        string     `json:"status"` // "sent", "pending", "failed"
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+}
+
+// CampaignProgressData dikirim saat status campaign berubah (running, paused, completed, cancelled)
+// dan berkala selama pengiriman berjalan.
+type CampaignProgressData struct {
+	InstanceID  string      `json:"instance_id"`
+	PhoneNumber string      `json:"phone_number,omitempty"`
+	CampaignID  int64       `json:"campaign_id"`
+	Status      string      `json:"status"`
+	LastError   string      `json:"last_error,omitempty"`
+	Progress    interface{} `json:"progress"` // model.CampaignStats
+}
+
+// PollVoteUpdatedData dikirim setiap vote polling (yang dikirim / diterima instance) masuk atau berubah.
+// selected_options kosong artinya voter menarik vote-nya.
+type PollVoteUpdatedData struct {
+	InstanceID      string      `json:"instance_id"`
+	PhoneNumber     string      `json:"phone_number,omitempty"`
+	MessageID       string      `json:"message_id"` // message id polling
+	ChatJID         string      `json:"chat_jid"`
+	VoterJID        string      `json:"voter_jid"`
+	SelectedOptions []string    `json:"selected_options"`
+	VotedAt         time.Time   `json:"voted_at"`
+	Results         interface{} `json:"results"` // model.PollResultsResp terbaru
+}
@@ -18,10 +18,22 @@ type Client struct {
 	// Goroutine write akan membaca dari sini dan mengirim ke conn.
 	send chan WsEvent
 
-	// (Opsional) informasi identitas untuk filtering ke depan,
-	// misalnya UserID / TenantID / daftar InstanceID yang di-subscribe.
-	// Untuk versi awal bisa dikosongkan dulu.
-	// UserID string
+	// Filter event yang di-subscribe client (instance, nomor, jenis event).
+	sub *Subscription
+
+	// Autentikasi: client baru menerima event setelah auth berhasil,
+	// koneksi ditutup otomatis saat token expire.
+	authenticate Authenticator
+	mu           sync.RWMutex
+	auth         *ClientAuth
+	expiry       *time.Timer
+	revokeOnce   sync.Once
+}
+
+// directEvent adalah event untuk satu client saja (mis. balasan perintah subscribe).
+type directEvent struct {
+	client *Client
+	event  WsEvent
 }
 
 // Hub menyimpan semua client aktif dan menangani broadcast event.
@@ -33,9 +45,12 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 
-	// Broadcast adalah channel event yang akan dikirim ke semua client.
+	// Broadcast adalah channel event yang akan dikirim ke client yang subscribe.
 	broadcast chan WsEvent
 
+	// Direct adalah channel event untuk satu client tertentu.
+	direct chan directEvent
+
 	// Mutex kalau nanti butuh akses synchronous ke clients dari luar Run().
 	mu sync.RWMutex
 }
@@ -47,6 +62,7 @@ func NewHub() *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan WsEvent, 256), // buffer kecil untuk mencegah blocking
+		direct:     make(chan directEvent, 64),
 	}
 }
 
@@ -54,7 +70,8 @@ func NewHub() *Hub {
 // Loop ini akan:
 // - menerima client baru (register)
 // - menghapus client yang disconnect (unregister)
-// - mengirim event ke semua client (broadcast)
+// - mengirim event ke client yang subscription-nya cocok (broadcast)
+// - mengirim balasan perintah ke satu client (direct)
 func (h *Hub) Run() {
 	for {
 		select {
@@ -72,22 +89,54 @@ func (h *Hub) Run() {
 			h.mu.Unlock()
 
 		case event := <-h.broadcast:
+			// Scope dihitung sekali per event, bukan per client
+			instanceID, phoneNumber := EventScope(event)
+
+			// Cek subscription & hak akses di luar lock karena CanAccess bisa query DB
 			h.mu.RLock()
+			targets := make([]*Client, 0, len(h.clients))
 			for client := range h.clients {
-				select {
-				case client.send <- event:
-					// sukses kirim ke buffer client
-				default:
-					// kalau buffer penuh, anggap client bermasalah dan putuskan
-					close(client.send)
-					delete(h.clients, client)
-				}
+				targets = append(targets, client)
 			}
 			h.mu.RUnlock()
+
+			matched := targets[:0]
+			for _, client := range targets {
+				if client.sub.Matches(event.Event, instanceID, phoneNumber) && client.allowed(instanceID) {
+					matched = append(matched, client)
+				}
+			}
+
+			h.mu.Lock()
+			for _, client := range matched {
+				if _, ok := h.clients[client]; ok {
+					h.deliver(client, event)
+				}
+			}
+			h.mu.Unlock()
+
+		case d := <-h.direct:
+			h.mu.Lock()
+			if _, ok := h.clients[d.client]; ok {
+				h.deliver(d.client, d.event)
+			}
+			h.mu.Unlock()
 		}
 	}
 }
 
+// deliver kirim event ke buffer client, h.mu harus sudah di-lock.
+func (h *Hub) deliver(client *Client, event WsEvent) {
+	select {
+	case client.send <- event:
+		// sukses kirim ke buffer client
+	default:
+		// kalau buffer penuh, anggap client bermasalah dan putuskan
+		close(client.send)
+		delete(h.clients, client)
+	}
+}
+
 // Register digunakan oleh handler WS saat koneksi baru dibuat.
 func (h *Hub) Register(client *Client) {
 	h.register <- client
@@ -99,7 +148,7 @@ func (h *Hub) Unregister(client *Client) {
 }
 
 // Publish mengimplementasikan RealtimePublisher.
-// Service lain cukup memanggil ini untuk mengirim event ke semua client.
+// Service lain cukup memanggil ini, hub yang memilih client berdasarkan subscription.
 func (h *Hub) Publish(event WsEvent) {
 	// Pastikan timestamp terisi kalau belum diset.
 	if event.Timestamp.IsZero() {
@@ -108,19 +157,45 @@ func (h *Hub) Publish(event WsEvent) {
 	h.broadcast <- event
 }
 
+// sendTo kirim event hanya ke satu client (lewat Run supaya aman dari channel yang sudah ditutup).
+func (h *Hub) sendTo(client *Client, event WsEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	h.direct <- directEvent{client: client, event: event}
+}
+
 // RealtimePublisher adalah interface yang akan dipegang oleh service lain
 // (whatsapp.go, handler QR) agar tidak tergantung langsung ke Hub.
 type RealtimePublisher interface {
 	Publish(event WsEvent)
 }
 
+// MultiPublisher meneruskan satu event ke beberapa publisher sekaligus
+// (mis. Hub WebSocket + webhook dispatcher).
+type MultiPublisher []RealtimePublisher
+
+func (m MultiPublisher) Publish(event WsEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	for _, p := range m {
+		if p != nil {
+			p.Publish(event)
+		}
+	}
+}
+
 // NewClient membuat objek Client baru dari koneksi Gorilla WebSocket.
 // Fungsi ini tidak menjalankan goroutine read/write; itu tugas handler WS.
-func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+// authenticate dipakai untuk perintah {"action":"auth"} dari client.
+func NewClient(hub *Hub, conn *websocket.Conn, authenticate Authenticator) *Client {
 	return &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan WsEvent, 256),
+		hub:          hub,
+		conn:         conn,
+		send:         make(chan WsEvent, 256),
+		sub:          newSubscription(),
+		authenticate: authenticate,
 	}
 }
 
@@ -150,29 +225,86 @@ func (c *Client) WritePump() {
 	}
 }
 
-// ReadPump opsional: loop baca dari client.
-// Untuk versi awal, kamu bisa hanya consume dan buang,
-// atau pakai untuk menerima perintah subscribe dsb.
-// Kalau belum butuh, bisa dibuat minimal / dikosongkan.
+// ReadPump membaca perintah dari client: auth, subscribe / unsubscribe berdasarkan
+// instance ID, nomor telepon dan jenis event (lihat ClientCommand).
+// Kalau belum auth saat handshake, pesan auth wajib dikirim dalam authTimeout.
 func (c *Client) ReadPump() {
 	defer func() {
+		c.stopExpiry()
 		c.hub.Unregister(c)
 		_ = c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(4096)
 
-	_ = c.conn.SetReadDeadline(time.Now().Add(15 * time.Minute))
+	if c.currentAuth() == nil {
+		_ = c.conn.SetReadDeadline(time.Now().Add(authTimeout))
+	} else {
+		_ = c.conn.SetReadDeadline(time.Now().Add(15 * time.Minute))
+	}
 	c.conn.SetPongHandler(func(string) error {
 		_ = c.conn.SetReadDeadline(time.Now().Add(15 * time.Minute))
 		return nil
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, raw, err := c.conn.ReadMessage()
 		if err != nil {
 			log.Printf("ws read error: %v", err)
 			break
 		}
+		if c.currentAuth() != nil {
+			_ = c.conn.SetReadDeadline(time.Now().Add(15 * time.Minute))
+		}
+
+		var cmd ClientCommand
+		if err := json.Unmarshal(raw, &cmd); err != nil {
+			c.replyError("", "invalid JSON command")
+			continue
+		}
+		c.handleCommand(cmd)
 	}
 }
+
+func (c *Client) handleCommand(cmd ClientCommand) {
+	if cmd.Action == ActionAuth {
+		c.handleAuth(cmd)
+		return
+	}
+
+	auth := c.currentAuth()
+	if auth == nil {
+		c.replyError(cmd.Action, "authentication required, send {\"action\":\"auth\",\"token\":\"...\"} first")
+		return
+	}
+
+	switch cmd.Action {
+	case ActionSubscribe, ActionUnsubscribe:
+		if cmd.Action == ActionSubscribe && auth.CanAccess != nil {
+			// Instance yang bukan milik client tidak boleh di-subscribe
+			allowed := make([]string, 0, len(cmd.InstanceIDs))
+			for _, id := range cmd.InstanceIDs {
+				if id == SubscribeAll || auth.CanAccess(id) {
+					allowed = append(allowed, id)
+				} else {
+					c.replyError(cmd.Action, ErrForbiddenInstance.Error()+": "+id)
+				}
+			}
+			cmd.InstanceIDs = allowed
+		}
+		c.sub.Apply(cmd)
+		c.hub.sendTo(c, WsEvent{
+			Event: EventSubscriptionUpdated,
+			Data:  c.sub.Snapshot(),
+		})
+	default:
+		c.replyError(cmd.Action, "unknown action, use 'auth', 'subscribe' or 'unsubscribe'")
+	}
+}
+
+func (c *Client) replyError(action, msg string) {
+	c.hub.sendTo(c, WsEvent{
+		Event: EventCommandError,
+		Data:  CommandErrorData{Action: action, Error: msg},
+	})
+}
@@ -0,0 +1,131 @@
+package ws
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Aksi yang bisa dikirim client lewat WebSocket (lihat Client.ReadPump).
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+// Wildcard di instance_ids / events untuk subscribe ke semua instance / semua event.
+const SubscribeAll = "*"
+
+// ClientCommand adalah pesan JSON yang dikirim FE ke server, contoh:
+//
+//	{"action":"subscribe","instance_ids":["abc"],"phone_numbers":["62812..."],"events":["QR_GENERATED"]}
+//
+// events kosong = semua event. Client tanpa subscription instance / nomor tidak menerima event apa pun.
+type ClientCommand struct {
+	Action       string   `json:"action"`
+	Token        string   `json:"token,omitempty"` // hanya untuk action auth
+	InstanceIDs  []string `json:"instance_ids,omitempty"`
+	PhoneNumbers []string `json:"phone_numbers,omitempty"`
+	Events       []string `json:"events,omitempty"`
+}
+
+// SubscriptionData dikirim balik ke client setiap kali subscription berubah.
+type SubscriptionData struct {
+	InstanceIDs  []string `json:"instance_ids"`
+	PhoneNumbers []string `json:"phone_numbers"`
+	Events       []string `json:"events"`
+}
+
+// CommandErrorData dikirim ke client kalau perintah tidak valid.
+type CommandErrorData struct {
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error"`
+}
+
+// Subscription menyimpan filter event milik satu client.
+type Subscription struct {
+	mu           sync.RWMutex
+	instanceIDs  map[string]bool
+	phoneNumbers map[string]bool
+	events       map[string]bool
+}
+
+func newSubscription() *Subscription {
+	return &Subscription{
+		instanceIDs:  make(map[string]bool),
+		phoneNumbers: make(map[string]bool),
+		events:       make(map[string]bool),
+	}
+}
+
+// Apply menjalankan perintah subscribe / unsubscribe.
+func (s *Subscription) Apply(cmd ClientCommand) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscribe := cmd.Action == ActionSubscribe
+	update := func(set map[string]bool, values []string, normalize func(string) string) {
+		for _, v := range values {
+			if v = normalize(v); v == "" {
+				continue
+			}
+			if subscribe {
+				set[v] = true
+			} else {
+				delete(set, v)
+			}
+		}
+	}
+
+	update(s.instanceIDs, cmd.InstanceIDs, strings.TrimSpace)
+	update(s.phoneNumbers, cmd.PhoneNumbers, normalizePhone)
+	update(s.events, cmd.Events, func(v string) string { return strings.ToUpper(strings.TrimSpace(v)) })
+}
+
+// Matches cek apakah event dengan scope tertentu perlu dikirim ke client ini.
+func (s *Subscription) Matches(event, instanceID, phoneNumber string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.events) > 0 && !s.events[event] && !s.events[SubscribeAll] {
+		return false
+	}
+	if s.instanceIDs[SubscribeAll] {
+		return true
+	}
+	if instanceID != "" && s.instanceIDs[instanceID] {
+		return true
+	}
+	return phoneNumber != "" && s.phoneNumbers[normalizePhone(phoneNumber)]
+}
+
+// Snapshot isi subscription saat ini (untuk dikirim balik ke client).
+func (s *Subscription) Snapshot() SubscriptionData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return SubscriptionData{
+		InstanceIDs:  setKeys(s.instanceIDs),
+		PhoneNumbers: setKeys(s.phoneNumbers),
+		Events:       setKeys(s.events),
+	}
+}
+
+func setKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// normalizePhone buang karakter non-digit ("+62 812-..." -> "62812...")
+func normalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -1,15 +1,19 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"time"
 
+	"gowa-yourself/config"
 	"gowa-yourself/database"
 	"gowa-yourself/internal/handler"
 	"gowa-yourself/internal/helper"
+	"gowa-yourself/internal/model"
 	"gowa-yourself/internal/service"
 
 	echojwt "github.com/labstack/echo-jwt/v4"
@@ -21,6 +25,8 @@ import (
 )
 
 func main() {
+	cfg := config.Load()
+
 	// Load env
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
@@ -44,22 +50,90 @@ func main() {
 		helper.InitCustomSchema()
 	}
 
-	// Load all existing devices from database
-	log.Println("Loading existing devices...")
-	err := service.LoadAllDevices()
-	if err != nil {
-		log.Printf("Warning: Failed to load devices: %v", err)
+	// Auth: signing key & TTL dari config
+	if cfg.JWTSecret == "" {
+		b := make([]byte, 32)
+		rand.Read(b)
+		cfg.JWTSecret = hex.EncodeToString(b)
+		log.Println("Warning: JWT_SECRET is not set, using a random key (tokens will be invalid after restart)")
+	}
+	handler.JwtKey = []byte(cfg.JWTSecret)
+	handler.AccessTokenTTL = cfg.AccessTokenTTL
+	handler.RefreshTokenTTL = cfg.RefreshTokenTTL
+	handler.WSAllowedOrigins = cfg.WSAllowedOrigins
+
+	if err := service.EnsureAdminUser(cfg.AdminUsername, cfg.AdminPassword); err != nil {
+		log.Printf("Warning: failed to ensure admin user: %v", err)
 	}
 
 	// Inisialisasi WebSocket Hub
 	hub := ws.NewHub()
 	go hub.Run()
 
-	service.Realtime = hub
+	// Webhook dispatcher: event yang sama dengan WS juga dikirim ke webhook per instance
+	service.WebhookAllowPrivateNetworks = cfg.WebhookAllowPrivateNetworks
+	webhooks := service.NewWebhookDispatcher()
+	go webhooks.Run()
+	service.Webhooks = webhooks
+
+	service.Realtime = ws.MultiPublisher{hub, webhooks}
+
+	// Outbox: antrian kirim async (?async=true) dengan worker per instance + retry
+	service.OutboxMaxAttempts = cfg.OutboxMaxAttempts
+	outbox := service.NewOutboxDispatcher()
+	go outbox.Run()
+	service.Outbox = outbox
+
+	// Anti-ban: rate limit kirim per instance, berlaku untuk semua jalur kirim (sync, outbox, campaign)
+	service.SendRateLimit = service.RateLimitConfig{
+		PerMinute:         cfg.SendLimitPerMinute,
+		PerHour:           cfg.SendLimitPerHour,
+		PerDay:            cfg.SendLimitPerDay,
+		MinGap:            cfg.SendMinGap,
+		Jitter:            cfg.SendJitter,
+		MaxWait:           cfg.SendMaxWait,
+		WarmupDays:        cfg.SendWarmupDays,
+		WarmupStartPerDay: cfg.SendWarmupStartPerDay,
+	}
+
+	// Idempotency-Key endpoint kirim: hasil disimpan selama window, key expired dibersihkan berkala
+	service.IdempotencyWindow = cfg.IdempotencyWindow
+	go service.RunIdempotencyCleaner()
+
+	// Scheduler: pesan dengan sendAt dipindah ke outbox saat jatuh tempo
+	service.ScheduleMediaMaxAhead = cfg.ScheduleMediaMaxAhead
+	go service.RunScheduler()
+
+	// Reconnect supervisor: dipakai juga oleh LoadAllDevices untuk device yang gagal connect
+	service.ReconnectPolicy = service.ReconnectConfig{
+		MaxAttempts: cfg.ReconnectMaxAttempts,
+		BaseDelay:   cfg.ReconnectBaseDelay,
+		MaxDelay:    cfg.ReconnectMaxDelay,
+	}
+
+	// Refresh profil instance (push name, about, foto) berkala
+	service.ProfileRefreshInterval = cfg.ProfileRefreshInterval
+	go service.RunProfileRefresher()
+
+	// Load all existing devices from database (setelah hub siap supaya event status ikut terkirim)
+	log.Println("Loading existing devices...")
+	err := service.LoadAllDevices()
+	if err != nil {
+		log.Printf("Warning: Failed to load devices: %v", err)
+	}
+
+	// Campaign yang masih running sebelum restart dilanjutkan (setelah device di-load)
+	service.ResumeRunningCampaigns()
 
 	// Setup Echo
 	e := echo.New()
-	e.Use(middleware.Logger())
+	// Access log tanpa query string: /ws?token= berisi JWT / API key yang tidak boleh masuk log
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: `{"time":"${time_rfc3339_nano}","id":"${id}","remote_ip":"${remote_ip}",` +
+			`"host":"${host}","method":"${method}","path":"${path}","user_agent":"${user_agent}",` +
+			`"status":${status},"error":"${error}","latency":${latency},"latency_human":"${latency_human}"` +
+			`,"bytes_in":${bytes_in},"bytes_out":${bytes_out}}` + "\n",
+	}))
 	e.Use(middleware.Recover())
 
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
@@ -78,6 +152,8 @@ func main() {
 			echo.HeaderAccept,
 			echo.HeaderXRequestedWith,
 			echo.HeaderAuthorization,
+			handler.HeaderAPIKey,
+			handler.HeaderIdempotencyKey,
 		},
 		AllowCredentials: true, // kalau pakai cookie / auth
 	}))
@@ -95,9 +171,10 @@ func main() {
 			},
 		),
 	}))
-	e.POST("/login-jwt", handler.LoginJWT)      // di luar group JWT
-	e.GET("/ws", handler.WebSocketHandler(hub)) //listen socket gorilla
-	e.GET("/", func(c echo.Context) error {     // Health check
+	e.POST("/login-jwt", handler.LoginJWT)       // di luar group JWT
+	e.POST("/refresh-token", handler.RefreshJWT) // tukar refresh token
+	e.GET("/ws", handler.WebSocketHandler(hub))  //listen socket gorilla, auth via token / pesan pertama
+	e.GET("/", func(c echo.Context) error {      // Health check
 		return c.JSON(200, map[string]interface{}{
 			"success": true,
 			"message": "WhatsApp API is running",
@@ -105,20 +182,41 @@ func main() {
 		})
 	})
 
-	// Daftar group route yang butuh JWT
-	api := e.Group("/api", echojwt.WithConfig(echojwt.Config{
-		SigningKey: handler.JwtKey,
+	// Daftar group route yang butuh JWT (atau header X-API-Key)
+	jwtAuth := echojwt.WithConfig(echojwt.Config{
+		SigningKey:    handler.JwtKey,
+		NewClaimsFunc: handler.NewClaims,
 		ErrorHandler: func(c echo.Context, err error) error {
 			// Custom response untuk JWT authentication error
 			return c.JSON(http.StatusUnauthorized, map[string]interface{}{
 				"success": false,
 				"error":   "Authentication required",
-				"message": "Please provide a valid Bearer token in the Authorization header",
+				"message": "Please provide a valid Bearer token in the Authorization header or an X-API-Key header",
 			})
 		},
-	}))
+	})
+	api := e.Group("/api", handler.APIKeyOrJWT(jwtAuth))
 	api.GET("/validate", handler.ValidateToken)
 
+	// User management
+	api.PUT("/users/me/password", handler.ChangeMyPassword)
+	api.POST("/users", handler.CreateUser, handler.RequireAdmin)
+	api.GET("/users", handler.GetUsers, handler.RequireAdmin)
+	api.POST("/users/:userId/disable", handler.DisableUser, handler.RequireAdmin)
+	api.POST("/users/:userId/enable", handler.EnableUser, handler.RequireAdmin)
+	api.PUT("/users/:userId/password", handler.ResetUserPassword, handler.RequireAdmin)
+
+	// API key (machine-to-machine), hanya bisa dikelola via login JWT
+	api.POST("/api-keys", handler.CreateAPIKey, handler.RequireUser)
+	api.GET("/api-keys", handler.GetAPIKeys, handler.RequireUser)
+	api.DELETE("/api-keys/:keyId", handler.RevokeAPIKey, handler.RequireUser)
+
+	// Permission per route untuk API key
+	canSend := handler.RequirePermission(model.PermSend)
+	canRead := handler.RequirePermission(model.PermRead)
+	canManage := handler.RequirePermission(model.PermManage)
+	idempotent := handler.Idempotent // Idempotency-Key di route kirim (dijalankan setelah cek permission)
+
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
 		code := http.StatusInternalServerError
 		message := "Internal Server Error"
@@ -146,45 +244,121 @@ func main() {
 	}
 
 	// Routes
-	api.POST("/login", handler.Login)
-	api.GET("/qr/:instanceId", handler.GetQR)
-	api.GET("/status/:instanceId", handler.GetStatus)
-	api.POST("/logout/:instanceId", handler.Logout)
-	api.DELETE("/instances/:instanceId", handler.DeleteInstance)
-	api.DELETE("/qr-cancel/:instanceId", handler.CancelQR)
+	api.POST("/login", handler.Login, canManage)
+	api.GET("/qr/:instanceId", handler.GetQR, canManage)
+	api.POST("/pair-phone/:instanceId", handler.PairPhone, canManage)
+	api.GET("/status/:instanceId", handler.GetStatus, canRead)
+	api.POST("/logout/:instanceId", handler.Logout, canManage)
+	api.POST("/reconnect/:instanceId", handler.ReconnectInstance, canManage)
+	api.DELETE("/instances/:instanceId", handler.DeleteInstance, canManage)
+	api.DELETE("/qr-cancel/:instanceId", handler.CancelQR, canManage)
 
 	// ambil semua instance
-	api.GET("/instances", handler.GetAllInstances)
+	api.GET("/instances", handler.GetAllInstances, canRead)
+	api.GET("/instances/:instanceId", handler.GetInstance, canRead)
+	api.PUT("/instances/:instanceId/owner", handler.SetInstanceOwner, handler.RequireAdmin)
+	api.GET("/instances/:instanceId/rate-limit", handler.GetRateLimitStatus, canRead)
+
+	// Webhook routes by instance id
+	api.POST("/webhooks/:instanceId", handler.CreateWebhook, canManage)
+	api.GET("/webhooks/:instanceId", handler.GetWebhooks, canManage)
+	api.PUT("/webhooks/:instanceId/:webhookId", handler.UpdateWebhook, canManage)
+	api.DELETE("/webhooks/:instanceId/:webhookId", handler.DeleteWebhook, canManage)
+	api.GET("/webhooks/:instanceId/:webhookId/deliveries", handler.GetWebhookDeliveries, canManage)
+	api.POST("/webhooks/:instanceId/:webhookId/deliveries/:deliveryId/replay", handler.ReplayWebhookDelivery, canManage)
 
 	// Message routes by instance id
-	api.POST("/send/:instanceId", handler.SendMessage)
-	api.POST("/check/:instanceId", handler.CheckNumber)
+	api.POST("/send/:instanceId", handler.SendMessage, canSend, idempotent)
+	api.POST("/check/:instanceId", handler.CheckNumber, canRead)
+
+	// Riwayat pesan (incoming & outgoing) per instance
+	api.GET("/messages/:instanceId", handler.GetMessages, canRead)
+	api.GET("/messages/:instanceId/:messageId/status", handler.GetMessageStatus, canRead)
+
+	// Reaksi, edit & tarik pesan (delete for everyone) berdasarkan message id di riwayat
+	api.POST("/messages/:instanceId/:messageId/react", handler.ReactToMessage, canSend)
+	api.PUT("/messages/:instanceId/:messageId", handler.EditMessage, canSend)
+	api.DELETE("/messages/:instanceId/:messageId", handler.RevokeMessage, canSend)
+
+	// Outbox (job kirim async) per instance
+	api.GET("/outbox/:instanceId", handler.GetOutboxJobs, canRead)
+	api.GET("/outbox/:instanceId/:jobId", handler.GetOutboxJob, canRead)
+
+	// Pesan terjadwal (sendAt) per instance
+	api.GET("/scheduled/:instanceId", handler.GetScheduledMessages, canRead)
+	api.GET("/scheduled/:instanceId/:scheduleId", handler.GetScheduledMessage, canRead)
+	api.PUT("/scheduled/:instanceId/:scheduleId", handler.RescheduleMessage, canSend)
+	api.DELETE("/scheduled/:instanceId/:scheduleId", handler.CancelScheduledMessage, canSend)
+
+	// Bulk broadcast campaign per instance
+	api.POST("/campaigns/:instanceId", handler.CreateCampaign, canSend, idempotent)
+	api.GET("/campaigns/:instanceId", handler.GetCampaigns, canRead)
+	api.GET("/campaigns/:instanceId/:campaignId", handler.GetCampaign, canRead)
+	api.GET("/campaigns/:instanceId/:campaignId/recipients", handler.GetCampaignRecipients, canRead)
+	api.POST("/campaigns/:instanceId/:campaignId/pause", handler.PauseCampaign, canSend)
+	api.POST("/campaigns/:instanceId/:campaignId/resume", handler.ResumeCampaign, canSend)
+	api.POST("/campaigns/:instanceId/:campaignId/cancel", handler.CancelCampaign, canSend)
+
+	// Sender pool: beberapa instance pengirim dengan load balancing & failover
+	api.POST("/pools", handler.CreateSenderPool, canManage)
+	api.GET("/pools", handler.GetSenderPools, canRead)
+	api.GET("/pools/:poolName", handler.GetSenderPool, canRead)
+	api.PUT("/pools/:poolName", handler.UpdateSenderPool, canManage)
+	api.DELETE("/pools/:poolName", handler.DeleteSenderPool, canManage)
+	api.POST("/pool/:poolName/send", handler.SendPoolMessage, canSend, idempotent)
+
+	// Template pesan (per user, dipakai via templateId di endpoint kirim & campaign)
+	api.POST("/templates", handler.CreateTemplate, canManage)
+	api.GET("/templates", handler.GetTemplates, canRead)
+	api.GET("/templates/:templateId", handler.GetTemplate, canRead)
+	api.PUT("/templates/:templateId", handler.UpdateTemplate, canManage)
+	api.DELETE("/templates/:templateId", handler.DeleteTemplate, canManage)
+	api.POST("/templates/:templateId/render", handler.RenderTemplate, canRead)
+
 	// Media routes by instance id
-	api.POST("/send/:instanceId/media", handler.SendMediaFile)
-	api.POST("/send/:instanceId/media-url", handler.SendMediaURL)
+	api.POST("/send/:instanceId/media", handler.SendMediaFile, canSend, idempotent)
+	api.POST("/send/:instanceId/media-url", handler.SendMediaURL, canSend, idempotent)
+	api.POST("/send/:instanceId/sticker", handler.SendSticker, canSend, idempotent)
+
+	// Lokasi (pin / live location) & kartu kontak (vCard)
+	api.POST("/send/:instanceId/location", handler.SendLocation, canSend, idempotent)
+	api.POST("/send/:instanceId/contact", handler.SendContact, canSend, idempotent)
+
+	// Polling: kirim & hasil vote
+	api.POST("/send/:instanceId/poll", handler.SendPoll, canSend, idempotent)
+	api.GET("/polls/:instanceId/:messageId", handler.GetPollResults, canRead)
 
 	//Message by nohp
-	api.POST("/by-number/:phoneNumber", handler.SendMessageByNumber)
-	api.POST("/by-number/:phoneNumber/media-url", handler.SendMediaURLByNumber)
-	api.POST("/by-number/:phoneNumber/media-file", handler.SendMediaFileByNumber)
+	api.POST("/by-number/:phoneNumber", handler.SendMessageByNumber, canSend, idempotent)
+	api.POST("/by-number/:phoneNumber/media-url", handler.SendMediaURLByNumber, canSend, idempotent)
+	api.POST("/by-number/:phoneNumber/media-file", handler.SendMediaFileByNumber, canSend, idempotent)
+	api.POST("/by-number/:phoneNumber/sticker", handler.SendSticker, canSend, idempotent)
+	api.POST("/by-number/:phoneNumber/location", handler.SendLocation, canSend, idempotent)
+	api.POST("/by-number/:phoneNumber/contact", handler.SendContact, canSend, idempotent)
+	api.POST("/by-number/:phoneNumber/poll", handler.SendPoll, canSend, idempotent)
 
 	// Group routes
-	api.GET("/groups/:instanceId", handler.GetGroups)
-	api.POST("/send-group/:instanceId", handler.SendGroupMessage)
-	api.POST("/send-group/:instanceId/media", handler.SendGroupMedia)
-	api.POST("/send-group/:instanceId/media-url", handler.SendGroupMediaURL)
+	api.GET("/groups/:instanceId", handler.GetGroups, canRead)
+	api.POST("/send-group/:instanceId", handler.SendGroupMessage, canSend, idempotent)
+	api.POST("/send-group/:instanceId/media", handler.SendGroupMedia, canSend, idempotent)
+	api.POST("/send-group/:instanceId/media-url", handler.SendGroupMediaURL, canSend, idempotent)
+	api.POST("/send-group/:instanceId/sticker", handler.SendGroupSticker, canSend, idempotent)
+	api.POST("/send-group/:instanceId/location", handler.SendGroupLocation, canSend, idempotent)
+	api.POST("/send-group/:instanceId/contact", handler.SendGroupContact, canSend, idempotent)
+	api.POST("/send-group/:instanceId/poll", handler.SendGroupPoll, canSend, idempotent)
 
 	//Group by no hp
-	api.GET("/groups/by-number/:phoneNumber", handler.GetGroupsByNumber)
-	api.POST("/send-group/by-number/:phoneNumber", handler.SendGroupMessageByNumber)
-	api.POST("/send-group/by-number/:phoneNumber/media", handler.SendGroupMediaByNumber)
-	api.POST("/send-group/by-number/:phoneNumber/media-url", handler.SendGroupMediaURLByNumber)
+	api.GET("/groups/by-number/:phoneNumber", handler.GetGroupsByNumber, canRead)
+	api.POST("/send-group/by-number/:phoneNumber", handler.SendGroupMessageByNumber, canSend, idempotent)
+	api.POST("/send-group/by-number/:phoneNumber/media", handler.SendGroupMediaByNumber, canSend, idempotent)
+	api.POST("/send-group/by-number/:phoneNumber/media-url", handler.SendGroupMediaURLByNumber, canSend, idempotent)
+	api.POST("/send-group/by-number/:phoneNumber/sticker", handler.SendGroupSticker, canSend, idempotent)
+	api.POST("/send-group/by-number/:phoneNumber/location", handler.SendGroupLocation, canSend, idempotent)
+	api.POST("/send-group/by-number/:phoneNumber/contact", handler.SendGroupContact, canSend, idempotent)
+	api.POST("/send-group/by-number/:phoneNumber/poll", handler.SendGroupPoll, canSend, idempotent)
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "2121"
-	}
+	port := cfg.Port
 
 	log.Printf("Server starting on port %s", port)
 	log.Fatal(e.Start("127.0.0.1:" + port))